@@ -1,6 +1,6 @@
 // src/lib/api.ts
 import axios from 'axios';
-import type { User, SlotData, SlotDetails, CreateSessionFeedback, SessionFeedback, CreateSlotData, ApiResponse, Paginated } from '../types';
+import type { User, SlotData, SlotDetails, CreateSessionFeedback, SessionFeedback, CreateSlotData, ApiResponse, Paginated, SlotSearchParams, CoachDirectoryEntry, Attendee, Attendance, NoShowSummary, SessionFeedbackRevision, SessionRating, CreateSessionRating, CoachRatingSummary, SatisfactionAnalytics, SatisfactionAnalyticsParams, FeedbackAnswer, FeedbackTemplate, CreateFeedbackTemplate, FeedbackTemplateReport, FeedbackSearchResult } from '../types';
 import { browser } from '$app/environment';
 
 let initialUserId: string | null = null;
@@ -53,9 +53,45 @@ export const api = {
     });
   },
 
+  searchSlots: (params: SlotSearchParams, page: number = 1, pageSize: number = 10) => {
+    return axiosInstance.get<Paginated<SlotData>>('/api/slots/search', {
+      params: { ...params, page, pageSize }
+    })
+    .then(response => response.data)
+    .catch(error => {
+      console.error('Error in searchSlots:', error);
+      throw error;
+    });
+  },
+
+  getCoachDirectory: (filter: { specialty?: string; availableWithinDays?: number } = {}, page: number = 1, pageSize: number = 10) => {
+    return axiosInstance.get<Paginated<CoachDirectoryEntry>>('/api/coaches', {
+      params: { ...filter, page, pageSize }
+    })
+    .then(response => response.data)
+    .catch(error => {
+      console.error('Error in getCoachDirectory:', error);
+      throw error;
+    });
+  },
+
+  holdSlot: (id: string) =>
+    axiosInstance.post<Attendee>(`/api/slots/${id}/hold`).then(response => response.data),
+
+  releaseHold: (id: string) =>
+    axiosInstance.delete(`/api/slots/${id}/hold`),
+
   bookSlot: (id: string) => 
     axiosInstance.post<SlotData>(`/api/slots/${id}/book`),
 
+  markAttendance: (slotId: string, attendance: Attendance, studentId?: string) =>
+    axiosInstance.put<Attendee>(`/api/slots/${slotId}/attendance`, { attendance, studentId }).then(response => response.data),
+
+  getNoShowSummary: (studentId?: string) =>
+    axiosInstance.get<NoShowSummary>('/api/attendance/no-shows', {
+      params: studentId ? { studentId } : {}
+    }).then(response => response.data),
+
   getUpcomingBookingsForStudent: (page: number = 1, pageSize: number = 10) => {
     return axiosInstance.get<Paginated<SlotData>>('/api/students/bookings', {
         params: { page, pageSize }
@@ -79,6 +115,44 @@ export const api = {
   createSessionFeedback: (feedbackData: CreateSessionFeedback) => 
     axiosInstance.post<ApiResponse<SessionFeedback>>(`/api/session-feedback`, feedbackData),
 
+  updateSessionFeedback: (id: string, satisfaction: number, notes: string, templateId?: string, answers?: Record<string, FeedbackAnswer>) =>
+    axiosInstance.put<SessionFeedback>(`/api/session-feedback/${id}`, { satisfaction, notes, templateId, answers }).then(response => response.data),
+
+  getFeedbackRevisions: (id: string) =>
+    axiosInstance.get<SessionFeedbackRevision[]>(`/api/session-feedback/${id}/revisions`).then(response => response.data),
+
+  getSatisfactionAnalytics: (params: SatisfactionAnalyticsParams = {}) =>
+    axiosInstance.get<SatisfactionAnalytics>('/api/session-feedback/analytics', { params }).then(response => response.data),
+
+  searchSessionNotes: (q: string, page: number = 1, pageSize: number = 10) =>
+    axiosInstance.get<Paginated<FeedbackSearchResult>>('/api/session-feedback/search', { params: { q, page, pageSize } }).then(response => response.data),
+
+  getFeedbackTemplates: (includeArchived = false) =>
+    axiosInstance.get<FeedbackTemplate[]>('/api/feedback-templates', { params: { includeArchived } }).then(response => response.data),
+
+  getFeedbackTemplate: (id: string) =>
+    axiosInstance.get<FeedbackTemplate>(`/api/feedback-templates/${id}`).then(response => response.data),
+
+  createFeedbackTemplate: (template: CreateFeedbackTemplate) =>
+    axiosInstance.post<FeedbackTemplate>('/api/feedback-templates', template).then(response => response.data),
+
+  archiveFeedbackTemplate: (id: string) =>
+    axiosInstance.post(`/api/feedback-templates/${id}/archive`),
+
+  getFeedbackTemplateReport: (id: string, params: SatisfactionAnalyticsParams = {}) =>
+    axiosInstance.get<FeedbackTemplateReport>(`/api/feedback-templates/${id}/report`, { params }).then(response => response.data),
+
+  rateSession: (ratingData: CreateSessionRating) =>
+    axiosInstance.post<SessionRating>('/api/session-ratings', ratingData).then(response => response.data),
+
+  getMyRatings: (page: number = 1, pageSize: number = 10) =>
+    axiosInstance.get<Paginated<SessionRating>>('/api/session-ratings', {
+      params: { page, pageSize }
+    }).then(response => response.data),
+
+  getCoachRatingSummary: (coachId: string) =>
+    axiosInstance.get<CoachRatingSummary>(`/api/coaches/${coachId}/ratings`).then(response => response.data),
+
   getStudentsWithSessions: () => {
     return axiosInstance.get<User[]>('/api/session-feedback/studentswithsessions')
       .then(response => response.data)
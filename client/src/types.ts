@@ -11,31 +11,76 @@
     coachName: string;
     startTime: string;
     endTime: string;
-    studentId?: string;
+    sessionTypeId?: string;
+    sessionTypeName?: string;
     booked: boolean;
+    status: SlotStatus;
+    capacity: number;
+    seatsRemaining: number;
+    attendees?: Attendee[];
+  }
+
+  export interface Attendee {
+    id: string;
+    slotId: string;
+    studentId: string;
+    studentName: string;
+    studentPhoneNumber?: string;
+    status: 'confirmed' | 'held' | 'pending' | 'checkout';
+    expiresAt?: string;
+    createdAt: string;
+    attendance?: Attendance;
+    attendanceMarkedBy?: string;
+    attendanceMarkedAt?: string;
+  }
+
+  export type Attendance = 'attended' | 'student_no_show' | 'coach_no_show';
+
+  export interface NoShowSummary {
+    studentId: string;
+    recentNoShows: number;
+    totalNoShows: number;
   }
 
+  export type SlotStatus = 'open' | 'booked' | 'completed' | 'cancelled' | 'no_show';
+
   export interface SlotDetails {
     id: string;
     coachId: string;
     coachName: string;
     startTime: string;
     endTime: string;
-    studentId?: string;
     booked: boolean;
+    status: SlotStatus;
+    capacity: number;
+    seatsRemaining: number;
+    attendees?: Attendee[];
     coachPhoneNumber: string;
-    studentPhoneNumber: string;
-    studentName: string;
   }
   
   export interface CreateSlotData {
     startTime: string;
+    endTime?: string;
+    sessionTypeId?: string;
+    capacity?: number;
+  }
+
+  export interface SessionType {
+    id: string;
+    coachId: string;
+    name: string;
+    durationMinutes: number;
+    description: string;
+    priceCents: number;
+    currency: string;
   }
   
   export interface CreateSessionFeedback {
     slotId: string;
     satisfaction: number;
     notes: string;
+    templateId?: string;
+    answers?: Record<string, FeedbackAnswer>;
   }
 
   export interface SessionFeedback {
@@ -46,6 +91,137 @@
     satisfaction: number;
     notes: string;
     createdAt: string;
+    updatedAt?: string;
+    templateId?: string;
+    responses?: FeedbackResponse[];
+  }
+
+  export interface SessionFeedbackRevision {
+    id: string;
+    feedbackId: string;
+    revision: number;
+    satisfaction: number;
+    notes: string;
+    authorId: string;
+    authorName: string;
+    createdAt: string;
+    templateId?: string;
+    responses?: FeedbackResponse[];
+  }
+
+  export type FeedbackFieldKind = 'score' | 'choice' | 'text' | 'checklist';
+
+  export interface FeedbackSearchResult {
+    feedbackId: string;
+    slotId: string;
+    studentId: string;
+    studentName: string;
+    startTime: string;
+    endTime: string;
+    satisfaction: number;
+    createdAt: string;
+    rank: number;
+    // HTML-escaped notes with matches wrapped in <mark> tags
+    snippet: string;
+  }
+
+  export interface FeedbackTemplateField {
+    id: string;
+    templateId: string;
+    position: number;
+    key: string;
+    label: string;
+    kind: FeedbackFieldKind;
+    required: boolean;
+    minScore?: number;
+    maxScore?: number;
+    options: string[];
+  }
+
+  export interface FeedbackTemplate {
+    id: string;
+    name: string;
+    description: string;
+    active: boolean;
+    createdBy: string;
+    createdAt: string;
+    fields: FeedbackTemplateField[];
+  }
+
+  export interface CreateFeedbackTemplate {
+    name: string;
+    description?: string;
+    fields: Pick<FeedbackTemplateField, 'key' | 'label' | 'kind' | 'required' | 'minScore' | 'maxScore' | 'options'>[];
+  }
+
+  export interface FeedbackAnswer {
+    score?: number;
+    text?: string;
+    selected?: string[];
+  }
+
+  export interface FeedbackResponse {
+    feedbackId: string;
+    fieldId: string;
+    fieldKey: string;
+    score?: number;
+    text?: string;
+    selected?: string[];
+  }
+
+  export interface FeedbackFieldReport {
+    fieldId: string;
+    key: string;
+    label: string;
+    kind: FeedbackFieldKind;
+    responses: number;
+    average: number | null;
+    distribution: Record<string, number>;
+  }
+
+  export interface FeedbackTemplateReport {
+    templateId: string;
+    from: string;
+    to: string;
+    sessions: number;
+    fields: FeedbackFieldReport[];
+  }
+
+  export interface WeeklySatisfaction {
+    subjectId: string;
+    subjectName: string;
+    weekStart: string;
+    sessions: number;
+    average: number;
+    rollingAverage: number;
+  }
+
+  export interface DecliningStudent {
+    studentId: string;
+    studentName: string;
+    sessions: number;
+    average: number;
+    firstSatisfaction: number;
+    latestSatisfaction: number;
+    trendPerWeek: number;
+  }
+
+  export interface SatisfactionAnalytics {
+    from: string;
+    to: string;
+    sessions: number;
+    average: number | null;
+    distribution: Record<number, number>;
+    coaches: WeeklySatisfaction[];
+    students: WeeklySatisfaction[];
+    decliningStudents: DecliningStudent[];
+  }
+
+  export interface SatisfactionAnalyticsParams {
+    from?: string;
+    to?: string;
+    coachId?: string;
+    studentId?: string;
   }
 
   export interface Paginated<T> {
@@ -56,6 +232,60 @@
     totalPages: number;
 }
   
+  export interface CoachDirectoryEntry {
+    id: string;
+    name: string;
+    timeZone: string;
+    bio: string;
+    photoUrl: string;
+    specialties: string[];
+    languages: string[];
+    nextAvailableAt: string | null;
+    averageRating: number | null;
+    ratingCount: number;
+  }
+
+  export interface SessionRating {
+    id: string;
+    slotId: string;
+    coachId: string;
+    studentId: string;
+    rating: number;
+    comments: string;
+    useful: boolean;
+    createdAt: string;
+  }
+
+  export interface CreateSessionRating {
+    slotId: string;
+    rating: number;
+    comments: string;
+    useful: boolean;
+  }
+
+  export interface CoachRatingSummary {
+    coachId: string;
+    average: number | null;
+    count: number;
+    usefulCount: number;
+    distribution: Record<number, number>;
+  }
+
+  export interface SlotSearchParams {
+    from?: string;
+    to?: string;
+    weekday?: string;
+    timeFrom?: string;
+    timeTo?: string;
+    tz?: string;
+    minDuration?: number;
+    maxDuration?: number;
+    coachId?: string;
+    coachName?: string;
+    sessionType?: string;
+    sort?: 'earliest' | 'latest';
+  }
+
   export interface ApiResponse<T> {
     data: T;
     // Add other properties that your API returns, if any
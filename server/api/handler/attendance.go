@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type AttendanceHandler struct {
+	service *service.AttendanceService
+}
+
+func NewAttendanceHandler(service *service.AttendanceService) *AttendanceHandler {
+	return &AttendanceHandler{service: service}
+}
+
+// MarkAttendance records whether a session took place. studentId may be left
+// out for a slot with a single attendee.
+func (h *AttendanceHandler) MarkAttendance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		StudentID  *uuid.UUID             `json:"studentId"`
+		Attendance model.AttendanceStatus `json:"attendance"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	booking, err := h.service.MarkAttendance(r.Context(), userID, slotID, req.StudentID, req.Attendance)
+	if err != nil {
+		writeAttendanceError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(booking)
+}
+
+// GetNoShowSummary returns the caller's no-show counts. Coaches pass a
+// studentId query parameter to look up a student.
+func (h *AttendanceHandler) GetNoShowSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	studentID := userID
+	if studentIDStr := r.URL.Query().Get("studentId"); studentIDStr != "" {
+		studentID, err = uuid.Parse(studentIDStr)
+		if err != nil {
+			http.Error(w, "Invalid student ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	summary, err := h.service.GetNoShowSummary(r.Context(), userID, studentID)
+	if err != nil {
+		writeAttendanceError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(summary)
+}
+
+// writeAttendanceError maps attendance failures to HTTP status codes.
+func writeAttendanceError(w http.ResponseWriter, err error) {
+	var errNotAuthorized *service.ErrNotAuthorized
+	var errNotStudent *service.ErrNotStudent
+	var errSlotNotFound *service.ErrSlotNotFound
+	var errSlotNotAssignedToCoach *service.ErrSlotNotAssignedToCoach
+	var errBookingNotFound *service.ErrBookingNotFound
+	var errSlotNotBooked *service.ErrSlotNotBooked
+	var errSessionNotEnded *service.ErrSessionNotEnded
+	var errInvalidAttendance *service.ErrInvalidAttendance
+	var errStudentRequired *service.ErrStudentRequired
+	switch {
+	case errors.As(err, &errNotAuthorized),
+		errors.As(err, &errNotStudent),
+		errors.As(err, &errSlotNotAssignedToCoach):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errSlotNotFound), errors.As(err, &errBookingNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errSlotNotBooked), errors.As(err, &errSessionNotEnded):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &errInvalidAttendance), errors.As(err, &errStudentRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type AvailabilityRuleHandler struct {
+	service *service.AvailabilityRuleService
+}
+
+func NewAvailabilityRuleHandler(service *service.AvailabilityRuleService) *AvailabilityRuleHandler {
+	return &AvailabilityRuleHandler{service: service}
+}
+
+type availabilityRuleRequest struct {
+	SessionTypeID *uuid.UUID     `json:"sessionTypeId"`
+	RRule         string         `json:"rrule"`
+	Weekdays      []time.Weekday `json:"weekdays"`
+	Weeks         int            `json:"weeks"`
+	StartDate     string         `json:"startDate"`
+	StartMinute   int            `json:"startMinute"`
+	EndMinute     int            `json:"endMinute"`
+}
+
+func (req availabilityRuleRequest) toInput() (service.AvailabilityRuleInput, error) {
+	startDate, err := time.Parse(time.DateOnly, req.StartDate)
+	if err != nil {
+		return service.AvailabilityRuleInput{}, errors.New("startDate must be a date in YYYY-MM-DD format")
+	}
+	return service.AvailabilityRuleInput{
+		SessionTypeID: req.SessionTypeID,
+		RRule:         req.RRule,
+		Weekdays:      req.Weekdays,
+		Weeks:         req.Weeks,
+		StartDate:     startDate,
+		StartMinute:   req.StartMinute,
+		EndMinute:     req.EndMinute,
+	}, nil
+}
+
+func (h *AvailabilityRuleHandler) CreateAvailabilityRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var req availabilityRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input, err := req.toInput()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.CreateAvailabilityRule(r.Context(), userID, input)
+	if err != nil {
+		writeAvailabilityRuleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *AvailabilityRuleHandler) GetAvailabilityRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := h.service.GetAvailabilityRules(r.Context(), userID)
+	if err != nil {
+		writeAvailabilityRuleError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(rules)
+}
+
+func (h *AvailabilityRuleHandler) UpdateAvailabilityRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	ruleID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid availability rule ID", http.StatusBadRequest)
+		return
+	}
+	var req availabilityRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	input, err := req.toInput()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.UpdateAvailabilityRule(r.Context(), userID, ruleID, input)
+	if err != nil {
+		writeAvailabilityRuleError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *AvailabilityRuleHandler) DeleteAvailabilityRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	ruleID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid availability rule ID", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := h.service.DeleteAvailabilityRule(r.Context(), userID, ruleID)
+	if err != nil {
+		writeAvailabilityRuleError(w, err)
+		return
+	}
+
+	response := struct {
+		SlotsRemoved int `json:"slotsRemoved"`
+	}{
+		SlotsRemoved: removed,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeAvailabilityRuleError(w http.ResponseWriter, err error) {
+	var errNotAuthorized *service.ErrNotAuthorized
+	var errRuleNotFound *service.ErrAvailabilityRuleNotFound
+	var errSessionTypeNotFound *service.ErrSessionTypeNotFound
+	var errInvalidRule *service.ErrInvalidAvailabilityRule
+	switch {
+	case errors.As(err, &errNotAuthorized):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errRuleNotFound), errors.As(err, &errSessionTypeNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errInvalidRule):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
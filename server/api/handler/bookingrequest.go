@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type BookingRequestHandler struct {
+	service *service.BookingRequestService
+}
+
+func NewBookingRequestHandler(service *service.BookingRequestService) *BookingRequestHandler {
+	return &BookingRequestHandler{service: service}
+}
+
+func (h *BookingRequestHandler) GetBookingRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	page, pageSize := getPaginationParams(r)
+	requests, totalCount, err := h.service.GetBookingRequests(r.Context(), userID, page, pageSize)
+	if err != nil {
+		writeBookingRequestError(w, err)
+		return
+	}
+	totalPages := (totalCount + pageSize - 1) / pageSize
+	response := model.Paginated[model.BookingRequest]{
+		Data:       requests,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *BookingRequestHandler) ApproveBookingRequest(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	bookingID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid booking request ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ApproveBookingRequest(r.Context(), userID, bookingID); err != nil {
+		writeBookingRequestError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *BookingRequestHandler) DeclineBookingRequest(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	bookingID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid booking request ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is allowed
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeclineBookingRequest(r.Context(), userID, bookingID, req.Reason); err != nil {
+		writeBookingRequestError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeBookingRequestError maps booking request failures to HTTP status codes.
+func writeBookingRequestError(w http.ResponseWriter, err error) {
+	var errNotAuthorized *service.ErrNotAuthorized
+	var errBookingRequestNotFound *service.ErrBookingRequestNotFound
+	var errBookingRequestClosed *service.ErrBookingRequestClosed
+	switch {
+	case errors.As(err, &errNotAuthorized):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errBookingRequestNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errBookingRequestClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
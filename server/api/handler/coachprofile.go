@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type CoachProfileHandler struct {
+	service *service.CoachProfileService
+}
+
+func NewCoachProfileHandler(service *service.CoachProfileService) *CoachProfileHandler {
+	return &CoachProfileHandler{service: service}
+}
+
+func (h *CoachProfileHandler) GetCoachProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	coachID, err := uuid.Parse(mux.Vars(r)["coachId"])
+	if err != nil {
+		http.Error(w, "Invalid coach ID", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.service.GetCoachProfile(r.Context(), coachID)
+	if err != nil {
+		var errNotCoach *service.ErrNotCoach
+		if errors.As(err, &errNotCoach) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(profile)
+}
+
+// GetCoachDirectory lists coaches. The optional specialty query parameter
+// keeps coaches tagged with it, and availableWithinDays keeps coaches with a
+// bookable slot in that many days.
+func (h *CoachProfileHandler) GetCoachDirectory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	filter := model.CoachDirectoryFilter{Specialty: r.URL.Query().Get("specialty")}
+	if value := r.URL.Query().Get("availableWithinDays"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "availableWithinDays must be a number of days", http.StatusBadRequest)
+			return
+		}
+		filter.AvailableWithinDays = days
+	}
+
+	page, pageSize := getPaginationParams(r)
+	coaches, totalCount, err := h.service.GetCoachDirectory(r.Context(), filter, page, pageSize)
+	if err != nil {
+		var errInvalidCoachDirectoryFilter *service.ErrInvalidCoachDirectoryFilter
+		if errors.As(err, &errInvalidCoachDirectoryFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	totalPages := (totalCount + pageSize - 1) / pageSize
+	response := model.Paginated[model.CoachDirectoryEntry]{
+		Data:       coaches,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *CoachProfileHandler) UpdateCoachProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var req model.CoachProfile
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.service.UpdateCoachProfile(r.Context(), userID, req)
+	if err != nil {
+		var errNotAuthorized *service.ErrNotAuthorized
+		var errInvalidTimeZone *service.ErrInvalidTimeZone
+		var errInvalidWorkingHours *service.ErrInvalidWorkingHours
+		var errInvalidCoachProfile *service.ErrInvalidCoachProfile
+		switch {
+		case errors.As(err, &errNotAuthorized):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.As(err, &errInvalidTimeZone),
+			errors.As(err, &errInvalidWorkingHours),
+			errors.As(err, &errInvalidCoachProfile):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(profile)
+}
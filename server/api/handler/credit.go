@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+)
+
+type CreditHandler struct {
+	service *service.CreditService
+}
+
+func NewCreditHandler(service *service.CreditService) *CreditHandler {
+	return &CreditHandler{service: service}
+}
+
+// GrantCredits adds credits to a student's balance, e.g. when they buy a
+// session package.
+func (h *CreditHandler) GrantCredits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		StudentID uuid.UUID `json:"studentId"`
+		Amount    int       `json:"amount"`
+		Note      string    `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.GrantCredits(r.Context(), userID, req.StudentID, req.Amount, req.Note)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetBalance returns the caller's credit balance. Coaches pass a studentId
+// query parameter to look up a student.
+func (h *CreditHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, studentID, ok := creditSubject(w, r)
+	if !ok {
+		return
+	}
+
+	balance, err := h.service.GetBalance(r.Context(), userID, studentID)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(balance)
+}
+
+// GetLedger returns the credit history behind GetBalance, newest first.
+func (h *CreditHandler) GetLedger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, studentID, ok := creditSubject(w, r)
+	if !ok {
+		return
+	}
+
+	page, pageSize := getPaginationParams(r)
+	entries, totalCount, err := h.service.GetLedger(r.Context(), userID, studentID, page, pageSize)
+	if err != nil {
+		writeCreditError(w, err)
+		return
+	}
+	totalPages := (totalCount + pageSize - 1) / pageSize
+	response := model.Paginated[model.CreditLedgerEntry]{
+		Data:       entries,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// creditSubject returns the caller and the student whose credits they asked
+// for, which defaults to the caller. It writes the error response itself.
+func creditSubject(w http.ResponseWriter, r *http.Request) (userID, studentID uuid.UUID, ok bool) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+	studentID = userID
+	if studentIDStr := r.URL.Query().Get("studentId"); studentIDStr != "" {
+		studentID, err = uuid.Parse(studentIDStr)
+		if err != nil {
+			http.Error(w, "Invalid student ID", http.StatusBadRequest)
+			return uuid.Nil, uuid.Nil, false
+		}
+	}
+	return userID, studentID, true
+}
+
+// writeCreditError maps credit failures to HTTP status codes.
+func writeCreditError(w http.ResponseWriter, err error) {
+	var errNotAuthorized *service.ErrNotAuthorized
+	var errNotStudent *service.ErrNotStudent
+	var errInvalidCreditGrant *service.ErrInvalidCreditGrant
+	switch {
+	case errors.As(err, &errNotAuthorized), errors.As(err, &errNotStudent):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errInvalidCreditGrant):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
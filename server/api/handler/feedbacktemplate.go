@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type FeedbackTemplateHandler struct {
+	service *service.FeedbackTemplateService
+}
+
+func NewFeedbackTemplateHandler(service *service.FeedbackTemplateService) *FeedbackTemplateHandler {
+	return &FeedbackTemplateHandler{service: service}
+}
+
+// CreateTemplate lets a program lead define a feedback template. Fields are
+// numbered in the order they are sent.
+func (h *FeedbackTemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Name        string                        `json:"name"`
+		Description string                        `json:"description"`
+		Fields      []model.FeedbackTemplateField `json:"fields"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.CreateTemplate(r.Context(), userID, model.FeedbackTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		Fields:      req.Fields,
+	})
+	if err != nil {
+		writeFeedbackTemplateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// GetTemplates lists the active templates, or every template with
+// includeArchived=true.
+func (h *FeedbackTemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := middleware.GetUserID(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	includeArchived := r.URL.Query().Get("includeArchived") == "true"
+
+	templates, err := h.service.GetTemplates(r.Context(), includeArchived)
+	if err != nil {
+		writeFeedbackTemplateError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(templates)
+}
+
+func (h *FeedbackTemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := middleware.GetUserID(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	templateID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.GetTemplate(r.Context(), templateID)
+	if err != nil {
+		writeFeedbackTemplateError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *FeedbackTemplateHandler) ArchiveTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	templateID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ArchiveTemplate(r.Context(), userID, templateID); err != nil {
+		writeFeedbackTemplateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTemplateReport reports the answers to each field of the template. It
+// takes the same from, to, coachId and studentId parameters as satisfaction
+// analytics.
+func (h *FeedbackTemplateHandler) GetTemplateReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	templateID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+	filter, err := getAnalyticsFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetTemplateReport(r.Context(), userID, templateID, filter)
+	if err != nil {
+		writeFeedbackTemplateError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeFeedbackTemplateError maps template failures to HTTP status codes.
+func writeFeedbackTemplateError(w http.ResponseWriter, err error) {
+	var errNotAuthorized *service.ErrNotAuthorized
+	var errFeedbackTemplateNotFound *service.ErrFeedbackTemplateNotFound
+	var errInvalidFeedbackTemplate *service.ErrInvalidFeedbackTemplate
+	var errInvalidAnalyticsFilter *service.ErrInvalidAnalyticsFilter
+	switch {
+	case errors.As(err, &errNotAuthorized):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errFeedbackTemplateNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errInvalidFeedbackTemplate), errors.As(err, &errInvalidAnalyticsFilter):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
 	"github.com/cargoreligion/booking/server/service"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -25,9 +28,11 @@ func (h *SessionFeedbackHandler) CreateSessionFeedback(w http.ResponseWriter, r
 		return
 	}
 	var req struct {
-		SlotID       uuid.UUID `json:"slotId"`
-		Satisfaction int       `json:"satisfaction"`
-		Notes        string    `json:"notes"`
+		SlotID       uuid.UUID  `json:"slotId"`
+		StudentID    *uuid.UUID `json:"studentId"`
+		Satisfaction int        `json:"satisfaction"`
+		Notes        string     `json:"notes"`
+		rubricRequest
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -37,11 +42,82 @@ func (h *SessionFeedbackHandler) CreateSessionFeedback(w http.ResponseWriter, r
 		http.Error(w, "Satisfaction must be between 1 and 5", http.StatusBadRequest)
 		return
 	}
-	if err := h.service.CreateSessionFeedback(userID, req.SlotID, req.Satisfaction, req.Notes); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	rubric, err := req.rubric()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	feedback, err := h.service.CreateSessionFeedback(r.Context(), userID, req.SlotID, req.StudentID, req.Satisfaction, req.Notes, rubric)
+	if err != nil {
+		writeSessionFeedbackError(w, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(feedback)
+}
+
+// UpdateSessionFeedback replaces the satisfaction and notes of existing
+// feedback, and its template answers when given. Earlier versions stay in its
+// revision history.
+func (h *SessionFeedbackHandler) UpdateSessionFeedback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	feedbackID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid feedback ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Satisfaction int    `json:"satisfaction"`
+		Notes        string `json:"notes"`
+		rubricRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Satisfaction < 1 || req.Satisfaction > 5 {
+		http.Error(w, "Satisfaction must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+	rubric, err := req.rubric()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	feedback, err := h.service.UpdateSessionFeedback(r.Context(), userID, feedbackID, req.Satisfaction, req.Notes, rubric)
+	if err != nil {
+		writeSessionFeedbackError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(feedback)
+}
+
+func (h *SessionFeedbackHandler) GetFeedbackRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	feedbackID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid feedback ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.service.GetFeedbackRevisions(r.Context(), userID, feedbackID)
+	if err != nil {
+		writeSessionFeedbackError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(revisions)
 }
 
 func (h *SessionFeedbackHandler) GetPastSessionFeedbacks(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +126,7 @@ func (h *SessionFeedbackHandler) GetPastSessionFeedbacks(w http.ResponseWriter,
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-	feedbacks, err := h.service.GetPastSessionFeedbacks(userID)
+	feedbacks, err := h.service.GetPastSessionFeedbacks(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -65,7 +141,7 @@ func (h *SessionFeedbackHandler) GetStudentsWithSessionsByCoach(w http.ResponseW
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-	students, err := h.service.GetStudentsWithSessionsByCoach(userID)
+	students, err := h.service.GetStudentsWithSessionsByCoach(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -86,10 +162,153 @@ func (h *SessionFeedbackHandler) GetSessionsForStudent(w http.ResponseWriter, r
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessions, err := h.service.GetSessionsForStudent(studentId, userID)
+	sessions, err := h.service.GetSessionsForStudent(r.Context(), studentId, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	json.NewEncoder(w).Encode(sessions)
 }
+
+// SearchSessionNotes searches the calling coach's session notes for q,
+// returning a page of ranked matches.
+func (h *SessionFeedbackHandler) SearchSessionNotes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	page, pageSize := getPaginationParams(r)
+
+	results, totalCount, err := h.service.SearchSessionNotes(r.Context(), userID, r.URL.Query().Get("q"), page, pageSize)
+	if err != nil {
+		writeSessionFeedbackError(w, err)
+		return
+	}
+	totalPages := (totalCount + pageSize - 1) / pageSize
+	response := model.Paginated[model.FeedbackSearchResult]{
+		Data:       results,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetSatisfactionAnalytics reports satisfaction trends. from and to are
+// dates in YYYY-MM-DD format, both included; coachId and studentId narrow
+// the report.
+func (h *SessionFeedbackHandler) GetSatisfactionAnalytics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := getAnalyticsFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := h.service.GetSatisfactionAnalytics(r.Context(), userID, filter)
+	if err != nil {
+		writeSessionFeedbackError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(analytics)
+}
+
+// rubricRequest holds the template answers that can accompany feedback.
+type rubricRequest struct {
+	TemplateID *uuid.UUID                      `json:"templateId"`
+	Answers    map[string]model.FeedbackAnswer `json:"answers"`
+}
+
+func (req rubricRequest) rubric() (*model.RubricSubmission, error) {
+	if req.TemplateID == nil {
+		if len(req.Answers) > 0 {
+			return nil, errors.New("answers need a templateId")
+		}
+		return nil, nil
+	}
+	return &model.RubricSubmission{TemplateID: *req.TemplateID, Answers: req.Answers}, nil
+}
+
+// getAnalyticsFilter reads the from, to, coachId and studentId query
+// parameters shared by feedback reports. Dates are in YYYY-MM-DD format and
+// to is included.
+func getAnalyticsFilter(r *http.Request) (model.SatisfactionAnalyticsFilter, error) {
+	query := r.URL.Query()
+	var filter model.SatisfactionAnalyticsFilter
+	if from := query.Get("from"); from != "" {
+		day, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return filter, errors.New("from must be a date in YYYY-MM-DD format")
+		}
+		filter.From = day
+	}
+	if to := query.Get("to"); to != "" {
+		day, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			return filter, errors.New("to must be a date in YYYY-MM-DD format")
+		}
+		filter.To = day.AddDate(0, 0, 1)
+	}
+	for name, dest := range map[string]**uuid.UUID{"coachId": &filter.CoachID, "studentId": &filter.StudentID} {
+		if value := query.Get(name); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return filter, errors.New("Invalid " + name)
+			}
+			*dest = &id
+		}
+	}
+	return filter, nil
+}
+
+// writeSessionFeedbackError maps feedback failures to HTTP status codes.
+func writeSessionFeedbackError(w http.ResponseWriter, err error) {
+	var errNotAuthorized *service.ErrNotAuthorized
+	var errSlotNotAssignedToCoach *service.ErrSlotNotAssignedToCoach
+	var errSlotNotFound *service.ErrSlotNotFound
+	var errBookingNotFound *service.ErrBookingNotFound
+	var errFeedbackNotFound *service.ErrFeedbackNotFound
+	var errSlotNotBooked *service.ErrSlotNotBooked
+	var errSessionNotEnded *service.ErrSessionNotEnded
+	var errNoShowSession *service.ErrNoShowSession
+	var errFeedbackExists *service.ErrFeedbackExists
+	var errStudentRequired *service.ErrStudentRequired
+	var errInvalidAnalyticsFilter *service.ErrInvalidAnalyticsFilter
+	var errFeedbackTemplateNotFound *service.ErrFeedbackTemplateNotFound
+	var errFeedbackTemplateArchived *service.ErrFeedbackTemplateArchived
+	var errInvalidFeedbackTemplate *service.ErrInvalidFeedbackTemplate
+	var errInvalidFeedbackResponse *service.ErrInvalidFeedbackResponse
+	var errInvalidSearchQuery *service.ErrInvalidSearchQuery
+	switch {
+	case errors.As(err, &errNotAuthorized), errors.As(err, &errSlotNotAssignedToCoach):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errSlotNotFound),
+		errors.As(err, &errBookingNotFound),
+		errors.As(err, &errFeedbackNotFound),
+		errors.As(err, &errFeedbackTemplateNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errSlotNotBooked),
+		errors.As(err, &errSessionNotEnded),
+		errors.As(err, &errNoShowSession),
+		errors.As(err, &errFeedbackExists),
+		errors.As(err, &errFeedbackTemplateArchived):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &errStudentRequired),
+		errors.As(err, &errInvalidAnalyticsFilter),
+		errors.As(err, &errInvalidFeedbackTemplate),
+		errors.As(err, &errInvalidFeedbackResponse),
+		errors.As(err, &errInvalidSearchQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
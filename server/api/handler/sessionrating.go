@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type SessionRatingHandler struct {
+	service *service.SessionRatingService
+}
+
+func NewSessionRatingHandler(service *service.SessionRatingService) *SessionRatingHandler {
+	return &SessionRatingHandler{service: service}
+}
+
+// RateSession lets a student rate a session they attended.
+func (h *SessionRatingHandler) RateSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		SlotID   uuid.UUID `json:"slotId"`
+		Rating   int       `json:"rating"`
+		Comments string    `json:"comments"`
+		Useful   *bool     `json:"useful"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Useful == nil {
+		http.Error(w, "Say whether the session was useful", http.StatusBadRequest)
+		return
+	}
+
+	rating, err := h.service.RateSession(r.Context(), userID, req.SlotID, req.Rating, req.Comments, *req.Useful)
+	if err != nil {
+		writeSessionRatingError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rating)
+}
+
+// GetMyRatings returns the ratings the calling student has given.
+func (h *SessionRatingHandler) GetMyRatings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	page, pageSize := getPaginationParams(r)
+	ratings, totalCount, err := h.service.GetRatingsByStudent(r.Context(), userID, page, pageSize)
+	if err != nil {
+		writeSessionRatingError(w, err)
+		return
+	}
+	totalPages := (totalCount + pageSize - 1) / pageSize
+	response := model.Paginated[model.SessionRating]{
+		Data:       ratings,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCoachRatingSummary returns a coach's aggregated ratings.
+func (h *SessionRatingHandler) GetCoachRatingSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	coachID, err := uuid.Parse(mux.Vars(r)["coachId"])
+	if err != nil {
+		http.Error(w, "Invalid coach ID", http.StatusBadRequest)
+		return
+	}
+	summary, err := h.service.GetCoachRatingSummary(r.Context(), coachID)
+	if err != nil {
+		writeSessionRatingError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(summary)
+}
+
+// writeSessionRatingError maps rating failures to HTTP status codes.
+func writeSessionRatingError(w http.ResponseWriter, err error) {
+	var errNotStudent *service.ErrNotStudent
+	var errNotCoach *service.ErrNotCoach
+	var errSlotNotFound *service.ErrSlotNotFound
+	var errBookingNotFound *service.ErrBookingNotFound
+	var errSlotNotBooked *service.ErrSlotNotBooked
+	var errSessionNotEnded *service.ErrSessionNotEnded
+	var errNoShowSession *service.ErrNoShowSession
+	var errSessionAlreadyRated *service.ErrSessionAlreadyRated
+	var errInvalidSessionRating *service.ErrInvalidSessionRating
+	switch {
+	case errors.As(err, &errNotStudent):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errSlotNotFound),
+		errors.As(err, &errBookingNotFound),
+		errors.As(err, &errNotCoach):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errSlotNotBooked),
+		errors.As(err, &errSessionNotEnded),
+		errors.As(err, &errNoShowSession),
+		errors.As(err, &errSessionAlreadyRated):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &errInvalidSessionRating):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type SessionTypeHandler struct {
+	service *service.SessionTypeService
+}
+
+func NewSessionTypeHandler(service *service.SessionTypeService) *SessionTypeHandler {
+	return &SessionTypeHandler{service: service}
+}
+
+func (h *SessionTypeHandler) CreateSessionType(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Name            string `json:"name"`
+		DurationMinutes int    `json:"durationMinutes"`
+		Description     string `json:"description"`
+		PriceCents      int    `json:"priceCents"`
+		Currency        string `json:"currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sessionType, err := h.service.CreateSessionType(r.Context(), userID, req.Name, req.DurationMinutes, req.Description, req.PriceCents, req.Currency)
+	if err != nil {
+		var errNotAuthorized *service.ErrNotAuthorized
+		var errInvalidSessionType *service.ErrInvalidSessionType
+		switch {
+		case errors.As(err, &errNotAuthorized):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.As(err, &errInvalidSessionType):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sessionType)
+}
+
+func (h *SessionTypeHandler) GetSessionTypesForCoach(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	coachID, err := uuid.Parse(mux.Vars(r)["coachId"])
+	if err != nil {
+		http.Error(w, "Invalid coach ID", http.StatusBadRequest)
+		return
+	}
+
+	sessionTypes, err := h.service.GetSessionTypesForCoach(r.Context(), coachID)
+	if err != nil {
+		var errNotCoach *service.ErrNotCoach
+		if errors.As(err, &errNotCoach) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(sessionTypes)
+}
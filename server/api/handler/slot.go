@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cargoreligion/booking/server/api/middleware"
@@ -31,31 +33,19 @@ func (h *SlotHandler) CreateSlot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		StartTime time.Time `json:"startTime"`
+		StartTime     time.Time  `json:"startTime"`
+		EndTime       *time.Time `json:"endTime"`
+		SessionTypeID *uuid.UUID `json:"sessionTypeId"`
+		Capacity      int        `json:"capacity"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	id, err := h.service.CreateSlot(userID, req.StartTime)
+	id, err := h.service.CreateSlot(r.Context(), userID, req.StartTime, req.SessionTypeID, req.EndTime, req.Capacity)
 	if err != nil {
-		switch err.Error() {
-		case "only coaches can create slots":
-			http.Error(w, err.Error(), http.StatusForbidden)
-		case "slot overlaps with an existing slot":
-			http.Error(w, err.Error(), http.StatusConflict)
-		case "cannot create a slot in the past":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case "slot must start at 15-minute increments (e.g., 9:00, 9:15, 9:30, 9:45)":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case "slots must be between 9 AM and 5 PM":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case "slots must end by 5 PM":
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeCreateSlotError(w, err)
 		return
 	}
 
@@ -69,6 +59,82 @@ func (h *SlotHandler) CreateSlot(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *SlotHandler) UpdateSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		StartTime     time.Time  `json:"startTime"`
+		EndTime       *time.Time `json:"endTime"`
+		SessionTypeID *uuid.UUID `json:"sessionTypeId"`
+		Capacity      int        `json:"capacity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateSlot(r.Context(), userID, slotID, req.StartTime, req.SessionTypeID, req.EndTime, req.Capacity); err != nil {
+		writeSlotChangeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *SlotHandler) DeleteSlot(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSlot(r.Context(), userID, slotID); err != nil {
+		writeSlotChangeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SlotHandler) CoachCancelBooking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.CoachCancelBooking(r.Context(), userID, slotID, req.Reason); err != nil {
+		writeSlotChangeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 func (h *SlotHandler) GetUpcomingSlots(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userID, err := middleware.GetUserID(r.Context())
@@ -77,7 +143,7 @@ func (h *SlotHandler) GetUpcomingSlots(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	page, pageSize := getPaginationParams(r)
-	paginatedSlots, totalCount, err := h.service.GetUpcomingSlots(userID, page, pageSize)
+	paginatedSlots, totalCount, err := h.service.GetUpcomingSlots(r.Context(), userID, page, pageSize)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -102,8 +168,13 @@ func (h *SlotHandler) GetAvailableSlots(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid coach ID", http.StatusBadRequest)
 		return
 	}
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
 	page, pageSize := getPaginationParams(r)
-	paginatedSlots, totalCount, err := h.service.GetAvailableSlots(coachId, page, pageSize)
+	paginatedSlots, totalCount, err := h.service.GetAvailableSlots(r.Context(), userID, coachId, page, pageSize)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -119,6 +190,48 @@ func (h *SlotHandler) GetAvailableSlots(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
+// SearchAvailableSlots returns bookable slots across all coaches. Query
+// parameters, all optional:
+//
+//	from, to                     RFC 3339 bounds on the start time
+//	weekday                      comma-separated days, e.g. "thu" or "mon,wed"
+//	timeFrom, timeTo             "HH:MM" window the session must fit in
+//	tz                           IANA zone for weekday and time of day (UTC)
+//	minDuration, maxDuration     session length in minutes
+//	coachId                      comma-separated coach IDs
+//	coachName, sessionType       case-insensitive substring matches
+//	sort                         "earliest" (default) or "latest"
+func (h *SlotHandler) SearchAvailableSlots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	criteria, err := parseSlotSearchCriteria(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, pageSize := getPaginationParams(r)
+	paginatedSlots, totalSlots, err := h.service.SearchAvailableSlots(r.Context(), criteria, page, pageSize)
+	if err != nil {
+		var errInvalidSlotSearch *service.ErrInvalidSlotSearch
+		var errInvalidTimeZone *service.ErrInvalidTimeZone
+		if errors.As(err, &errInvalidSlotSearch) || errors.As(err, &errInvalidTimeZone) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	totalPages := (totalSlots + pageSize - 1) / pageSize
+	response := model.Paginated[model.Slot]{
+		Data:       paginatedSlots,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalSlots,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *SlotHandler) BookSlot(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userID, err := middleware.GetUserID(r.Context())
@@ -131,13 +244,179 @@ func (h *SlotHandler) BookSlot(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
 		return
 	}
-	if err := h.service.BookSlot(slotID, userID); err != nil {
+	booking, err := h.service.BookSlot(r.Context(), slotID, userID)
+	if err != nil {
+		writeBookSlotError(w, err)
+		return
+	}
+	// A booking request still needs the coach's approval
+	if booking.Status == model.BookingStatusPending {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(booking)
+}
+
+// HoldSlot reserves a seat for the caller while they check out. The
+// response's expiresAt says when the hold lapses.
+func (h *SlotHandler) HoldSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := h.service.HoldSlot(r.Context(), slotID, userID)
+	if err != nil {
+		var errCheckoutHoldExists *service.ErrCheckoutHoldExists
+		if errors.As(err, &errCheckoutHoldExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeBookSlotError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(booking)
+}
+
+func (h *SlotHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ReleaseHold(r.Context(), slotID, userID); err != nil {
+		var errSlotNotFound *service.ErrSlotNotFound
+		var errBookingNotFound *service.ErrBookingNotFound
+		if errors.As(err, &errSlotNotFound) || errors.As(err, &errBookingNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SlotHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is allowed
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.CancelBooking(r.Context(), slotID, userID, req.Reason); err != nil {
+		var errSlotNotFound *service.ErrSlotNotFound
+		var errBookingNotFound *service.ErrBookingNotFound
+		var errCancellationTooLate *service.ErrCancellationTooLate
+		var errInvalidSlotTransition *service.ErrInvalidSlotTransition
+		switch {
+		case errors.As(err, &errSlotNotFound), errors.As(err, &errBookingNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.As(err, &errCancellationTooLate), errors.As(err, &errInvalidSlotTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *SlotHandler) RescheduleBooking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	slotID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		TargetSlotID uuid.UUID `json:"targetSlotId"`
+		Reason       string    `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RescheduleBooking(r.Context(), userID, slotID, req.TargetSlotID, req.Reason); err != nil {
+		var errBookingNotFound *service.ErrBookingNotFound
+		var errSlotStarted *service.ErrSlotStarted
+		switch {
+		case errors.As(err, &errBookingNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.As(err, &errSlotStarted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			writeBookSlotError(w, err)
+		}
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
+func (h *SlotHandler) GetBookingHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	page, pageSize := getPaginationParams(r)
+	entries, totalCount, err := h.service.GetBookingHistory(r.Context(), userID, page, pageSize)
+	if err != nil {
+		var errNotAuthorized *service.ErrNotAuthorized
+		if errors.As(err, &errNotAuthorized) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	totalPages := (totalCount + pageSize - 1) / pageSize
+	response := model.Paginated[model.BookingHistory]{
+		Data:       entries,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *SlotHandler) GetUpcomingBookingsForStudent(w http.ResponseWriter, r *http.Request) {
 	userID, err := middleware.GetUserID(r.Context())
 	if err != nil {
@@ -145,7 +424,7 @@ func (h *SlotHandler) GetUpcomingBookingsForStudent(w http.ResponseWriter, r *ht
 		return
 	}
 	page, pageSize := getPaginationParams(r)
-	paginatedSlots, totalCount, err := h.service.GetUpcomingBookingsForStudent(userID, page, pageSize)
+	paginatedSlots, totalCount, err := h.service.GetUpcomingBookingsForStudent(r.Context(), userID, page, pageSize)
 	if err != nil {
 		fmt.Println(err.Error())
 		var errNotStudent *service.ErrNotStudent
@@ -183,7 +462,7 @@ func (h *SlotHandler) GetSlotDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slotDetails, err := h.service.GetSlotDetails(userID, slotID)
+	slotDetails, err := h.service.GetSlotDetails(r.Context(), userID, slotID)
 	if err != nil {
 		var errNotAuthorized *service.ErrNotAuthorized
 		if errors.As(err, &errNotAuthorized) {
@@ -198,6 +477,181 @@ func (h *SlotHandler) GetSlotDetails(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(slotDetails)
 }
 
+// writeBookSlotError maps booking failures to HTTP status codes.
+func writeBookSlotError(w http.ResponseWriter, err error) {
+	var errNotStudent *service.ErrNotStudent
+	var errSlotNotFound *service.ErrSlotNotFound
+	var errSlotAlreadyBooked *service.ErrSlotAlreadyBooked
+	var errOverlappingBooking *service.ErrOverlappingBooking
+	var errPastSlot *service.ErrPastSlot
+	var errInvalidSlotTransition *service.ErrInvalidSlotTransition
+	var errInsufficientCredits *service.ErrInsufficientCredits
+	var errPaymentFailed *service.ErrPaymentFailed
+	var errBookingTooSoon *service.ErrBookingTooSoon
+	var errBookingTooFarAhead *service.ErrBookingTooFarAhead
+	var errTooManyNoShows *service.ErrTooManyNoShows
+	switch {
+	case errors.As(err, &errNotStudent), errors.As(err, &errTooManyNoShows):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errSlotNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errInsufficientCredits), errors.As(err, &errPaymentFailed):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.As(err, &errSlotAlreadyBooked),
+		errors.As(err, &errOverlappingBooking),
+		errors.As(err, &errInvalidSlotTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &errPastSlot),
+		errors.As(err, &errBookingTooSoon),
+		errors.As(err, &errBookingTooFarAhead):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeSlotChangeError maps failures of coach edits, deletes and
+// cancellations to HTTP status codes.
+func writeSlotChangeError(w http.ResponseWriter, err error) {
+	var errSlotNotFound *service.ErrSlotNotFound
+	var errSlotNotAssignedToCoach *service.ErrSlotNotAssignedToCoach
+	var errSlotBooked *service.ErrSlotBooked
+	var errSlotNotBooked *service.ErrSlotNotBooked
+	var errSlotStarted *service.ErrSlotStarted
+	var errReasonRequired *service.ErrReasonRequired
+	var errSlotNotOpen *service.ErrSlotNotOpen
+	var errInvalidSlotTransition *service.ErrInvalidSlotTransition
+	switch {
+	case errors.As(err, &errSlotNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errSlotNotAssignedToCoach):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errSlotBooked),
+		errors.As(err, &errSlotNotBooked),
+		errors.As(err, &errSlotStarted),
+		errors.As(err, &errSlotNotOpen),
+		errors.As(err, &errInvalidSlotTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &errReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeCreateSlotError(w, err)
+	}
+}
+
+// writeCreateSlotError maps slot validation failures to HTTP status codes.
+func writeCreateSlotError(w http.ResponseWriter, err error) {
+	var errNotAuthorized *service.ErrNotAuthorized
+	var errSessionTypeNotFound *service.ErrSessionTypeNotFound
+	var errInvalidSlotDuration *service.ErrInvalidSlotDuration
+	var errSlotStartInPast *service.ErrSlotStartInPast
+	var errSlotStartIncrement *service.ErrSlotStartIncrement
+	var errOutsideWorkingHours *service.ErrOutsideWorkingHours
+	var errOverlappingSlot *service.ErrOverlappingSlot
+	var errInvalidCapacity *service.ErrInvalidCapacity
+	switch {
+	case errors.As(err, &errNotAuthorized):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errSessionTypeNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errOverlappingSlot):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &errInvalidSlotDuration),
+		errors.As(err, &errSlotStartInPast),
+		errors.As(err, &errSlotStartIncrement),
+		errors.As(err, &errOutsideWorkingHours),
+		errors.As(err, &errInvalidCapacity):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func parseSlotSearchCriteria(r *http.Request) (model.SlotSearchCriteria, error) {
+	query := r.URL.Query()
+	criteria := model.SlotSearchCriteria{
+		TimeZone:        query.Get("tz"),
+		CoachName:       strings.TrimSpace(query.Get("coachName")),
+		SessionTypeName: strings.TrimSpace(query.Get("sessionType")),
+		Sort:            model.SlotSearchSort(query.Get("sort")),
+	}
+
+	for name, dest := range map[string]**time.Time{"from": &criteria.From, "to": &criteria.To} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return criteria, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*dest = &t
+		}
+	}
+
+	for name, dest := range map[string]**int{"timeFrom": &criteria.StartMinute, "timeTo": &criteria.EndMinute} {
+		if value := query.Get(name); value != "" {
+			minute, err := parseTimeOfDay(value)
+			if err != nil {
+				return criteria, fmt.Errorf("%s must be a time in HH:MM format", name)
+			}
+			*dest = &minute
+		}
+	}
+
+	for name, dest := range map[string]*int{"minDuration": &criteria.MinDurationMinutes, "maxDuration": &criteria.MaxDurationMinutes} {
+		if value := query.Get(name); value != "" {
+			minutes, err := strconv.Atoi(value)
+			if err != nil {
+				return criteria, fmt.Errorf("%s must be a number of minutes", name)
+			}
+			*dest = minutes
+		}
+	}
+
+	if value := query.Get("weekday"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			weekday, ok := weekdaysByName[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return criteria, fmt.Errorf("unknown weekday %q", name)
+			}
+			criteria.Weekdays = append(criteria.Weekdays, weekday)
+		}
+	}
+
+	if value := query.Get("coachId"); value != "" {
+		for _, idStr := range strings.Split(value, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(idStr))
+			if err != nil {
+				return criteria, fmt.Errorf("invalid coach ID %q", idStr)
+			}
+			criteria.CoachIDs = append(criteria.CoachIDs, id)
+		}
+	}
+
+	return criteria, nil
+}
+
+var weekdaysByName = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseTimeOfDay turns "HH:MM" into minutes after midnight. "24:00" is
+// accepted as the end of the day.
+func parseTimeOfDay(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
 func getPaginationParams(r *http.Request) (page, pageSize int) {
 	// Get page parameter
 	pageStr := r.URL.Query().Get("page")
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cargoreligion/booking/server/api/middleware"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type WaitlistHandler struct {
+	service *service.WaitlistService
+}
+
+func NewWaitlistHandler(service *service.WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{service: service}
+}
+
+// JoinWaitlist accepts either a slotId, to wait for a seat in that slot, or a
+// coachId and a week (any date in it, as YYYY-MM-DD), to wait for any slot
+// with the coach that week.
+func (h *WaitlistHandler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		SlotID  *uuid.UUID `json:"slotId"`
+		CoachID *uuid.UUID `json:"coachId"`
+		Week    string     `json:"week"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var entry *model.WaitlistEntry
+	switch {
+	case req.SlotID != nil && req.CoachID == nil && req.Week == "":
+		entry, err = h.service.JoinSlotWaitlist(r.Context(), userID, *req.SlotID)
+	case req.SlotID == nil && req.CoachID != nil && req.Week != "":
+		week, parseErr := time.Parse(time.DateOnly, req.Week)
+		if parseErr != nil {
+			http.Error(w, "week must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+			return
+		}
+		entry, err = h.service.JoinWeekWaitlist(r.Context(), userID, *req.CoachID, week)
+	default:
+		http.Error(w, "Specify either slotId, or coachId and week", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeWaitlistError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *WaitlistHandler) GetWaitlistEntries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	entries, err := h.service.GetWaitlistEntries(r.Context(), userID)
+	if err != nil {
+		writeWaitlistError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *WaitlistHandler) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	entryID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid waitlist entry ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.LeaveWaitlist(r.Context(), userID, entryID); err != nil {
+		writeWaitlistError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WaitlistHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	page, pageSize := getPaginationParams(r)
+	promotions, totalCount, err := h.service.GetPromotions(r.Context(), userID, page, pageSize)
+	if err != nil {
+		writeWaitlistError(w, err)
+		return
+	}
+	totalPages := (totalCount + pageSize - 1) / pageSize
+	response := model.Paginated[model.WaitlistPromotion]{
+		Data:       promotions,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		TotalCount: totalCount,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *WaitlistHandler) ConfirmPromotion(w http.ResponseWriter, r *http.Request) {
+	h.answerPromotion(w, r, h.service.ConfirmPromotion)
+}
+
+func (h *WaitlistHandler) DeclinePromotion(w http.ResponseWriter, r *http.Request) {
+	h.answerPromotion(w, r, h.service.DeclinePromotion)
+}
+
+func (h *WaitlistHandler) answerPromotion(w http.ResponseWriter, r *http.Request, answer func(ctx context.Context, studentID, promotionID uuid.UUID) error) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	promotionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := answer(r.Context(), userID, promotionID); err != nil {
+		writeWaitlistError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeWaitlistError maps waitlist failures to HTTP status codes.
+func writeWaitlistError(w http.ResponseWriter, err error) {
+	var errNotStudent *service.ErrNotStudent
+	var errNotCoach *service.ErrNotCoach
+	var errSlotNotFound *service.ErrSlotNotFound
+	var errWaitlistEntryNotFound *service.ErrWaitlistEntryNotFound
+	var errPromotionNotFound *service.ErrPromotionNotFound
+	var errSlotHasSeats *service.ErrSlotHasSeats
+	var errAlreadyWaitlisted *service.ErrAlreadyWaitlisted
+	var errPromotionClosed *service.ErrPromotionClosed
+	var errPastSlot *service.ErrPastSlot
+	var errInvalidWaitlistRequest *service.ErrInvalidWaitlistRequest
+	var errInsufficientCredits *service.ErrInsufficientCredits
+	var errPaymentFailed *service.ErrPaymentFailed
+	var errTooManyNoShows *service.ErrTooManyNoShows
+	switch {
+	case errors.As(err, &errNotStudent), errors.As(err, &errTooManyNoShows):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &errSlotNotFound),
+		errors.As(err, &errWaitlistEntryNotFound),
+		errors.As(err, &errPromotionNotFound),
+		errors.As(err, &errNotCoach):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &errSlotHasSeats),
+		errors.As(err, &errAlreadyWaitlisted),
+		errors.As(err, &errPromotionClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &errInsufficientCredits), errors.As(err, &errPaymentFailed):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.As(err, &errPastSlot), errors.As(err, &errInvalidWaitlistRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/infrastructure/scheduler"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/cargoreligion/booking/server/service"
+)
+
+// RegisterJobs registers the services' background jobs with sched. It only
+// registers them; the caller decides when, and whether, to start sched.
+func RegisterJobs(sched *scheduler.Scheduler, dbc db.DbClient, paymentProvider service.PaymentProvider) {
+	userRepo := repository.NewUserRepository(dbc)
+	sessionTypeRepo := repository.NewSessionTypeRepository(dbc)
+	coachProfileRepo := repository.NewCoachProfileRepository(dbc)
+	bookingHistoryRepo := repository.NewBookingHistoryRepository(dbc)
+	paymentRepo := repository.NewPaymentRepository(dbc)
+	bookingRepo := repository.NewBookingRepository(dbc)
+	slotRepo := repository.NewSlotRepository(dbc)
+	waitlistRepo := repository.NewWaitlistRepository(dbc)
+
+	slotService := service.NewSlotService(dbc, slotRepo, bookingRepo, userRepo, sessionTypeRepo, coachProfileRepo, bookingHistoryRepo, paymentRepo, paymentProvider)
+	bookingRequestService := service.NewBookingRequestService(dbc, bookingRepo, userRepo, paymentRepo, paymentProvider)
+	waitlistService := service.NewWaitlistService(dbc, waitlistRepo, slotRepo, bookingRepo, userRepo, coachProfileRepo, paymentRepo, paymentProvider)
+
+	sched.Every("complete-ended-sessions", time.Minute, func(ctx context.Context) error {
+		_, err := slotService.CompleteEndedSessions(ctx)
+		return err
+	})
+	sched.Every("expire-waitlist-promotions", time.Minute, func(ctx context.Context) error {
+		_, err := waitlistService.ExpirePromotions(ctx)
+		return err
+	})
+	sched.Every("expire-booking-requests", time.Minute, func(ctx context.Context) error {
+		_, err := bookingRequestService.ExpireBookingRequests(ctx)
+		return err
+	})
+	// Checkout holds are short, so sweep them more often than the other jobs
+	sched.Every("expire-checkout-holds", 30*time.Second, func(ctx context.Context) error {
+		_, err := slotService.ExpireCheckoutHolds(ctx)
+		return err
+	})
+}
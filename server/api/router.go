@@ -12,33 +12,143 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(dbc db.DbClient) *mux.Router {
+// NewRouter wires the repositories, services and handlers into the HTTP
+// routes. Background jobs are registered separately by RegisterJobs.
+func NewRouter(dbc db.DbClient, paymentProvider service.PaymentProvider) *mux.Router {
 	userRepo := repository.NewUserRepository(dbc)
 	userService := service.NewUserService(userRepo)
 	userHandler := handler.NewUserHandler(userService)
 
+	sessionTypeRepo := repository.NewSessionTypeRepository(dbc)
+	sessionTypeService := service.NewSessionTypeService(sessionTypeRepo, userRepo)
+	sessionTypeHandler := handler.NewSessionTypeHandler(sessionTypeService)
+
+	coachProfileRepo := repository.NewCoachProfileRepository(dbc)
+	coachProfileService := service.NewCoachProfileService(dbc, coachProfileRepo, userRepo)
+	coachProfileHandler := handler.NewCoachProfileHandler(coachProfileService)
+
+	bookingHistoryRepo := repository.NewBookingHistoryRepository(dbc)
+	paymentRepo := repository.NewPaymentRepository(dbc)
+
+	bookingRepo := repository.NewBookingRepository(dbc)
 	slotRepo := repository.NewSlotRepository(dbc)
-	slotService := service.NewSlotService(slotRepo, userRepo)
+	slotService := service.NewSlotService(dbc, slotRepo, bookingRepo, userRepo, sessionTypeRepo, coachProfileRepo, bookingHistoryRepo, paymentRepo, paymentProvider)
 	slotHandler := handler.NewSlotHandler(slotService)
 
+	bookingRequestService := service.NewBookingRequestService(dbc, bookingRepo, userRepo, paymentRepo, paymentProvider)
+	bookingRequestHandler := handler.NewBookingRequestHandler(bookingRequestService)
+
+	attendanceService := service.NewAttendanceService(dbc, bookingRepo, userRepo)
+	attendanceHandler := handler.NewAttendanceHandler(attendanceService)
+
+	creditRepo := repository.NewCreditRepository(dbc)
+	creditService := service.NewCreditService(dbc, creditRepo, bookingRepo, userRepo)
+	creditHandler := handler.NewCreditHandler(creditService)
+
+	waitlistRepo := repository.NewWaitlistRepository(dbc)
+	waitlistService := service.NewWaitlistService(dbc, waitlistRepo, slotRepo, bookingRepo, userRepo, coachProfileRepo, paymentRepo, paymentProvider)
+	waitlistHandler := handler.NewWaitlistHandler(waitlistService)
+
+	availabilityRuleRepo := repository.NewAvailabilityRuleRepository(dbc)
+	availabilityRuleService := service.NewAvailabilityRuleService(dbc, availabilityRuleRepo, userRepo, sessionTypeRepo, coachProfileRepo)
+	availabilityRuleHandler := handler.NewAvailabilityRuleHandler(availabilityRuleService)
+
+	notificationRepo := repository.NewNotificationRepository(dbc)
+	notificationService := service.NewNotificationService(notificationRepo)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+
+	feedbackTemplateRepo := repository.NewFeedbackTemplateRepository(dbc)
+	feedbackTemplateService := service.NewFeedbackTemplateService(dbc, feedbackTemplateRepo, userRepo)
+	feedbackTemplateHandler := handler.NewFeedbackTemplateHandler(feedbackTemplateService)
+
 	sessionRepo := repository.NewSessionFeedbackRepository(dbc)
-	sessionService := service.NewSessionFeedbackService(sessionRepo, slotRepo, userRepo)
+	sessionService := service.NewSessionFeedbackService(dbc, sessionRepo, feedbackTemplateRepo, slotRepo, userRepo)
 	sessionFeedbackHandler := handler.NewSessionFeedbackHandler(sessionService)
+
+	sessionRatingRepo := repository.NewSessionRatingRepository(dbc)
+	sessionRatingService := service.NewSessionRatingService(dbc, sessionRatingRepo, userRepo)
+	sessionRatingHandler := handler.NewSessionRatingHandler(sessionRatingService)
+
 	r := mux.NewRouter()
 
 	// Slot routes
 	r.HandleFunc("/api/slots", slotHandler.CreateSlot).Methods("POST")
 	r.HandleFunc("/api/slots/upcoming", slotHandler.GetUpcomingSlots).Methods("GET")
 	r.HandleFunc("/api/slots/available/{coachId}", slotHandler.GetAvailableSlots).Methods("GET")
+	r.HandleFunc("/api/slots/search", slotHandler.SearchAvailableSlots).Methods("GET")
+	r.HandleFunc("/api/slots/{id}/hold", slotHandler.HoldSlot).Methods("POST")
+	r.HandleFunc("/api/slots/{id}/hold", slotHandler.ReleaseHold).Methods("DELETE")
 	r.HandleFunc("/api/slots/{id}/book", slotHandler.BookSlot).Methods("POST")
+	r.HandleFunc("/api/slots/{id}/cancel", slotHandler.CancelBooking).Methods("POST")
+	r.HandleFunc("/api/slots/{id}/reschedule", slotHandler.RescheduleBooking).Methods("POST")
+	r.HandleFunc("/api/slots/{id}/coach-cancel", slotHandler.CoachCancelBooking).Methods("POST")
+	r.HandleFunc("/api/slots/{id}/attendance", attendanceHandler.MarkAttendance).Methods("PUT")
 	r.HandleFunc("/api/students/bookings", slotHandler.GetUpcomingBookingsForStudent).Methods("GET")
+	r.HandleFunc("/api/bookings/history", slotHandler.GetBookingHistory).Methods("GET")
 	r.HandleFunc("/api/slots/{id}/details", slotHandler.GetSlotDetails).Methods("GET")
+	r.HandleFunc("/api/slots/{id}", slotHandler.UpdateSlot).Methods("PUT")
+	r.HandleFunc("/api/slots/{id}", slotHandler.DeleteSlot).Methods("DELETE")
+
+	// Waitlist routes
+	r.HandleFunc("/api/waitlist", waitlistHandler.JoinWaitlist).Methods("POST")
+	r.HandleFunc("/api/waitlist", waitlistHandler.GetWaitlistEntries).Methods("GET")
+	r.HandleFunc("/api/waitlist/promotions", waitlistHandler.GetPromotions).Methods("GET")
+	r.HandleFunc("/api/waitlist/promotions/{id}/confirm", waitlistHandler.ConfirmPromotion).Methods("POST")
+	r.HandleFunc("/api/waitlist/promotions/{id}/decline", waitlistHandler.DeclinePromotion).Methods("POST")
+	r.HandleFunc("/api/waitlist/{id}", waitlistHandler.LeaveWaitlist).Methods("DELETE")
+
+	// Booking request routes
+	r.HandleFunc("/api/booking-requests", bookingRequestHandler.GetBookingRequests).Methods("GET")
+	r.HandleFunc("/api/booking-requests/{id}/approve", bookingRequestHandler.ApproveBookingRequest).Methods("POST")
+	r.HandleFunc("/api/booking-requests/{id}/decline", bookingRequestHandler.DeclineBookingRequest).Methods("POST")
+
+	// Attendance routes
+	r.HandleFunc("/api/attendance/no-shows", attendanceHandler.GetNoShowSummary).Methods("GET")
+
+	// Credit routes
+	r.HandleFunc("/api/credits", creditHandler.GetBalance).Methods("GET")
+	r.HandleFunc("/api/credits/ledger", creditHandler.GetLedger).Methods("GET")
+	r.HandleFunc("/api/credits/grants", creditHandler.GrantCredits).Methods("POST")
+
+	// Availability rule routes
+	r.HandleFunc("/api/availability-rules", availabilityRuleHandler.CreateAvailabilityRule).Methods("POST")
+	r.HandleFunc("/api/availability-rules", availabilityRuleHandler.GetAvailabilityRules).Methods("GET")
+	r.HandleFunc("/api/availability-rules/{id}", availabilityRuleHandler.UpdateAvailabilityRule).Methods("PUT")
+	r.HandleFunc("/api/availability-rules/{id}", availabilityRuleHandler.DeleteAvailabilityRule).Methods("DELETE")
+
+	// Session type routes
+	r.HandleFunc("/api/session-types", sessionTypeHandler.CreateSessionType).Methods("POST")
+	r.HandleFunc("/api/session-types/{coachId}", sessionTypeHandler.GetSessionTypesForCoach).Methods("GET")
+
+	// Coach profile routes
+	r.HandleFunc("/api/coach-profile", coachProfileHandler.UpdateCoachProfile).Methods("PUT")
+	r.HandleFunc("/api/coach-profile/{coachId}", coachProfileHandler.GetCoachProfile).Methods("GET")
+	r.HandleFunc("/api/coaches", coachProfileHandler.GetCoachDirectory).Methods("GET")
+	r.HandleFunc("/api/coaches/{coachId}/ratings", sessionRatingHandler.GetCoachRatingSummary).Methods("GET")
 
 	// Session feedback routes
 	r.HandleFunc("/api/session-feedback", sessionFeedbackHandler.CreateSessionFeedback).Methods("POST")
 	r.HandleFunc("/api/session-feedback/past", sessionFeedbackHandler.GetPastSessionFeedbacks).Methods("GET")
+	r.HandleFunc("/api/session-feedback/search", sessionFeedbackHandler.SearchSessionNotes).Methods("GET")
+	r.HandleFunc("/api/session-feedback/analytics", sessionFeedbackHandler.GetSatisfactionAnalytics).Methods("GET")
 	r.HandleFunc("/api/session-feedback/studentswithsessions", sessionFeedbackHandler.GetStudentsWithSessionsByCoach).Methods("GET")
 	r.HandleFunc("/api/session-feedback/sessionsforstudent/{studentId}", sessionFeedbackHandler.GetSessionsForStudent).Methods("GET")
+	r.HandleFunc("/api/session-feedback/{id}", sessionFeedbackHandler.UpdateSessionFeedback).Methods("PUT")
+	r.HandleFunc("/api/session-feedback/{id}/revisions", sessionFeedbackHandler.GetFeedbackRevisions).Methods("GET")
+
+	// Feedback template routes
+	r.HandleFunc("/api/feedback-templates", feedbackTemplateHandler.CreateTemplate).Methods("POST")
+	r.HandleFunc("/api/feedback-templates", feedbackTemplateHandler.GetTemplates).Methods("GET")
+	r.HandleFunc("/api/feedback-templates/{id}", feedbackTemplateHandler.GetTemplate).Methods("GET")
+	r.HandleFunc("/api/feedback-templates/{id}/archive", feedbackTemplateHandler.ArchiveTemplate).Methods("POST")
+	r.HandleFunc("/api/feedback-templates/{id}/report", feedbackTemplateHandler.GetTemplateReport).Methods("GET")
+
+	// Session rating routes
+	r.HandleFunc("/api/session-ratings", sessionRatingHandler.RateSession).Methods("POST")
+	r.HandleFunc("/api/session-ratings", sessionRatingHandler.GetMyRatings).Methods("GET")
+
+	// Notification routes
+	r.HandleFunc("/api/notifications", notificationHandler.GetNotifications).Methods("GET")
 
 	// User routes
 	r.HandleFunc("/api/users", userHandler.GetAllUsers).Methods("GET")
@@ -0,0 +1,9 @@
+ALTER TABLE booking_history
+ADD COLUMN new_slot_id UUID;
+
+ALTER TABLE booking_history
+DROP CONSTRAINT check_booking_history_event;
+
+ALTER TABLE booking_history
+ADD CONSTRAINT check_booking_history_event
+CHECK (event IN ('cancelled', 'coach_cancelled', 'rescheduled'));
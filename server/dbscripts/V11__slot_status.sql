@@ -0,0 +1,15 @@
+ALTER TABLE slot
+ADD COLUMN status TEXT NOT NULL DEFAULT 'open';
+
+UPDATE slot
+SET status = CASE
+    WHEN booked AND end_time <= NOW() THEN 'completed'
+    WHEN booked THEN 'booked'
+    ELSE 'open'
+END;
+
+ALTER TABLE slot
+ADD CONSTRAINT check_slot_status
+CHECK (status IN ('open', 'booked', 'completed', 'cancelled', 'no_show'));
+
+CREATE INDEX idx_slot_status_end_time ON slot (status, end_time);
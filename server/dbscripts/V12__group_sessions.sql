@@ -0,0 +1,36 @@
+ALTER TABLE slot
+ADD COLUMN capacity INT NOT NULL DEFAULT 1;
+
+ALTER TABLE slot
+ADD CONSTRAINT check_slot_capacity
+CHECK (capacity >= 1);
+
+CREATE TABLE booking (
+    id UUID PRIMARY KEY,
+    slot_id UUID NOT NULL,
+    student_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE booking
+ADD CONSTRAINT fk_booking_slot
+FOREIGN KEY (slot_id) REFERENCES slot(id) ON DELETE CASCADE;
+
+ALTER TABLE booking
+ADD CONSTRAINT fk_booking_student
+FOREIGN KEY (student_id) REFERENCES stepful_user(id);
+
+ALTER TABLE booking
+ADD CONSTRAINT uq_booking_slot_student
+UNIQUE (slot_id, student_id);
+
+CREATE INDEX idx_booking_student_id ON booking(student_id);
+
+-- Existing one-on-one bookings become the first attendee of their slot
+INSERT INTO booking (id, slot_id, student_id, created_at)
+SELECT gen_random_uuid(), id, student_id, NOW()
+FROM slot
+WHERE student_id IS NOT NULL;
+
+ALTER TABLE slot
+DROP COLUMN student_id;
@@ -0,0 +1,61 @@
+ALTER TABLE booking
+ADD COLUMN status TEXT NOT NULL DEFAULT 'confirmed';
+
+ALTER TABLE booking
+ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE booking
+ADD CONSTRAINT check_booking_status
+CHECK (status IN ('confirmed', 'held'));
+
+CREATE TABLE waitlist_entry (
+    id UUID PRIMARY KEY,
+    student_id UUID NOT NULL,
+    coach_id UUID NOT NULL,
+    slot_id UUID,
+    week_start DATE,
+    status TEXT NOT NULL DEFAULT 'waiting',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT check_waitlist_entry_target CHECK ((slot_id IS NULL) <> (week_start IS NULL)),
+    CONSTRAINT check_waitlist_entry_status CHECK (status IN ('waiting', 'promoted', 'left'))
+);
+
+ALTER TABLE waitlist_entry
+ADD CONSTRAINT fk_waitlist_entry_student
+FOREIGN KEY (student_id) REFERENCES stepful_user(id);
+
+ALTER TABLE waitlist_entry
+ADD CONSTRAINT fk_waitlist_entry_coach
+FOREIGN KEY (coach_id) REFERENCES stepful_user(id);
+
+ALTER TABLE waitlist_entry
+ADD CONSTRAINT fk_waitlist_entry_slot
+FOREIGN KEY (slot_id) REFERENCES slot(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_waitlist_entry_slot ON waitlist_entry(slot_id, created_at) WHERE status = 'waiting';
+CREATE INDEX idx_waitlist_entry_week ON waitlist_entry(coach_id, week_start, created_at) WHERE status = 'waiting';
+CREATE INDEX idx_waitlist_entry_student ON waitlist_entry(student_id, created_at DESC);
+
+-- Promotions are kept after their slot goes away, like booking history
+CREATE TABLE waitlist_promotion (
+    id UUID PRIMARY KEY,
+    entry_id UUID NOT NULL,
+    slot_id UUID NOT NULL,
+    student_id UUID NOT NULL,
+    status TEXT NOT NULL DEFAULT 'offered',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT check_waitlist_promotion_status CHECK (status IN ('offered', 'confirmed', 'declined', 'expired'))
+);
+
+ALTER TABLE waitlist_promotion
+ADD CONSTRAINT fk_waitlist_promotion_entry
+FOREIGN KEY (entry_id) REFERENCES waitlist_entry(id) ON DELETE CASCADE;
+
+ALTER TABLE waitlist_promotion
+ADD CONSTRAINT fk_waitlist_promotion_student
+FOREIGN KEY (student_id) REFERENCES stepful_user(id);
+
+CREATE INDEX idx_waitlist_promotion_expiry ON waitlist_promotion(expires_at) WHERE status = 'offered';
+CREATE INDEX idx_waitlist_promotion_student ON waitlist_promotion(student_id, created_at DESC);
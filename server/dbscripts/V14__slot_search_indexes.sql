@@ -0,0 +1,3 @@
+CREATE INDEX idx_slot_start_time_booked ON slot(start_time, booked);
+
+CREATE INDEX idx_slot_coach_start_time ON slot(coach_id, start_time);
@@ -0,0 +1,9 @@
+-- Public profile fields shown in the coach directory. Specialties are stored
+-- lower-cased so directory filters can match them with array containment.
+ALTER TABLE coach_profile
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN photo_url TEXT NOT NULL DEFAULT '',
+ADD COLUMN specialties TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN languages TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_coach_profile_specialties ON coach_profile USING GIN (specialties);
@@ -0,0 +1,41 @@
+-- Append-only ledger of session credits. A student's balance is the sum of
+-- their entries: grants add credits, each booking debits one and an eligible
+-- cancellation refunds it. Like booking_history, slot_id is not a foreign key
+-- so entries survive slots that coaches later remove.
+CREATE TABLE credit_ledger_entry (
+    id UUID PRIMARY KEY,
+    student_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    amount INT NOT NULL,
+    slot_id UUID,
+    -- NULL for entries made by the system rather than a user
+    actor_id UUID,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT check_credit_ledger_kind
+        CHECK (kind IN ('grant', 'debit', 'refund')),
+    CONSTRAINT check_credit_ledger_amount
+        CHECK ((kind = 'debit' AND amount < 0) OR (kind <> 'debit' AND amount > 0)),
+    CONSTRAINT check_credit_ledger_slot
+        CHECK (kind = 'grant' OR slot_id IS NOT NULL)
+);
+
+ALTER TABLE credit_ledger_entry
+ADD CONSTRAINT fk_credit_ledger_student
+FOREIGN KEY (student_id) REFERENCES stepful_user(id);
+
+ALTER TABLE credit_ledger_entry
+ADD CONSTRAINT fk_credit_ledger_actor
+FOREIGN KEY (actor_id) REFERENCES stepful_user(id);
+
+CREATE INDEX idx_credit_ledger_student_id ON credit_ledger_entry(student_id, created_at DESC);
+CREATE INDEX idx_credit_ledger_slot_id ON credit_ledger_entry(slot_id, student_id);
+
+-- Existing students get an opening balance so that bookings keep working once
+-- the balance is enforced. The system makes the grant, so it has no actor.
+-- The amount is set by the deployment through the opening_credit_balance
+-- Flyway placeholder rather than fixed here.
+INSERT INTO credit_ledger_entry (id, student_id, kind, amount, actor_id, note)
+SELECT gen_random_uuid(), id, 'grant', ${opening_credit_balance}, NULL,
+    'Opening balance, backfilled by the system when credits were introduced'
+FROM stepful_user WHERE user_role = 'student';
@@ -0,0 +1,37 @@
+-- Session types with a price are paid for at booking time instead of with a
+-- session credit. Prices are in the currency's minor unit.
+ALTER TABLE session_type
+ADD COLUMN price_cents INT NOT NULL DEFAULT 0,
+ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+ALTER TABLE session_type
+ADD CONSTRAINT check_session_type_price
+CHECK (price_cents >= 0);
+
+-- One row per attempt to charge a student for a seat. provider_ref is the
+-- payment provider's own ID for the charge. Like booking_history, slot_id is
+-- not a foreign key so payments outlive slots that coaches later remove.
+CREATE TABLE payment_intent (
+    id UUID PRIMARY KEY,
+    slot_id UUID NOT NULL,
+    student_id UUID NOT NULL,
+    amount_cents INT NOT NULL,
+    currency TEXT NOT NULL,
+    provider_ref TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    failure_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT check_payment_intent_amount
+        CHECK (amount_cents > 0),
+    CONSTRAINT check_payment_intent_status
+        CHECK (status IN ('captured', 'failed', 'refunded', 'refund_failed'))
+);
+
+ALTER TABLE payment_intent
+ADD CONSTRAINT fk_payment_intent_student
+FOREIGN KEY (student_id) REFERENCES stepful_user(id);
+
+-- A seat is paid for at most once at a time
+CREATE UNIQUE INDEX uq_payment_intent_captured ON payment_intent(slot_id, student_id) WHERE status = 'captured';
+CREATE INDEX idx_payment_intent_student_id ON payment_intent(student_id, created_at DESC);
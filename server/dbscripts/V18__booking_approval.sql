@@ -0,0 +1,26 @@
+-- Coaches who vet their students get booking requests instead of bookings.
+-- Unanswered requests expire after booking_request_ttl_minutes.
+ALTER TABLE coach_profile
+ADD COLUMN requires_booking_approval BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN booking_request_ttl_minutes INT NOT NULL DEFAULT 1440;
+
+ALTER TABLE coach_profile
+ADD CONSTRAINT check_booking_request_ttl
+CHECK (booking_request_ttl_minutes > 0);
+
+-- A pending booking holds its seat until the coach answers or expires_at
+ALTER TABLE booking
+DROP CONSTRAINT check_booking_status;
+
+ALTER TABLE booking
+ADD CONSTRAINT check_booking_status
+CHECK (status IN ('confirmed', 'held', 'pending'));
+
+CREATE INDEX idx_booking_pending_expires_at ON booking(expires_at) WHERE status = 'pending';
+
+ALTER TABLE booking_history
+DROP CONSTRAINT check_booking_history_event;
+
+ALTER TABLE booking_history
+ADD CONSTRAINT check_booking_history_event
+CHECK (event IN ('cancelled', 'coach_cancelled', 'rescheduled', 'request_declined', 'request_expired'));
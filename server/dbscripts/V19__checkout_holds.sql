@@ -0,0 +1,11 @@
+-- A checkout booking reserves a seat for a few minutes while the student
+-- confirms it. Like held and pending seats it counts towards capacity.
+ALTER TABLE booking
+DROP CONSTRAINT check_booking_status;
+
+ALTER TABLE booking
+ADD CONSTRAINT check_booking_status
+CHECK (status IN ('confirmed', 'held', 'pending', 'checkout'));
+
+CREATE INDEX idx_booking_checkout_expires_at ON booking(expires_at) WHERE status = 'checkout';
+CREATE INDEX idx_booking_checkout_student ON booking(student_id) WHERE status = 'checkout';
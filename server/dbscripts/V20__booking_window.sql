@@ -0,0 +1,9 @@
+-- Students must book at least min_booking_lead_minutes before a session and
+-- at most max_booking_horizon_days ahead of it. A horizon of 0 means no limit.
+ALTER TABLE coach_profile
+ADD COLUMN min_booking_lead_minutes INT NOT NULL DEFAULT 0,
+ADD COLUMN max_booking_horizon_days INT NOT NULL DEFAULT 0;
+
+ALTER TABLE coach_profile
+ADD CONSTRAINT check_booking_window
+CHECK (min_booking_lead_minutes >= 0 AND max_booking_horizon_days >= 0);
@@ -0,0 +1,12 @@
+-- Coaches record after a session whether each booked student attended.
+ALTER TABLE booking
+ADD COLUMN attendance VARCHAR(20) CHECK (attendance IN ('attended', 'student_no_show', 'coach_no_show')),
+ADD COLUMN attendance_marked_by UUID REFERENCES stepful_user(id),
+ADD COLUMN attendance_marked_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_booking_student_no_show ON booking(student_id) WHERE attendance = 'student_no_show';
+
+-- Students with more than max_student_no_shows no-shows in the last 30 days
+-- cannot book the coach. NULL means no limit.
+ALTER TABLE coach_profile
+ADD COLUMN max_student_no_shows INT CHECK (max_student_no_shows >= 0);
@@ -0,0 +1,45 @@
+-- Each booked student gets one feedback record per session. Edits update it
+-- in place and every version, including the first, is kept as a revision.
+ALTER TABLE session_feedback
+ADD COLUMN updated_at TIMESTAMP;
+
+CREATE TABLE session_feedback_revision (
+    id UUID PRIMARY KEY,
+    feedback_id UUID NOT NULL REFERENCES session_feedback(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    satisfaction INT NOT NULL CHECK (satisfaction >= 1 AND satisfaction <= 5),
+    notes TEXT,
+    author_id UUID NOT NULL REFERENCES stepful_user(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (feedback_id, revision)
+);
+
+-- Feedback given more than once for the same session becomes the revision
+-- history of the newest record, which is kept.
+CREATE TEMPORARY TABLE session_feedback_ranked AS
+SELECT
+    id,
+    FIRST_VALUE(id) OVER (PARTITION BY slot_id, student_id ORDER BY created_at DESC, id DESC) AS canonical_id,
+    ROW_NUMBER() OVER (PARTITION BY slot_id, student_id ORDER BY created_at ASC, id ASC) AS revision,
+    COUNT(*) OVER (PARTITION BY slot_id, student_id) AS versions,
+    MIN(created_at) OVER (PARTITION BY slot_id, student_id) AS first_created_at
+FROM session_feedback;
+
+INSERT INTO session_feedback_revision (id, feedback_id, revision, satisfaction, notes, author_id, created_at)
+SELECT gen_random_uuid(), r.canonical_id, r.revision, sf.satisfaction, sf.notes, sf.coach_id, sf.created_at
+FROM session_feedback sf
+JOIN session_feedback_ranked r ON r.id = sf.id;
+
+UPDATE session_feedback sf
+SET updated_at = sf.created_at, created_at = r.first_created_at
+FROM session_feedback_ranked r
+WHERE r.id = sf.id AND r.canonical_id = sf.id AND r.versions > 1;
+
+DELETE FROM session_feedback sf
+USING session_feedback_ranked r
+WHERE r.id = sf.id AND r.canonical_id <> sf.id;
+
+DROP TABLE session_feedback_ranked;
+
+ALTER TABLE session_feedback
+ADD CONSTRAINT unique_session_feedback UNIQUE (slot_id, student_id);
@@ -0,0 +1,15 @@
+-- Students rate their own sessions once they have ended, one rating each.
+CREATE TABLE session_rating (
+    id UUID PRIMARY KEY,
+    slot_id UUID NOT NULL REFERENCES slot(id),
+    coach_id UUID NOT NULL REFERENCES stepful_user(id),
+    student_id UUID NOT NULL REFERENCES stepful_user(id),
+    rating INT NOT NULL CHECK (rating >= 1 AND rating <= 5),
+    comments TEXT NOT NULL DEFAULT '',
+    useful BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (slot_id, student_id)
+);
+
+CREATE INDEX idx_session_rating_coach ON session_rating(coach_id);
+CREATE INDEX idx_session_rating_student ON session_rating(student_id, created_at DESC);
@@ -0,0 +1,8 @@
+-- Program leads oversee every coach, e.g. through the satisfaction analytics.
+INSERT INTO stepful_user (id, name, phone_number, user_role) VALUES
+  ('3f2b8c1e-6d4a-4b7e-9a15-2c8d7e6f5a41', 'Priya Patel', '555-0301', 'program_lead');
+
+-- Analytics group feedback by the week of the session and scan it by coach
+-- and student.
+CREATE INDEX idx_session_feedback_coach ON session_feedback(coach_id);
+CREATE INDEX idx_session_feedback_student ON session_feedback(student_id);
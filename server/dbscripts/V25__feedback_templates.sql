@@ -0,0 +1,64 @@
+-- Program leads define feedback templates made of typed fields. Coaches may
+-- fill one in alongside the overall satisfaction score, and each answer is
+-- stored on its own so every rubric dimension can be reported separately.
+CREATE TABLE feedback_template (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID NOT NULL REFERENCES stepful_user(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Fields never change once the template exists, so stored answers always
+-- match the field they answer.
+CREATE TABLE feedback_template_field (
+    id UUID PRIMARY KEY,
+    template_id UUID NOT NULL REFERENCES feedback_template(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    key TEXT NOT NULL,
+    label TEXT NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('score', 'choice', 'text', 'checklist')),
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    min_score INT,
+    max_score INT,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    UNIQUE (template_id, key),
+    UNIQUE (template_id, position)
+);
+
+ALTER TABLE session_feedback
+ADD COLUMN template_id UUID REFERENCES feedback_template(id);
+
+CREATE INDEX idx_session_feedback_template ON session_feedback(template_id) WHERE template_id IS NOT NULL;
+
+-- A choice answer has one selected option; a checklist has any number.
+CREATE TABLE feedback_response (
+    feedback_id UUID NOT NULL REFERENCES session_feedback(id) ON DELETE CASCADE,
+    field_id UUID NOT NULL REFERENCES feedback_template_field(id),
+    score INT,
+    text TEXT,
+    selected TEXT[],
+    PRIMARY KEY (feedback_id, field_id)
+);
+
+CREATE INDEX idx_feedback_response_field ON feedback_response(field_id);
+
+-- Revisions keep the rubric answers as they were at the time
+ALTER TABLE session_feedback_revision
+ADD COLUMN template_id UUID REFERENCES feedback_template(id),
+ADD COLUMN responses JSONB;
+
+-- The program's session rubric
+INSERT INTO feedback_template (id, name, description, created_by) VALUES
+  ('7d1e4b2a-9c3f-4e8d-b6a5-1f2e3d4c5b6a', 'Session rubric',
+   'Preparation, communication, technical depth and action items',
+   '3f2b8c1e-6d4a-4b7e-9a15-2c8d7e6f5a41');
+
+INSERT INTO feedback_template_field (id, template_id, position, key, label, kind, required, min_score, max_score) VALUES
+  ('a1c2e3f4-0b1d-4f2a-8c3e-5d6f7a8b9c01', '7d1e4b2a-9c3f-4e8d-b6a5-1f2e3d4c5b6a', 1, 'preparation', 'Preparation', 'score', TRUE, 1, 5),
+  ('a1c2e3f4-0b1d-4f2a-8c3e-5d6f7a8b9c02', '7d1e4b2a-9c3f-4e8d-b6a5-1f2e3d4c5b6a', 2, 'communication', 'Communication', 'score', TRUE, 1, 5),
+  ('a1c2e3f4-0b1d-4f2a-8c3e-5d6f7a8b9c03', '7d1e4b2a-9c3f-4e8d-b6a5-1f2e3d4c5b6a', 3, 'technical_depth', 'Technical depth', 'score', TRUE, 1, 5);
+
+INSERT INTO feedback_template_field (id, template_id, position, key, label, kind, required) VALUES
+  ('a1c2e3f4-0b1d-4f2a-8c3e-5d6f7a8b9c04', '7d1e4b2a-9c3f-4e8d-b6a5-1f2e3d4c5b6a', 4, 'action_items', 'Action items', 'text', TRUE);
@@ -0,0 +1,4 @@
+-- Coaches search their session notes. The index is on the expression rather
+-- than a stored tsvector column so queries must use the same
+-- to_tsvector('english', notes) to hit it.
+CREATE INDEX idx_session_feedback_notes_search ON session_feedback USING GIN (to_tsvector('english', notes));
@@ -0,0 +1,24 @@
+CREATE TABLE session_type (
+    id UUID PRIMARY KEY,
+    coach_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    duration_minutes INT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    CONSTRAINT check_session_type_duration
+        CHECK (duration_minutes > 0 AND duration_minutes % 15 = 0)
+);
+
+ALTER TABLE session_type
+ADD CONSTRAINT fk_session_type_coach
+FOREIGN KEY (coach_id) REFERENCES stepful_user(id);
+
+ALTER TABLE session_type
+ADD CONSTRAINT uq_session_type_coach_name
+UNIQUE (coach_id, name);
+
+ALTER TABLE slot
+ADD COLUMN session_type_id UUID;
+
+ALTER TABLE slot
+ADD CONSTRAINT fk_slots_session_type
+FOREIGN KEY (session_type_id) REFERENCES session_type(id);
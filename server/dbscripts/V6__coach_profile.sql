@@ -0,0 +1,36 @@
+CREATE TABLE coach_profile (
+    coach_id UUID PRIMARY KEY,
+    time_zone TEXT NOT NULL DEFAULT 'America/New_York'
+);
+
+ALTER TABLE coach_profile
+ADD CONSTRAINT fk_coach_profile_coach
+FOREIGN KEY (coach_id) REFERENCES stepful_user(id);
+
+-- Weekly working hours in the coach's local time. weekday follows Go's
+-- time.Weekday (0 = Sunday) and minutes count from local midnight.
+CREATE TABLE coach_working_hours (
+    coach_id UUID NOT NULL,
+    weekday SMALLINT NOT NULL,
+    start_minute INT NOT NULL,
+    end_minute INT NOT NULL,
+    PRIMARY KEY (coach_id, weekday, start_minute),
+    CONSTRAINT check_working_hours_weekday
+        CHECK (weekday >= 0 AND weekday <= 6),
+    CONSTRAINT check_working_hours_range
+        CHECK (start_minute >= 0 AND end_minute <= 1440 AND start_minute < end_minute)
+);
+
+ALTER TABLE coach_working_hours
+ADD CONSTRAINT fk_coach_working_hours_coach
+FOREIGN KEY (coach_id) REFERENCES stepful_user(id);
+
+-- Existing coaches keep the previous 9 AM - 5 PM Eastern schedule
+INSERT INTO coach_profile (coach_id, time_zone)
+SELECT id, 'America/New_York' FROM stepful_user WHERE user_role = 'coach';
+
+INSERT INTO coach_working_hours (coach_id, weekday, start_minute, end_minute)
+SELECT u.id, d.weekday, 540, 1020
+FROM stepful_user u
+CROSS JOIN generate_series(0, 6) AS d(weekday)
+WHERE u.user_role = 'coach';
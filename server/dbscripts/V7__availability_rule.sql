@@ -0,0 +1,37 @@
+-- Recurring availability. rrule holds an RFC 5545 recurrence (FREQ=DAILY or
+-- WEEKLY) whose first occurrence is start_date in the coach's time zone; each
+-- occurrence is split into back-to-back slots between start_minute and
+-- end_minute.
+CREATE TABLE availability_rule (
+    id UUID PRIMARY KEY,
+    coach_id UUID NOT NULL,
+    session_type_id UUID,
+    rrule TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    start_minute INT NOT NULL,
+    end_minute INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT check_availability_rule_window
+        CHECK (start_minute >= 0 AND end_minute <= 1440 AND start_minute < end_minute)
+);
+
+ALTER TABLE availability_rule
+ADD CONSTRAINT fk_availability_rule_coach
+FOREIGN KEY (coach_id) REFERENCES stepful_user(id);
+
+ALTER TABLE availability_rule
+ADD CONSTRAINT fk_availability_rule_session_type
+FOREIGN KEY (session_type_id) REFERENCES session_type(id);
+
+CREATE INDEX idx_availability_rule_coach_id ON availability_rule(coach_id);
+
+-- Slots materialized from a rule keep a link to it; booked slots outlive the rule
+ALTER TABLE slot
+ADD COLUMN availability_rule_id UUID;
+
+ALTER TABLE slot
+ADD CONSTRAINT fk_slots_availability_rule
+FOREIGN KEY (availability_rule_id) REFERENCES availability_rule(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_slot_availability_rule_id ON slot(availability_rule_id);
@@ -0,0 +1,39 @@
+ALTER TABLE coach_profile
+ADD COLUMN min_cancellation_notice_minutes INT NOT NULL DEFAULT 1440;
+
+ALTER TABLE coach_profile
+ADD CONSTRAINT check_min_cancellation_notice
+CHECK (min_cancellation_notice_minutes >= 0);
+
+-- Append-only log of changes to bookings. slot_id is deliberately not a
+-- foreign key: the history outlives slots that coaches later remove, and the
+-- session times are copied so entries stay meaningful on their own.
+CREATE TABLE booking_history (
+    id UUID PRIMARY KEY,
+    slot_id UUID NOT NULL,
+    coach_id UUID NOT NULL,
+    student_id UUID NOT NULL,
+    event TEXT NOT NULL,
+    actor_id UUID NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT check_booking_history_event
+        CHECK (event IN ('cancelled'))
+);
+
+ALTER TABLE booking_history
+ADD CONSTRAINT fk_booking_history_coach
+FOREIGN KEY (coach_id) REFERENCES stepful_user(id);
+
+ALTER TABLE booking_history
+ADD CONSTRAINT fk_booking_history_student
+FOREIGN KEY (student_id) REFERENCES stepful_user(id);
+
+ALTER TABLE booking_history
+ADD CONSTRAINT fk_booking_history_actor
+FOREIGN KEY (actor_id) REFERENCES stepful_user(id);
+
+CREATE INDEX idx_booking_history_coach_id ON booking_history(coach_id, created_at DESC);
+CREATE INDEX idx_booking_history_student_id ON booking_history(student_id, created_at DESC);
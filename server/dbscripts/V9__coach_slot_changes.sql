@@ -0,0 +1,19 @@
+ALTER TABLE booking_history
+DROP CONSTRAINT check_booking_history_event;
+
+ALTER TABLE booking_history
+ADD CONSTRAINT check_booking_history_event
+CHECK (event IN ('cancelled', 'coach_cancelled'));
+
+CREATE TABLE notification (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE notification
+ADD CONSTRAINT fk_notification_user
+FOREIGN KEY (user_id) REFERENCES stepful_user(id);
+
+CREATE INDEX idx_notification_user_id ON notification(user_id, created_at DESC);
@@ -1,31 +1,59 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
 )
 
 type DbClient interface {
-	NamedGetSingleEntity(dest interface{}, query string, args ...interface{}) error
-	NamedSelectEntities(dest interface{}, query string, args ...interface{}) error
-	GetSingleEntity(dest interface{}, query string, args ...interface{}) error
-	Select(dest interface{}, query string, args ...interface{}) error
-	ExecuteCommand(cmd string, args ...interface{}) (sql.Result, error)
-	NamedExec(query string, arg interface{}) (sql.Result, error)
+	NamedGetSingleEntity(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedSelectEntities(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	GetSingleEntity(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecuteCommand(ctx context.Context, cmd string, args ...interface{}) (sql.Result, error)
+	NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	// WithTx runs fn in a transaction and commits if fn returns nil. The
+	// transaction is rolled back if fn returns an error or panics. Calling
+	// WithTx on the client passed to fn opens a savepoint, so nested units of
+	// work can fail without aborting the enclosing transaction.
+	WithTx(ctx context.Context, fn func(tx DbClient) error) error
+}
+
+// queryRunner is the part of the sqlx API shared by *sqlx.DB and *sqlx.Tx.
+type queryRunner interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 }
 
 type dbClient struct {
-	conn *sqlx.DB
+	conn queryRunner
+	db   *sqlx.DB
+	// tx is set when the client is bound to a transaction; savepointDepth
+	// counts the savepoints opened above it.
+	tx             *sqlx.Tx
+	savepointDepth int
+	// queryTimeout bounds each statement; zero means only the caller's
+	// context applies.
+	queryTimeout time.Duration
 }
 
-func NewDbClient(db *sqlx.DB) DbClient {
+func NewDbClient(db *sqlx.DB, queryTimeout time.Duration) DbClient {
 	return &dbClient{
-		conn: db,
+		conn:         db,
+		db:           db,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (dc *dbClient) NamedGetSingleEntity(dest interface{}, query string, args ...interface{}) error {
+func (dc *dbClient) NamedGetSingleEntity(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
 	q, as, err := sqlx.Named(query, args)
 	if err != nil {
 		return err
@@ -33,9 +61,9 @@ func (dc *dbClient) NamedGetSingleEntity(dest interface{}, query string, args ..
 
 	q = sqlx.Rebind(sqlx.DOLLAR, q)
 
-	return dc.GetSingleEntity(dest, q, as...)
+	return dc.GetSingleEntity(ctx, dest, q, as...)
 }
-func (pc *dbClient) NamedSelectEntities(dest interface{}, query string, args ...interface{}) error {
+func (pc *dbClient) NamedSelectEntities(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
 	q, as, err := sqlx.Named(query, args)
 	if err != nil {
 		return err
@@ -43,21 +71,112 @@ func (pc *dbClient) NamedSelectEntities(dest interface{}, query string, args ...
 
 	q = sqlx.Rebind(sqlx.DOLLAR, q)
 
-	return pc.Select(dest, q, as...)
+	return pc.Select(ctx, dest, q, as...)
+}
+
+func (dc *dbClient) GetSingleEntity(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := dc.queryContext(ctx)
+	defer cancel()
+	return dc.logIfAborted(ctx, query, dc.conn.GetContext(ctx, dest, query, args...))
+}
+
+func (dc *dbClient) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := dc.queryContext(ctx)
+	defer cancel()
+	return dc.logIfAborted(ctx, query, dc.conn.SelectContext(ctx, dest, query, args...))
+}
+
+func (dc *dbClient) ExecuteCommand(ctx context.Context, cmd string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := dc.queryContext(ctx)
+	defer cancel()
+	result, err := dc.conn.ExecContext(ctx, cmd, args...)
+	return result, dc.logIfAborted(ctx, cmd, err)
 }
 
-func (dc *dbClient) GetSingleEntity(dest interface{}, query string, args ...interface{}) error {
-	return dc.conn.Get(dest, query, args...)
+func (dc *dbClient) NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	ctx, cancel := dc.queryContext(ctx)
+	defer cancel()
+	result, err := dc.conn.NamedExecContext(ctx, query, arg)
+	return result, dc.logIfAborted(ctx, query, err)
 }
 
-func (dc *dbClient) Select(dest interface{}, query string, args ...interface{}) error {
-	return dc.conn.Select(dest, query, args...)
+// queryContext applies the per-query deadline on top of the caller's context.
+func (dc *dbClient) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if dc.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, dc.queryTimeout)
 }
 
-func (dc *dbClient) ExecuteCommand(cmd string, args ...interface{}) (sql.Result, error) {
-	return dc.conn.Exec(cmd, args...)
+// logIfAborted logs queries that failed because the request went away or the
+// query deadline passed, and returns err unchanged.
+func (dc *dbClient) logIfAborted(ctx context.Context, query string, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		log.Warn().Err(err).Dur("timeout", dc.queryTimeout).Str("query", query).Msg("Database query timed out")
+	case errors.Is(ctx.Err(), context.Canceled):
+		log.Info().Err(err).Str("query", query).Msg("Database query cancelled")
+	}
+	return err
 }
 
-func (dc *dbClient) NamedExec(query string, arg interface{}) (sql.Result, error) {
-	return dc.conn.NamedExec(query, arg)
+func (dc *dbClient) WithTx(ctx context.Context, fn func(tx DbClient) error) error {
+	if dc.tx != nil {
+		return dc.withSavepoint(ctx, fn)
+	}
+
+	tx, err := dc.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&dbClient{conn: tx, db: dc.db, tx: tx, queryTimeout: dc.queryTimeout}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (dc *dbClient) withSavepoint(ctx context.Context, fn func(tx DbClient) error) error {
+	depth := dc.savepointDepth + 1
+	name := fmt.Sprintf("sp_%d", depth)
+
+	if _, err := dc.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("error creating savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			dc.tx.Exec("ROLLBACK TO SAVEPOINT " + name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(&dbClient{conn: dc.tx, db: dc.db, tx: dc.tx, savepointDepth: depth, queryTimeout: dc.queryTimeout}); err != nil {
+		if _, rbErr := dc.tx.Exec("ROLLBACK TO SAVEPOINT " + name); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if _, err := dc.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("error releasing savepoint: %w", err)
+	}
+	return nil
 }
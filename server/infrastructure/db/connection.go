@@ -7,6 +7,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
 )
 
 type DbConnectionInfo struct {
@@ -25,6 +26,29 @@ func GetDbConnectionInfo() *DbConnectionInfo {
 	}
 }
 
+// Used when DB_QUERY_TIMEOUT is unset or not a valid duration.
+const defaultQueryTimeout = 5 * time.Second
+
+// GetQueryTimeout reads the per-query deadline from DB_QUERY_TIMEOUT, a Go
+// duration string such as "3s" or "500ms". "0" disables the deadline. A value
+// that is not a duration, such as a bare "30", is logged and replaced by the
+// default.
+func GetQueryTimeout() time.Duration {
+	value := os.Getenv("DB_QUERY_TIMEOUT")
+	if value == "" {
+		return defaultQueryTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		log.Warn().
+			Str("DB_QUERY_TIMEOUT", value).
+			Dur("default", defaultQueryTimeout).
+			Msg("DB_QUERY_TIMEOUT must be a non-negative duration such as 30s; using the default")
+		return defaultQueryTimeout
+	}
+	return timeout
+}
+
 func GetDbConnection(attempts int) (*sqlx.DB, error) {
 	connInfo := GetDbConnectionInfo()
 
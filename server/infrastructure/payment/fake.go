@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDeclined is returned by FakeProvider when it has been told to fail.
+var ErrDeclined = errors.New("payment declined")
+
+type fakeStatus string
+
+const (
+	fakeAuthorized fakeStatus = "authorized"
+	fakeCaptured   fakeStatus = "captured"
+	fakeRefunded   fakeStatus = "refunded"
+	fakeVoided     fakeStatus = "voided"
+)
+
+type fakePayment struct {
+	reference   string
+	amountCents int
+	currency    string
+	status      fakeStatus
+}
+
+// FakeProvider is an in-process payment provider for development and tests.
+// It keeps payments in memory and approves every charge unless told to
+// decline authorizations or fail captures.
+type FakeProvider struct {
+	mu                    sync.Mutex
+	payments              map[string]*fakePayment
+	byReference           map[string]string
+	declineAuthorizations bool
+	failCaptures          bool
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		payments:    make(map[string]*fakePayment),
+		byReference: make(map[string]string),
+	}
+}
+
+// SetDeclineAuthorizations makes later Authorize calls fail with ErrDeclined.
+func (p *FakeProvider) SetDeclineAuthorizations(decline bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.declineAuthorizations = decline
+}
+
+// SetFailCaptures makes later Capture calls fail with ErrDeclined.
+func (p *FakeProvider) SetFailCaptures(fail bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failCaptures = fail
+}
+
+// Authorize places a hold for the amount. Authorizing the same reference
+// twice returns the original payment, as real providers do for idempotency
+// keys.
+func (p *FakeProvider) Authorize(ctx context.Context, reference string, amountCents int, currency string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if providerRef, ok := p.byReference[reference]; ok {
+		return providerRef, nil
+	}
+	if p.declineAuthorizations {
+		return "", ErrDeclined
+	}
+	if amountCents <= 0 {
+		return "", fmt.Errorf("invalid amount %d", amountCents)
+	}
+
+	providerRef := fmt.Sprintf("fake_%d", len(p.payments)+1)
+	p.payments[providerRef] = &fakePayment{
+		reference:   reference,
+		amountCents: amountCents,
+		currency:    currency,
+		status:      fakeAuthorized,
+	}
+	p.byReference[reference] = providerRef
+	return providerRef, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, providerRef string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, err := p.lookup(providerRef)
+	if err != nil {
+		return err
+	}
+	if payment.status != fakeAuthorized {
+		return fmt.Errorf("cannot capture %s payment %s", payment.status, providerRef)
+	}
+	if p.failCaptures {
+		return ErrDeclined
+	}
+	payment.status = fakeCaptured
+	return nil
+}
+
+// Refund returns a captured payment, or voids an authorization that was never
+// captured.
+func (p *FakeProvider) Refund(ctx context.Context, providerRef string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, err := p.lookup(providerRef)
+	if err != nil {
+		return err
+	}
+	switch payment.status {
+	case fakeAuthorized:
+		payment.status = fakeVoided
+	case fakeCaptured:
+		payment.status = fakeRefunded
+	default:
+		return fmt.Errorf("cannot refund %s payment %s", payment.status, providerRef)
+	}
+	return nil
+}
+
+// Status reports the state of a payment, or "" if the provider has never
+// seen it.
+func (p *FakeProvider) Status(providerRef string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if payment, ok := p.payments[providerRef]; ok {
+		return string(payment.status)
+	}
+	return ""
+}
+
+func (p *FakeProvider) lookup(providerRef string) (*fakePayment, error) {
+	payment, ok := p.payments[providerRef]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment %s", providerRef)
+	}
+	return payment, nil
+}
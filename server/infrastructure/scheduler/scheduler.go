@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Job is a unit of background work. It is given a context that is cancelled
+// when the scheduler stops.
+type Job func(ctx context.Context) error
+
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      Job
+}
+
+// Scheduler runs registered jobs at fixed intervals in the background.
+type Scheduler struct {
+	jobs []scheduledJob
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers job to run once when the scheduler starts and then every
+// interval. Jobs must be registered before Start is called.
+func (s *Scheduler) Every(name string, interval time.Duration, job Job) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, run: job})
+}
+
+// Start launches one goroutine per job. They stop when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job scheduledJob) {
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce runs the job and logs failures. A panicking job is logged and
+// retried on the next tick rather than taking the server down.
+func (s *Scheduler) runOnce(ctx context.Context, job scheduledJob) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Str("job", job.name).Interface("panic", p).Msg("Scheduled job panicked")
+		}
+	}()
+
+	if err := job.run(ctx); err != nil {
+		log.Error().Err(err).Str("job", job.name).Msg("Scheduled job failed")
+	}
+}
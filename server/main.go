@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 
 	"github.com/cargoreligion/booking/server/api"
 	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/infrastructure/payment"
+	"github.com/cargoreligion/booking/server/infrastructure/scheduler"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -25,9 +28,19 @@ func main() {
 	}
 	defer dbInst.Close()
 
-	dbc := db.NewDbClient(dbInst)
+	queryTimeout := db.GetQueryTimeout()
+	log.Info().Dur("queryTimeout", queryTimeout).Msg("Configured database query timeout")
+	dbc := db.NewDbClient(dbInst, queryTimeout)
 
-	router := api.NewRouter(dbc)
+	// Only the in-process fake is available until a real processor is
+	// integrated; it approves every charge
+	paymentProvider := payment.NewFakeProvider()
+
+	router := api.NewRouter(dbc, paymentProvider)
+
+	sched := scheduler.NewScheduler()
+	api.RegisterJobs(sched, dbc, paymentProvider)
+	sched.Start(context.Background())
 
 	port := fmt.Sprintf(":%s", os.Getenv("PORT"))
 
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AttendanceStatus string
+
+const (
+	AttendanceAttended      AttendanceStatus = "attended"
+	AttendanceStudentNoShow AttendanceStatus = "student_no_show"
+	AttendanceCoachNoShow   AttendanceStatus = "coach_no_show"
+)
+
+// NoShowWindow is how far back no-shows count against a coach's no-show
+// policy.
+const NoShowWindow = 30 * 24 * time.Hour
+
+func (a AttendanceStatus) IsValid() bool {
+	switch a {
+	case AttendanceAttended, AttendanceStudentNoShow, AttendanceCoachNoShow:
+		return true
+	}
+	return false
+}
+
+// IsNoShow reports whether the session did not take place, whoever missed it.
+func (a AttendanceStatus) IsNoShow() bool {
+	return a == AttendanceStudentNoShow || a == AttendanceCoachNoShow
+}
+
+// NoShowSummary counts the sessions a student missed, overall and within
+// NoShowWindow.
+type NoShowSummary struct {
+	StudentID     uuid.UUID `json:"studentId" db:"student_id"`
+	RecentNoShows int       `json:"recentNoShows" db:"recent_no_shows"`
+	TotalNoShows  int       `json:"totalNoShows" db:"total_no_shows"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AvailabilityRule struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	CoachID       uuid.UUID  `json:"coachId" db:"coach_id"`
+	SessionTypeID *uuid.UUID `json:"sessionTypeId" db:"session_type_id"`
+	RRule         string     `json:"rrule" db:"rrule"`
+	StartDate     time.Time  `json:"startDate" db:"start_date"`
+	StartMinute   int        `json:"startMinute" db:"start_minute"`
+	EndMinute     int        `json:"endMinute" db:"end_minute"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// AvailabilityRuleResult reports how many slots a rule produced. Occurrences
+// that are in the past, outside working hours or overlap an existing slot are
+// skipped rather than failing the whole rule.
+type AvailabilityRuleResult struct {
+	Rule         AvailabilityRule `json:"rule"`
+	SlotsCreated int              `json:"slotsCreated"`
+	SlotsSkipped int              `json:"slotsSkipped"`
+	SlotsRemoved int              `json:"slotsRemoved"`
+}
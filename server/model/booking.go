@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BookingStatus string
+
+const (
+	BookingStatusConfirmed BookingStatus = "confirmed"
+	// A held seat is reserved for the student until ExpiresAt but still
+	// needs to be confirmed.
+	BookingStatusHeld BookingStatus = "held"
+	// A pending booking is a request waiting for the coach's approval. It
+	// holds its seat until the coach answers or ExpiresAt passes.
+	BookingStatusPending BookingStatus = "pending"
+	// A checkout seat is reserved for a few minutes while the student
+	// confirms the booking, so nobody else can take it in the meantime.
+	BookingStatusCheckout BookingStatus = "checkout"
+)
+
+// Booking is one student's seat in a slot. A slot holds up to Capacity
+// bookings; held seats count towards it.
+type Booking struct {
+	ID                 uuid.UUID     `json:"id" db:"id"`
+	SlotID             uuid.UUID     `json:"slotId" db:"slot_id"`
+	StudentID          uuid.UUID     `json:"studentId" db:"student_id"`
+	StudentName        string        `json:"studentName" db:"student_name"`
+	StudentPhoneNumber string        `json:"studentPhoneNumber,omitempty" db:"student_phone_number"`
+	Status             BookingStatus `json:"status" db:"status"`
+	ExpiresAt          *time.Time    `json:"expiresAt,omitempty" db:"expires_at"`
+	CreatedAt          time.Time     `json:"createdAt" db:"created_at"`
+	// Attendance is recorded by the coach once the session has ended.
+	Attendance         *AttendanceStatus `json:"attendance,omitempty" db:"attendance"`
+	AttendanceMarkedBy *uuid.UUID        `json:"attendanceMarkedBy,omitempty" db:"attendance_marked_by"`
+	AttendanceMarkedAt *time.Time        `json:"attendanceMarkedAt,omitempty" db:"attendance_marked_at"`
+}
+
+// BookingRequest is a pending booking together with the session it asks for.
+type BookingRequest struct {
+	Booking
+	CoachID   uuid.UUID `json:"coachId" db:"coach_id"`
+	StartTime time.Time `json:"startTime" db:"start_time"`
+	EndTime   time.Time `json:"endTime" db:"end_time"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BookingEvent string
+
+const (
+	BookingEventCancelled       BookingEvent = "cancelled"
+	BookingEventCoachCancelled  BookingEvent = "coach_cancelled"
+	BookingEventRescheduled     BookingEvent = "rescheduled"
+	BookingEventRequestDeclined BookingEvent = "request_declined"
+	BookingEventRequestExpired  BookingEvent = "request_expired"
+)
+
+// BookingHistory records something that happened to a booking, such as a
+// cancellation. ActorID is the user who made the change. For reschedules,
+// SlotID is the slot the booking left and NewSlotID the one it moved to.
+type BookingHistory struct {
+	ID          uuid.UUID    `json:"id" db:"id"`
+	SlotID      uuid.UUID    `json:"slotId" db:"slot_id"`
+	NewSlotID   *uuid.UUID   `json:"newSlotId,omitempty" db:"new_slot_id"`
+	CoachID     uuid.UUID    `json:"coachId" db:"coach_id"`
+	StudentID   uuid.UUID    `json:"studentId" db:"student_id"`
+	StudentName string       `json:"studentName,omitempty" db:"student_name"`
+	Event       BookingEvent `json:"event" db:"event"`
+	ActorID     uuid.UUID    `json:"actorId" db:"actor_id"`
+	Reason      string       `json:"reason" db:"reason"`
+	StartTime   time.Time    `json:"startTime" db:"start_time"`
+	EndTime     time.Time    `json:"endTime" db:"end_time"`
+	CreatedAt   time.Time    `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Defaults for coaches who have not configured a profile yet.
+const (
+	DefaultCoachTimeZone      = "America/New_York"
+	DefaultWorkdayStartMinute = 9 * 60
+	DefaultWorkdayEndMinute   = 17 * 60
+	// Students must cancel at least this long before the session starts.
+	DefaultMinCancellationNoticeMinutes = 24 * 60
+	// Coaches who approve bookings have this long to answer a request.
+	DefaultBookingRequestTTLMinutes = 24 * 60
+)
+
+type CoachProfile struct {
+	CoachID                      uuid.UUID      `json:"coachId" db:"coach_id"`
+	TimeZone                     string         `json:"timeZone" db:"time_zone"`
+	MinCancellationNoticeMinutes int            `json:"minCancellationNoticeMinutes" db:"min_cancellation_notice_minutes"`
+	Bio                          string         `json:"bio" db:"bio"`
+	PhotoURL                     string         `json:"photoUrl" db:"photo_url"`
+	Specialties                  pq.StringArray `json:"specialties" db:"specialties"`
+	Languages                    pq.StringArray `json:"languages" db:"languages"`
+	RequiresBookingApproval      bool           `json:"requiresBookingApproval" db:"requires_booking_approval"`
+	BookingRequestTTLMinutes     int            `json:"bookingRequestTtlMinutes" db:"booking_request_ttl_minutes"`
+	// Students must book at least MinBookingLeadMinutes before a session
+	// and no more than MaxBookingHorizonDays ahead; 0 means no horizon.
+	MinBookingLeadMinutes int `json:"minBookingLeadMinutes" db:"min_booking_lead_minutes"`
+	MaxBookingHorizonDays int `json:"maxBookingHorizonDays" db:"max_booking_horizon_days"`
+	// Students with more than MaxStudentNoShows no-shows within NoShowWindow
+	// cannot book the coach; nil means no limit.
+	MaxStudentNoShows *int           `json:"maxStudentNoShows" db:"max_student_no_shows"`
+	WorkingHours      []WorkingHours `json:"workingHours" db:"-"`
+}
+
+// CoachDirectoryEntry is the public view of a coach shown to students
+// browsing for someone to book with. NextAvailableAt is the start of the
+// coach's earliest bookable slot, if any. AverageRating is nil until students
+// have rated the coach.
+type CoachDirectoryEntry struct {
+	ID              uuid.UUID      `json:"id" db:"id"`
+	Name            string         `json:"name" db:"name"`
+	TimeZone        string         `json:"timeZone" db:"time_zone"`
+	Bio             string         `json:"bio" db:"bio"`
+	PhotoURL        string         `json:"photoUrl" db:"photo_url"`
+	Specialties     pq.StringArray `json:"specialties" db:"specialties"`
+	Languages       pq.StringArray `json:"languages" db:"languages"`
+	NextAvailableAt *time.Time     `json:"nextAvailableAt" db:"next_available_at"`
+	AverageRating   *float64       `json:"averageRating" db:"average_rating"`
+	RatingCount     int            `json:"ratingCount" db:"rating_count"`
+}
+
+// CoachDirectoryFilter narrows the coach directory. A zero
+// AvailableWithinDays means availability is not considered.
+type CoachDirectoryFilter struct {
+	Specialty           string
+	AvailableWithinDays int
+}
+
+func (p CoachProfile) MinCancellationNotice() time.Duration {
+	return time.Duration(p.MinCancellationNoticeMinutes) * time.Minute
+}
+
+func (p CoachProfile) BookingRequestTTL() time.Duration {
+	return time.Duration(p.BookingRequestTTLMinutes) * time.Minute
+}
+
+func (p CoachProfile) MinBookingLead() time.Duration {
+	return time.Duration(p.MinBookingLeadMinutes) * time.Minute
+}
+
+// BookingWindow returns the range of start times students can book as of
+// now. latest is nil when the coach has no horizon.
+func (p CoachProfile) BookingWindow(now time.Time) (earliest time.Time, latest *time.Time) {
+	earliest = now.Add(p.MinBookingLead())
+	if p.MaxBookingHorizonDays > 0 {
+		horizon := now.AddDate(0, 0, p.MaxBookingHorizonDays)
+		latest = &horizon
+	}
+	return earliest, latest
+}
+
+// WorkingHours is a window of availability on one weekday, expressed in
+// minutes after midnight in the coach's time zone.
+type WorkingHours struct {
+	CoachID     uuid.UUID    `json:"-" db:"coach_id"`
+	Weekday     time.Weekday `json:"weekday" db:"weekday"`
+	StartMinute int          `json:"startMinute" db:"start_minute"`
+	EndMinute   int          `json:"endMinute" db:"end_minute"`
+}
+
+func DefaultCoachProfile(coachID uuid.UUID) CoachProfile {
+	hours := make([]WorkingHours, 0, 7)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		hours = append(hours, WorkingHours{
+			CoachID:     coachID,
+			Weekday:     day,
+			StartMinute: DefaultWorkdayStartMinute,
+			EndMinute:   DefaultWorkdayEndMinute,
+		})
+	}
+	return CoachProfile{
+		CoachID:                      coachID,
+		TimeZone:                     DefaultCoachTimeZone,
+		MinCancellationNoticeMinutes: DefaultMinCancellationNoticeMinutes,
+		BookingRequestTTLMinutes:     DefaultBookingRequestTTLMinutes,
+		Specialties:                  pq.StringArray{},
+		Languages:                    pq.StringArray{},
+		WorkingHours:                 hours,
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreditEntryKind string
+
+const (
+	CreditEntryGrant  CreditEntryKind = "grant"
+	CreditEntryDebit  CreditEntryKind = "debit"
+	CreditEntryRefund CreditEntryKind = "refund"
+)
+
+// CreditLedgerEntry is one change to a student's session credits. Debits
+// have a negative amount. SlotID names the session a debit or refund is for.
+// ActorID is nil for entries the system made rather than a user, such as the
+// opening balances backfilled when credits were introduced.
+type CreditLedgerEntry struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	StudentID uuid.UUID       `json:"studentId" db:"student_id"`
+	Kind      CreditEntryKind `json:"kind" db:"kind"`
+	Amount    int             `json:"amount" db:"amount"`
+	SlotID    *uuid.UUID      `json:"slotId,omitempty" db:"slot_id"`
+	ActorID   *uuid.UUID      `json:"actorId,omitempty" db:"actor_id"`
+	Note      string          `json:"note" db:"note"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+type CreditBalance struct {
+	StudentID uuid.UUID `json:"studentId"`
+	Balance   int       `json:"balance"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SatisfactionRollingWeeks is how many weeks, the current one included, the
+// rolling average in satisfaction analytics spans.
+const SatisfactionRollingWeeks = 4
+
+// SatisfactionAnalyticsFilter limits analytics to feedback on sessions that
+// started in [From, To), optionally for one coach or student.
+type SatisfactionAnalyticsFilter struct {
+	From      time.Time
+	To        time.Time
+	CoachID   *uuid.UUID
+	StudentID *uuid.UUID
+}
+
+// WeeklySatisfaction is the satisfaction one coach or student saw in a week.
+// RollingAverage covers the SatisfactionRollingWeeks weeks ending with this
+// one, weighted by session.
+type WeeklySatisfaction struct {
+	SubjectID      uuid.UUID `json:"subjectId" db:"subject_id"`
+	SubjectName    string    `json:"subjectName" db:"subject_name"`
+	WeekStart      time.Time `json:"weekStart" db:"week_start"`
+	Sessions       int       `json:"sessions" db:"sessions"`
+	Average        float64   `json:"average" db:"average"`
+	RollingAverage float64   `json:"rollingAverage" db:"rolling_average"`
+}
+
+// DecliningStudent is a student whose satisfaction fell over the period.
+// TrendPerWeek is the fitted change in score per week.
+type DecliningStudent struct {
+	StudentID          uuid.UUID `json:"studentId" db:"student_id"`
+	StudentName        string    `json:"studentName" db:"student_name"`
+	Sessions           int       `json:"sessions" db:"sessions"`
+	Average            float64   `json:"average" db:"average"`
+	FirstSatisfaction  int       `json:"firstSatisfaction" db:"first_satisfaction"`
+	LatestSatisfaction int       `json:"latestSatisfaction" db:"latest_satisfaction"`
+	TrendPerWeek       float64   `json:"trendPerWeek" db:"trend_per_week"`
+}
+
+type ScoreCount struct {
+	Score int `db:"score"`
+	Count int `db:"count"`
+}
+
+// SatisfactionAnalytics summarizes session feedback over a period.
+// Distribution maps each satisfaction score to how many sessions got it.
+type SatisfactionAnalytics struct {
+	From              time.Time            `json:"from"`
+	To                time.Time            `json:"to"`
+	Sessions          int                  `json:"sessions"`
+	Average           *float64             `json:"average"`
+	Distribution      map[int]int          `json:"distribution"`
+	Coaches           []WeeklySatisfaction `json:"coaches"`
+	Students          []WeeklySatisfaction `json:"students"`
+	DecliningStudents []DecliningStudent   `json:"decliningStudents"`
+}
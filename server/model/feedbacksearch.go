@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeedbackSearchResult is feedback whose notes match a search, with the
+// session it was given on. Snippet holds the best matching passages of the
+// notes, HTML-escaped, with matched words wrapped in <mark> tags.
+type FeedbackSearchResult struct {
+	FeedbackID   uuid.UUID `json:"feedbackId" db:"feedback_id"`
+	SlotID       uuid.UUID `json:"slotId" db:"slot_id"`
+	StudentID    uuid.UUID `json:"studentId" db:"student_id"`
+	StudentName  string    `json:"studentName" db:"student_name"`
+	StartTime    time.Time `json:"startTime" db:"start_time"`
+	EndTime      time.Time `json:"endTime" db:"end_time"`
+	Satisfaction int       `json:"satisfaction" db:"satisfaction"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	Rank         float64   `json:"rank" db:"rank"`
+	Snippet      string    `json:"snippet" db:"snippet"`
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type FeedbackFieldKind string
+
+const (
+	// A score is a whole number between the field's MinScore and MaxScore.
+	FeedbackFieldScore FeedbackFieldKind = "score"
+	// A choice picks exactly one of the field's Options.
+	FeedbackFieldChoice FeedbackFieldKind = "choice"
+	FeedbackFieldText   FeedbackFieldKind = "text"
+	// A checklist ticks any number of the field's Options.
+	FeedbackFieldChecklist FeedbackFieldKind = "checklist"
+)
+
+// FeedbackTemplate is a rubric coaches fill in when giving session feedback.
+// Its fields are fixed once created; an archived template can no longer be
+// used for new feedback.
+type FeedbackTemplate struct {
+	ID          uuid.UUID               `json:"id" db:"id"`
+	Name        string                  `json:"name" db:"name"`
+	Description string                  `json:"description" db:"description"`
+	Active      bool                    `json:"active" db:"active"`
+	CreatedBy   uuid.UUID               `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time               `json:"createdAt" db:"created_at"`
+	Fields      []FeedbackTemplateField `json:"fields" db:"-"`
+}
+
+// FieldByKey returns the template's field with the given key.
+func (t *FeedbackTemplate) FieldByKey(key string) (*FeedbackTemplateField, bool) {
+	for i := range t.Fields {
+		if t.Fields[i].Key == key {
+			return &t.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+type FeedbackTemplateField struct {
+	ID         uuid.UUID         `json:"id" db:"id"`
+	TemplateID uuid.UUID         `json:"templateId" db:"template_id"`
+	Position   int               `json:"position" db:"position"`
+	Key        string            `json:"key" db:"key"`
+	Label      string            `json:"label" db:"label"`
+	Kind       FeedbackFieldKind `json:"kind" db:"kind"`
+	Required   bool              `json:"required" db:"required"`
+	MinScore   *int              `json:"minScore,omitempty" db:"min_score"`
+	MaxScore   *int              `json:"maxScore,omitempty" db:"max_score"`
+	Options    pq.StringArray    `json:"options" db:"options"`
+}
+
+// FeedbackAnswer is a coach's answer to one template field as submitted.
+// Only the part matching the field's kind is set: Score for scores, Text for
+// text, and Selected for choices and checklists.
+type FeedbackAnswer struct {
+	Score    *int     `json:"score,omitempty"`
+	Text     *string  `json:"text,omitempty"`
+	Selected []string `json:"selected,omitempty"`
+}
+
+// RubricSubmission is feedback given against a template, with answers keyed
+// by field key.
+type RubricSubmission struct {
+	TemplateID uuid.UUID                 `json:"templateId"`
+	Answers    map[string]FeedbackAnswer `json:"answers"`
+}
+
+// FeedbackResponse is a stored answer to one template field.
+type FeedbackResponse struct {
+	FeedbackID uuid.UUID      `json:"feedbackId" db:"feedback_id"`
+	FieldID    uuid.UUID      `json:"fieldId" db:"field_id"`
+	FieldKey   string         `json:"fieldKey" db:"field_key"`
+	Score      *int           `json:"score,omitempty" db:"score"`
+	Text       *string        `json:"text,omitempty" db:"text"`
+	Selected   pq.StringArray `json:"selected,omitempty" db:"selected"`
+}
+
+// FeedbackResponses is stored as JSON where a snapshot of the answers is
+// kept, as in feedback revisions.
+type FeedbackResponses []FeedbackResponse
+
+func (r FeedbackResponses) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (r *FeedbackResponses) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, r)
+	case string:
+		return json.Unmarshal([]byte(src), r)
+	default:
+		return fmt.Errorf("cannot scan %T into FeedbackResponses", src)
+	}
+}
+
+// FeedbackFieldReport aggregates the answers to one template field.
+// Distribution counts each score, or each option for choices and
+// checklists; it is empty for text fields. Average is set for scores only.
+type FeedbackFieldReport struct {
+	FieldID      uuid.UUID         `json:"fieldId"`
+	Key          string            `json:"key"`
+	Label        string            `json:"label"`
+	Kind         FeedbackFieldKind `json:"kind"`
+	Responses    int               `json:"responses"`
+	Average      *float64          `json:"average"`
+	Distribution map[string]int    `json:"distribution"`
+}
+
+// FeedbackTemplateReport reports on every field of a template over a period.
+type FeedbackTemplateReport struct {
+	TemplateID uuid.UUID             `json:"templateId"`
+	From       time.Time             `json:"from"`
+	To         time.Time             `json:"to"`
+	Sessions   int                   `json:"sessions"`
+	Fields     []FeedbackFieldReport `json:"fields"`
+}
+
+// FieldResponseStats and FieldValueCount are the per-field aggregates a
+// template report is built from.
+type FieldResponseStats struct {
+	FieldID   uuid.UUID `db:"field_id"`
+	Responses int       `db:"responses"`
+	Average   *float64  `db:"average"`
+}
+
+type FieldValueCount struct {
+	FieldID uuid.UUID `db:"field_id"`
+	Value   string    `db:"value"`
+	Count   int       `db:"count"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"userId" db:"user_id"`
+	Message   string    `json:"message" db:"message"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusCaptured PaymentStatus = "captured"
+	PaymentStatusFailed   PaymentStatus = "failed"
+	PaymentStatusRefunded PaymentStatus = "refunded"
+	// The booking was cancelled but the provider rejected the refund; it
+	// needs to be retried by hand.
+	PaymentStatusRefundFailed PaymentStatus = "refund_failed"
+)
+
+// PaymentIntent records one attempt to charge a student for a seat in a slot.
+type PaymentIntent struct {
+	ID            uuid.UUID     `json:"id" db:"id"`
+	SlotID        uuid.UUID     `json:"slotId" db:"slot_id"`
+	StudentID     uuid.UUID     `json:"studentId" db:"student_id"`
+	AmountCents   int           `json:"amountCents" db:"amount_cents"`
+	Currency      string        `json:"currency" db:"currency"`
+	ProviderRef   string        `json:"-" db:"provider_ref"`
+	Status        PaymentStatus `json:"status" db:"status"`
+	FailureReason string        `json:"failureReason,omitempty" db:"failure_reason"`
+	CreatedAt     time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time     `json:"updatedAt" db:"updated_at"`
+}
@@ -7,11 +7,31 @@ import (
 )
 
 type SessionFeedback struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	SlotID       uuid.UUID `json:"slotId" db:"slot_id"`
-	CoachId      uuid.UUID `json:"coachId" db:"coach_id"`
-	StudentId    uuid.UUID `json:"studentId" db:"student_id"`
-	Satisfaction int       `json:"satisfaction" db:"satisfaction"`
-	Notes        string    `json:"notes" db:"notes"`
-	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	ID           uuid.UUID  `json:"id" db:"id"`
+	SlotID       uuid.UUID  `json:"slotId" db:"slot_id"`
+	CoachId      uuid.UUID  `json:"coachId" db:"coach_id"`
+	StudentId    uuid.UUID  `json:"studentId" db:"student_id"`
+	Satisfaction int        `json:"satisfaction" db:"satisfaction"`
+	Notes        string     `json:"notes" db:"notes"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	// TemplateID names the rubric the feedback was given against, if any,
+	// and Responses holds the answers to it.
+	TemplateID *uuid.UUID         `json:"templateId,omitempty" db:"template_id"`
+	Responses  []FeedbackResponse `json:"responses,omitempty" db:"-"`
+}
+
+// SessionFeedbackRevision is one version of a feedback record. Revisions are
+// never changed; the first is the feedback as originally given.
+type SessionFeedbackRevision struct {
+	ID           uuid.UUID         `json:"id" db:"id"`
+	FeedbackID   uuid.UUID         `json:"feedbackId" db:"feedback_id"`
+	Revision     int               `json:"revision" db:"revision"`
+	Satisfaction int               `json:"satisfaction" db:"satisfaction"`
+	Notes        string            `json:"notes" db:"notes"`
+	AuthorID     uuid.UUID         `json:"authorId" db:"author_id"`
+	AuthorName   string            `json:"authorName" db:"author_name"`
+	CreatedAt    time.Time         `json:"createdAt" db:"created_at"`
+	TemplateID   *uuid.UUID        `json:"templateId,omitempty" db:"template_id"`
+	Responses    FeedbackResponses `json:"responses,omitempty" db:"responses"`
 }
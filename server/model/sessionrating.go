@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MinSessionRating = 1
+	MaxSessionRating = 5
+)
+
+// SessionRating is a student's verdict on a session they attended: a star
+// rating for the coach, comments, and whether the session was useful.
+type SessionRating struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	SlotID    uuid.UUID `json:"slotId" db:"slot_id"`
+	CoachID   uuid.UUID `json:"coachId" db:"coach_id"`
+	StudentID uuid.UUID `json:"studentId" db:"student_id"`
+	Rating    int       `json:"rating" db:"rating"`
+	Comments  string    `json:"comments" db:"comments"`
+	Useful    bool      `json:"useful" db:"useful"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// CoachRatingSummary aggregates the ratings students gave a coach.
+// Distribution maps each star value to how many ratings gave it; Average is
+// nil until the coach has been rated.
+type CoachRatingSummary struct {
+	CoachID      uuid.UUID   `json:"coachId"`
+	Average      *float64    `json:"average"`
+	Count        int         `json:"count"`
+	UsefulCount  int         `json:"usefulCount"`
+	Distribution map[int]int `json:"distribution"`
+}
+
+// RatingCount is how many ratings gave a star value, and how many of those
+// found the session useful.
+type RatingCount struct {
+	Rating      int `db:"rating"`
+	Count       int `db:"count"`
+	UsefulCount int `db:"useful_count"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SessionType struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	CoachID         uuid.UUID `json:"coachId" db:"coach_id"`
+	Name            string    `json:"name" db:"name"`
+	DurationMinutes int       `json:"durationMinutes" db:"duration_minutes"`
+	Description     string    `json:"description" db:"description"`
+	// PriceCents is charged at booking time. Free session types cost one
+	// session credit instead.
+	PriceCents int    `json:"priceCents" db:"price_cents"`
+	Currency   string `json:"currency" db:"currency"`
+}
+
+func (st SessionType) Duration() time.Duration {
+	return time.Duration(st.DurationMinutes) * time.Minute
+}
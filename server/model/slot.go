@@ -6,20 +6,47 @@ import (
 	"github.com/google/uuid"
 )
 
+type SlotStatus string
+
+const (
+	SlotStatusOpen      SlotStatus = "open"
+	SlotStatusBooked    SlotStatus = "booked"
+	SlotStatusCompleted SlotStatus = "completed"
+	SlotStatusCancelled SlotStatus = "cancelled"
+	SlotStatusNoShow    SlotStatus = "no_show"
+)
+
+// Slot is a session a coach offers. Booked is set once all Capacity seats are
+// taken; Status tracks the session itself.
 type Slot struct {
-	ID        uuid.UUID  `json:"id" db:"id"`
-	CoachID   uuid.UUID  `json:"coachId" db:"coach_id"`
-	CoachName string     `json:"coachName" db:"coach_name"`
-	StudentID *uuid.UUID `json:"studentId" db:"student_id"`
-	StartTime time.Time  `json:"startTime" db:"start_time"`
-	EndTime   time.Time  `json:"endTime" db:"end_time"`
-	Booked    bool       `json:"booked" db:"booked"`
+	ID                 uuid.UUID  `json:"id" db:"id"`
+	CoachID            uuid.UUID  `json:"coachId" db:"coach_id"`
+	CoachName          string     `json:"coachName" db:"coach_name"`
+	SessionTypeID      *uuid.UUID `json:"sessionTypeId" db:"session_type_id"`
+	SessionTypeName    *string    `json:"sessionTypeName,omitempty" db:"session_type_name"`
+	AvailabilityRuleID *uuid.UUID `json:"availabilityRuleId,omitempty" db:"availability_rule_id"`
+	StartTime          time.Time  `json:"startTime" db:"start_time"`
+	EndTime            time.Time  `json:"endTime" db:"end_time"`
+	Booked             bool       `json:"booked" db:"booked"`
+	Status             SlotStatus `json:"status" db:"status"`
+	Capacity           int        `json:"capacity" db:"capacity"`
+	SeatsRemaining     int        `json:"seatsRemaining" db:"seats_remaining"`
+	Attendees          []Booking  `json:"attendees,omitempty" db:"-"`
 }
 
 type SlotDetails struct {
 	Slot
-	CoachName          string `db:"coach_name" json:"coachName"`
-	CoachPhoneNumber   string `db:"coach_phone_number" json:"coachPhoneNumber"`
-	StudentName        string `db:"student_name" json:"studentName,omitempty"`
-	StudentPhoneNumber string `db:"student_phone_number" json:"studentPhoneNumber,omitempty"`
+	CoachName        string `db:"coach_name" json:"coachName"`
+	CoachPhoneNumber string `db:"coach_phone_number" json:"coachPhoneNumber"`
+}
+
+// HasAttendee reports whether the student holds a seat in the slot. Attendees
+// must have been loaded.
+func (s *Slot) HasAttendee(studentID uuid.UUID) bool {
+	for _, attendee := range s.Attendees {
+		if attendee.StudentID == studentID {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SlotSearchSort string
+
+const (
+	SlotSearchSortEarliest SlotSearchSort = "earliest"
+	SlotSearchSortLatest   SlotSearchSort = "latest"
+)
+
+// SlotSearchCriteria narrows a search for bookable slots across coaches.
+// Zero values leave a filter off. Weekdays and the time-of-day window are
+// read in TimeZone; minutes count from midnight.
+type SlotSearchCriteria struct {
+	From               *time.Time
+	To                 *time.Time
+	Weekdays           []time.Weekday
+	TimeZone           string
+	StartMinute        *int
+	EndMinute          *int
+	MinDurationMinutes int
+	MaxDurationMinutes int
+	CoachIDs           []uuid.UUID
+	CoachName          string
+	SessionTypeName    string
+	Sort               SlotSearchSort
+}
@@ -9,6 +9,8 @@ type UserRole string
 const (
 	RoleCoach   UserRole = "coach"
 	RoleStudent UserRole = "student"
+	// Program leads oversee all coaches and their students.
+	RoleProgramLead UserRole = "program_lead"
 )
 
 type User struct {
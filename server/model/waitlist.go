@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting  WaitlistStatus = "waiting"
+	WaitlistStatusPromoted WaitlistStatus = "promoted"
+	WaitlistStatusLeft     WaitlistStatus = "left"
+)
+
+// WaitlistEntry is a student's place in line either for one slot (SlotID)
+// or for any slot with the coach in the week starting on WeekStart, a Monday
+// in the coach's time zone.
+type WaitlistEntry struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	StudentID uuid.UUID      `json:"studentId" db:"student_id"`
+	CoachID   uuid.UUID      `json:"coachId" db:"coach_id"`
+	SlotID    *uuid.UUID     `json:"slotId,omitempty" db:"slot_id"`
+	WeekStart *time.Time     `json:"weekStart,omitempty" db:"week_start"`
+	Status    WaitlistStatus `json:"status" db:"status"`
+	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
+}
+
+type PromotionStatus string
+
+const (
+	PromotionStatusOffered   PromotionStatus = "offered"
+	PromotionStatusConfirmed PromotionStatus = "confirmed"
+	PromotionStatusDeclined  PromotionStatus = "declined"
+	PromotionStatusExpired   PromotionStatus = "expired"
+)
+
+// WaitlistPromotion records a seat offered to a waitlisted student. The seat
+// is held until ExpiresAt; the student confirms or declines it before then.
+type WaitlistPromotion struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	EntryID    uuid.UUID       `json:"entryId" db:"entry_id"`
+	SlotID     uuid.UUID       `json:"slotId" db:"slot_id"`
+	StudentID  uuid.UUID       `json:"studentId" db:"student_id"`
+	Status     PromotionStatus `json:"status" db:"status"`
+	ExpiresAt  time.Time       `json:"expiresAt" db:"expires_at"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+	ResolvedAt *time.Time      `json:"resolvedAt,omitempty" db:"resolved_at"`
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type AvailabilityRuleRepository struct {
+	dbc db.DbClient
+}
+
+func NewAvailabilityRuleRepository(dbc db.DbClient) *AvailabilityRuleRepository {
+	return &AvailabilityRuleRepository{dbc: dbc}
+}
+
+func (r *AvailabilityRuleRepository) CreateAvailabilityRule(ctx context.Context, rule model.AvailabilityRule) error {
+	query := `INSERT INTO availability_rule (id, coach_id, session_type_id, rrule, start_date, start_minute, end_minute, created_at, updated_at)
+			  VALUES (:id, :coach_id, :session_type_id, :rrule, :start_date, :start_minute, :end_minute, :created_at, :updated_at)`
+	_, err := r.dbc.NamedExec(ctx, query, rule)
+	return err
+}
+
+func (r *AvailabilityRuleRepository) GetAvailabilityRuleByID(ctx context.Context, id uuid.UUID) (*model.AvailabilityRule, error) {
+	var rule model.AvailabilityRule
+	query := `SELECT * FROM availability_rule WHERE id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &rule, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *AvailabilityRuleRepository) GetAvailabilityRulesByCoach(ctx context.Context, coachID uuid.UUID) ([]model.AvailabilityRule, error) {
+	var rules []model.AvailabilityRule
+	query := `SELECT * FROM availability_rule WHERE coach_id = $1 ORDER BY created_at ASC`
+	err := r.dbc.Select(ctx, &rules, query, coachID)
+	return rules, err
+}
+
+func (r *AvailabilityRuleRepository) UpdateAvailabilityRule(ctx context.Context, rule model.AvailabilityRule) error {
+	query := `UPDATE availability_rule
+			  SET session_type_id = :session_type_id,
+				  rrule = :rrule,
+				  start_date = :start_date,
+				  start_minute = :start_minute,
+				  end_minute = :end_minute,
+				  updated_at = :updated_at
+			  WHERE id = :id`
+	_, err := r.dbc.NamedExec(ctx, query, rule)
+	return err
+}
+
+func (r *AvailabilityRuleRepository) DeleteAvailabilityRule(ctx context.Context, id uuid.UUID) error {
+	_, err := r.dbc.ExecuteCommand(ctx, `DELETE FROM availability_rule WHERE id = $1`, id)
+	return err
+}
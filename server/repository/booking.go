@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type BookingRepository struct {
+	dbc db.DbClient
+}
+
+func NewBookingRepository(dbc db.DbClient) *BookingRepository {
+	return &BookingRepository{dbc: dbc}
+}
+
+func (r *BookingRepository) GetBooking(ctx context.Context, slotID, studentID uuid.UUID) (*model.Booking, error) {
+	var booking model.Booking
+	query := `
+		SELECT b.*, u.name AS student_name, u.phone_number AS student_phone_number
+		FROM booking b
+		JOIN stepful_user u ON b.student_id = u.id
+		WHERE b.slot_id = $1 AND b.student_id = $2`
+	err := r.dbc.GetSingleEntity(ctx, &booking, query, slotID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// GetBookingsForSlots returns the attendees of the given slots in the order
+// they booked.
+func (r *BookingRepository) GetBookingsForSlots(ctx context.Context, slotIDs []uuid.UUID) ([]model.Booking, error) {
+	ids := make([]string, len(slotIDs))
+	for i, id := range slotIDs {
+		ids[i] = id.String()
+	}
+	var bookings []model.Booking
+	query := `
+		SELECT b.*, u.name AS student_name, u.phone_number AS student_phone_number
+		FROM booking b
+		JOIN stepful_user u ON b.student_id = u.id
+		WHERE b.slot_id = ANY($1::uuid[])
+		ORDER BY b.created_at ASC`
+	err := r.dbc.Select(ctx, &bookings, query, pq.StringArray(ids))
+	return bookings, err
+}
+
+// ConfirmBooking turns a held seat into a confirmed booking and marks the slot
+// booked.
+func (r *BookingRepository) ConfirmBooking(ctx context.Context, slotID, studentID uuid.UUID) error {
+	query := `UPDATE booking SET status = 'confirmed', expires_at = NULL WHERE slot_id = $1 AND student_id = $2`
+	if _, err := r.dbc.ExecuteCommand(ctx, query, slotID, studentID); err != nil {
+		return err
+	}
+	return NewSlotRepository(r.dbc).refreshOccupancy(ctx, slotID)
+}
+
+func (r *BookingRepository) GetBookingByID(ctx context.Context, id uuid.UUID) (*model.Booking, error) {
+	var booking model.Booking
+	query := `SELECT * FROM booking WHERE id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &booking, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// MarkPending turns a booking into a request awaiting the coach's approval
+// until expiresAt. The slot is open again unless another booking is confirmed.
+func (r *BookingRepository) MarkPending(ctx context.Context, slotID, studentID uuid.UUID, expiresAt time.Time) error {
+	query := `UPDATE booking SET status = 'pending', expires_at = $3 WHERE slot_id = $1 AND student_id = $2`
+	if _, err := r.dbc.ExecuteCommand(ctx, query, slotID, studentID, expiresAt); err != nil {
+		return err
+	}
+	return NewSlotRepository(r.dbc).refreshOccupancy(ctx, slotID)
+}
+
+// GetPendingRequestsForCoach returns open booking requests for the coach's
+// slots, oldest first so they are answered in order.
+func (r *BookingRepository) GetPendingRequestsForCoach(ctx context.Context, coachID uuid.UUID, offset, pagesize int) ([]model.BookingRequest, int, error) {
+	var totalCount int
+	query := `
+		SELECT COUNT(*)
+		FROM booking b
+		JOIN slot s ON b.slot_id = s.id
+		WHERE s.coach_id = $1 AND b.status = 'pending'`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, coachID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var requests []model.BookingRequest
+	query = `
+		SELECT b.*, u.name AS student_name, u.phone_number AS student_phone_number,
+			s.coach_id, s.start_time, s.end_time
+		FROM booking b
+		JOIN slot s ON b.slot_id = s.id
+		JOIN stepful_user u ON b.student_id = u.id
+		WHERE s.coach_id = $1 AND b.status = 'pending'
+		ORDER BY b.created_at ASC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &requests, query, coachID, pagesize, offset)
+	return requests, totalCount, err
+}
+
+func (r *BookingRepository) GetPendingRequestsForStudent(ctx context.Context, studentID uuid.UUID, offset, pagesize int) ([]model.BookingRequest, int, error) {
+	var totalCount int
+	query := `SELECT COUNT(*) FROM booking WHERE student_id = $1 AND status = 'pending'`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, studentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var requests []model.BookingRequest
+	query = `
+		SELECT b.*, u.name AS student_name, s.coach_id, s.start_time, s.end_time
+		FROM booking b
+		JOIN slot s ON b.slot_id = s.id
+		JOIN stepful_user u ON b.student_id = u.id
+		WHERE b.student_id = $1 AND b.status = 'pending'
+		ORDER BY s.start_time ASC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &requests, query, studentID, pagesize, offset)
+	return requests, totalCount, err
+}
+
+// GetExpiredRequests returns booking requests whose answer window has passed.
+func (r *BookingRepository) GetExpiredRequests(ctx context.Context) ([]model.Booking, error) {
+	var bookings []model.Booking
+	query := `SELECT * FROM booking WHERE status = 'pending' AND expires_at <= NOW() ORDER BY expires_at ASC`
+	err := r.dbc.Select(ctx, &bookings, query)
+	return bookings, err
+}
+
+// GetActiveCheckoutHold returns the student's unexpired checkout hold, or
+// sql.ErrNoRows if they have none.
+func (r *BookingRepository) GetActiveCheckoutHold(ctx context.Context, studentID uuid.UUID) (*model.Booking, error) {
+	var booking model.Booking
+	query := `
+		SELECT * FROM booking
+		WHERE student_id = $1 AND status = 'checkout' AND expires_at > NOW()
+		LIMIT 1`
+	err := r.dbc.GetSingleEntity(ctx, &booking, query, studentID)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// GetExpiredCheckoutHolds returns checkout holds whose time is up.
+func (r *BookingRepository) GetExpiredCheckoutHolds(ctx context.Context) ([]model.Booking, error) {
+	var bookings []model.Booking
+	query := `SELECT * FROM booking WHERE status = 'checkout' AND expires_at <= NOW() ORDER BY expires_at ASC`
+	err := r.dbc.Select(ctx, &bookings, query)
+	return bookings, err
+}
+
+// MarkAttendance records how the student's session went. Marking again
+// replaces the earlier record.
+func (r *BookingRepository) MarkAttendance(ctx context.Context, slotID, studentID uuid.UUID, attendance model.AttendanceStatus, markedBy uuid.UUID, markedAt time.Time) error {
+	query := `
+		UPDATE booking
+		SET attendance = $3, attendance_marked_by = $4, attendance_marked_at = $5
+		WHERE slot_id = $1 AND student_id = $2`
+	_, err := r.dbc.ExecuteCommand(ctx, query, slotID, studentID, attendance, markedBy, markedAt)
+	return err
+}
+
+// GetNoShowSummary counts the sessions the student missed, and those among
+// them that started after since.
+func (r *BookingRepository) GetNoShowSummary(ctx context.Context, studentID uuid.UUID, since time.Time) (*model.NoShowSummary, error) {
+	var summary model.NoShowSummary
+	query := `
+		SELECT
+			$1::uuid AS student_id,
+			COUNT(*) FILTER (WHERE s.start_time >= $2) AS recent_no_shows,
+			COUNT(*) AS total_no_shows
+		FROM booking b
+		JOIN slot s ON b.slot_id = s.id
+		WHERE b.student_id = $1 AND b.attendance = 'student_no_show'`
+	err := r.dbc.GetSingleEntity(ctx, &summary, query, studentID, since)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// HasBookedWithCoach reports whether the student has ever booked one of the
+// coach's slots, whatever became of the booking.
+func (r *BookingRepository) HasBookedWithCoach(ctx context.Context, studentID, coachID uuid.UUID) (bool, error) {
+	var booked bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM booking b
+			JOIN slot s ON b.slot_id = s.id
+			WHERE b.student_id = $1 AND s.coach_id = $2
+		)`
+	err := r.dbc.GetSingleEntity(ctx, &booked, query, studentID, coachID)
+	return booked, err
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type BookingHistoryRepository struct {
+	dbc db.DbClient
+}
+
+func NewBookingHistoryRepository(dbc db.DbClient) *BookingHistoryRepository {
+	return &BookingHistoryRepository{dbc: dbc}
+}
+
+func (r *BookingHistoryRepository) CreateBookingHistory(ctx context.Context, entry model.BookingHistory) error {
+	query := `INSERT INTO booking_history (id, slot_id, new_slot_id, coach_id, student_id, event, actor_id, reason, start_time, end_time, created_at)
+			  VALUES (:id, :slot_id, :new_slot_id, :coach_id, :student_id, :event, :actor_id, :reason, :start_time, :end_time, :created_at)`
+	_, err := r.dbc.NamedExec(ctx, query, entry)
+	return err
+}
+
+func (r *BookingHistoryRepository) GetBookingHistoryForCoach(ctx context.Context, coachID uuid.UUID, offset, pagesize int) ([]model.BookingHistory, int, error) {
+	var totalCount int
+	query := `SELECT COUNT(*) FROM booking_history WHERE coach_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, coachID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var entries []model.BookingHistory
+	query = `
+		SELECT bh.*, u.name AS student_name
+		FROM booking_history bh
+		JOIN stepful_user u ON bh.student_id = u.id
+		WHERE bh.coach_id = $1
+		ORDER BY bh.created_at DESC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &entries, query, coachID, pagesize, offset)
+	return entries, totalCount, err
+}
+
+func (r *BookingHistoryRepository) GetBookingHistoryForStudent(ctx context.Context, studentID uuid.UUID, offset, pagesize int) ([]model.BookingHistory, int, error) {
+	var totalCount int
+	query := `SELECT COUNT(*) FROM booking_history WHERE student_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, studentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var entries []model.BookingHistory
+	query = `
+		SELECT bh.*, u.name AS student_name
+		FROM booking_history bh
+		JOIN stepful_user u ON bh.student_id = u.id
+		WHERE bh.student_id = $1
+		ORDER BY bh.created_at DESC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &entries, query, studentID, pagesize, offset)
+	return entries, totalCount, err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type CoachProfileRepository struct {
+	dbc db.DbClient
+}
+
+func NewCoachProfileRepository(dbc db.DbClient) *CoachProfileRepository {
+	return &CoachProfileRepository{dbc: dbc}
+}
+
+// GetCoachProfile returns the coach's profile including working hours. It
+// returns sql.ErrNoRows when the coach has not configured a profile.
+func (r *CoachProfileRepository) GetCoachProfile(ctx context.Context, coachID uuid.UUID) (*model.CoachProfile, error) {
+	var profile model.CoachProfile
+	query := `
+		SELECT coach_id, time_zone, min_cancellation_notice_minutes, bio, photo_url, specialties, languages,
+			requires_booking_approval, booking_request_ttl_minutes,
+			min_booking_lead_minutes, max_booking_horizon_days, max_student_no_shows
+		FROM coach_profile
+		WHERE coach_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &profile, query, coachID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT coach_id, weekday, start_minute, end_minute
+		FROM coach_working_hours
+		WHERE coach_id = $1
+		ORDER BY weekday ASC, start_minute ASC`
+	err = r.dbc.Select(ctx, &profile.WorkingHours, query, coachID)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetCoachDirectory lists coaches by name. Coaches without a saved profile
+// are included with the default time zone and empty profile fields.
+// availableBefore, when set, keeps only coaches with a bookable slot starting
+// before it.
+func (r *CoachProfileRepository) GetCoachDirectory(ctx context.Context, specialty string, availableBefore *time.Time, offset, pagesize int) ([]model.CoachDirectoryEntry, int, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// A slot is bookable while it is open or partly booked and has a seat left
+	nextAvailable := `
+		SELECT MIN(s.start_time)
+		FROM slot s
+		WHERE s.coach_id = u.id AND
+			s.status IN ('open', 'booked') AND
+			s.booked = false AND
+			s.start_time > NOW()`
+	conditions := []string{"u.user_role = 'coach'"}
+	if specialty != "" {
+		conditions = append(conditions, fmt.Sprintf("p.specialties @> ARRAY[%s]::text[]", arg(specialty)))
+	}
+	if availableBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s) < %s", nextAvailable, arg(*availableBefore)))
+	}
+	from := `
+		FROM stepful_user u
+		LEFT JOIN coach_profile p ON p.coach_id = u.id
+		WHERE ` + strings.Join(conditions, " AND ")
+
+	var totalCount int
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, `SELECT COUNT(*) `+from, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var coaches []model.CoachDirectoryEntry
+	query := fmt.Sprintf(`
+		SELECT
+			u.id,
+			u.name,
+			COALESCE(p.time_zone, %s) AS time_zone,
+			COALESCE(p.bio, '') AS bio,
+			COALESCE(p.photo_url, '') AS photo_url,
+			COALESCE(p.specialties, '{}') AS specialties,
+			COALESCE(p.languages, '{}') AS languages,
+			(%s) AS next_available_at,
+			(SELECT AVG(r.rating)::float8 FROM session_rating r WHERE r.coach_id = u.id) AS average_rating,
+			(SELECT COUNT(*) FROM session_rating r WHERE r.coach_id = u.id) AS rating_count
+		%s
+		ORDER BY u.name ASC, u.id ASC
+		LIMIT %s OFFSET %s`,
+		arg(model.DefaultCoachTimeZone), nextAvailable, from, arg(pagesize), arg(offset))
+	err = r.dbc.Select(ctx, &coaches, query, args...)
+	return coaches, totalCount, err
+}
+
+func (r *CoachProfileRepository) UpsertCoachProfile(ctx context.Context, profile model.CoachProfile) error {
+	query := `INSERT INTO coach_profile (coach_id, time_zone, min_cancellation_notice_minutes, bio, photo_url, specialties, languages,
+				  requires_booking_approval, booking_request_ttl_minutes, min_booking_lead_minutes, max_booking_horizon_days,
+				  max_student_no_shows)
+			  VALUES (:coach_id, :time_zone, :min_cancellation_notice_minutes, :bio, :photo_url, :specialties, :languages,
+				  :requires_booking_approval, :booking_request_ttl_minutes, :min_booking_lead_minutes, :max_booking_horizon_days,
+				  :max_student_no_shows)
+			  ON CONFLICT (coach_id) DO UPDATE SET
+				  time_zone = EXCLUDED.time_zone,
+				  min_cancellation_notice_minutes = EXCLUDED.min_cancellation_notice_minutes,
+				  bio = EXCLUDED.bio,
+				  photo_url = EXCLUDED.photo_url,
+				  specialties = EXCLUDED.specialties,
+				  languages = EXCLUDED.languages,
+				  requires_booking_approval = EXCLUDED.requires_booking_approval,
+				  booking_request_ttl_minutes = EXCLUDED.booking_request_ttl_minutes,
+				  min_booking_lead_minutes = EXCLUDED.min_booking_lead_minutes,
+				  max_booking_horizon_days = EXCLUDED.max_booking_horizon_days,
+				  max_student_no_shows = EXCLUDED.max_student_no_shows`
+	_, err := r.dbc.NamedExec(ctx, query, profile)
+	return err
+}
+
+func (r *CoachProfileRepository) ReplaceWorkingHours(ctx context.Context, coachID uuid.UUID, hours []model.WorkingHours) error {
+	_, err := r.dbc.ExecuteCommand(ctx, `DELETE FROM coach_working_hours WHERE coach_id = $1`, coachID)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO coach_working_hours (coach_id, weekday, start_minute, end_minute)
+			  VALUES (:coach_id, :weekday, :start_minute, :end_minute)`
+	for _, h := range hours {
+		h.CoachID = coachID
+		if _, err := r.dbc.NamedExec(ctx, query, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type CreditRepository struct {
+	dbc db.DbClient
+}
+
+func NewCreditRepository(dbc db.DbClient) *CreditRepository {
+	return &CreditRepository{dbc: dbc}
+}
+
+func (r *CreditRepository) CreateEntry(ctx context.Context, entry model.CreditLedgerEntry) error {
+	query := `INSERT INTO credit_ledger_entry (id, student_id, kind, amount, slot_id, actor_id, note, created_at)
+			  VALUES (:id, :student_id, :kind, :amount, :slot_id, :actor_id, :note, :created_at)`
+	_, err := r.dbc.NamedExec(ctx, query, entry)
+	return err
+}
+
+func (r *CreditRepository) GetBalance(ctx context.Context, studentID uuid.UUID) (int, error) {
+	var balance int
+	query := `SELECT COALESCE(SUM(amount), 0) FROM credit_ledger_entry WHERE student_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &balance, query, studentID)
+	return balance, err
+}
+
+// GetSlotBalance returns the net credits the student has spent on the slot:
+// negative while a debit for it has not been refunded.
+func (r *CreditRepository) GetSlotBalance(ctx context.Context, studentID, slotID uuid.UUID) (int, error) {
+	var balance int
+	query := `SELECT COALESCE(SUM(amount), 0) FROM credit_ledger_entry WHERE student_id = $1 AND slot_id = $2`
+	err := r.dbc.GetSingleEntity(ctx, &balance, query, studentID, slotID)
+	return balance, err
+}
+
+func (r *CreditRepository) GetEntriesForStudent(ctx context.Context, studentID uuid.UUID, offset, pagesize int) ([]model.CreditLedgerEntry, int, error) {
+	var totalCount int
+	query := `SELECT COUNT(*) FROM credit_ledger_entry WHERE student_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, studentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var entries []model.CreditLedgerEntry
+	query = `
+		SELECT *
+		FROM credit_ledger_entry
+		WHERE student_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &entries, query, studentID, pagesize, offset)
+	return entries, totalCount, err
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type FeedbackTemplateRepository struct {
+	dbc db.DbClient
+}
+
+func NewFeedbackTemplateRepository(dbc db.DbClient) *FeedbackTemplateRepository {
+	return &FeedbackTemplateRepository{dbc: dbc}
+}
+
+// CreateTemplate stores the template together with its fields.
+func (r *FeedbackTemplateRepository) CreateTemplate(ctx context.Context, template model.FeedbackTemplate) error {
+	query := `INSERT INTO feedback_template (id, name, description, active, created_by, created_at)
+			  VALUES (:id, :name, :description, :active, :created_by, :created_at)`
+	if _, err := r.dbc.NamedExec(ctx, query, template); err != nil {
+		return err
+	}
+	query = `INSERT INTO feedback_template_field (id, template_id, position, key, label, kind, required, min_score, max_score, options)
+			  VALUES (:id, :template_id, :position, :key, :label, :kind, :required, :min_score, :max_score, :options)`
+	for _, field := range template.Fields {
+		if _, err := r.dbc.NamedExec(ctx, query, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTemplate returns the template with its fields in order, or
+// sql.ErrNoRows if there is none.
+func (r *FeedbackTemplateRepository) GetTemplate(ctx context.Context, id uuid.UUID) (*model.FeedbackTemplate, error) {
+	var template model.FeedbackTemplate
+	query := `SELECT * FROM feedback_template WHERE id = $1`
+	if err := r.dbc.GetSingleEntity(ctx, &template, query, id); err != nil {
+		return nil, err
+	}
+	query = `SELECT * FROM feedback_template_field WHERE template_id = $1 ORDER BY position ASC`
+	if err := r.dbc.Select(ctx, &template.Fields, query, id); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetTemplates lists templates by name, leaving out archived ones unless
+// includeArchived is set. Fields are not loaded.
+func (r *FeedbackTemplateRepository) GetTemplates(ctx context.Context, includeArchived bool) ([]model.FeedbackTemplate, error) {
+	var templates []model.FeedbackTemplate
+	query := `
+		SELECT * FROM feedback_template
+		WHERE active OR $1
+		ORDER BY name ASC, created_at ASC`
+	err := r.dbc.Select(ctx, &templates, query, includeArchived)
+	return templates, err
+}
+
+// GetFieldsForTemplates returns the fields of the given templates in order.
+func (r *FeedbackTemplateRepository) GetFieldsForTemplates(ctx context.Context, templateIDs []uuid.UUID) ([]model.FeedbackTemplateField, error) {
+	ids := make([]string, len(templateIDs))
+	for i, id := range templateIDs {
+		ids[i] = id.String()
+	}
+	var fields []model.FeedbackTemplateField
+	query := `
+		SELECT * FROM feedback_template_field
+		WHERE template_id = ANY($1::uuid[])
+		ORDER BY template_id, position ASC`
+	err := r.dbc.Select(ctx, &fields, query, pq.StringArray(ids))
+	return fields, err
+}
+
+func (r *FeedbackTemplateRepository) SetTemplateActive(ctx context.Context, id uuid.UUID, active bool) error {
+	query := `UPDATE feedback_template SET active = $2 WHERE id = $1`
+	_, err := r.dbc.ExecuteCommand(ctx, query, id, active)
+	return err
+}
+
+// ReplaceResponses stores the answers to a feedback's template in place of
+// any earlier ones.
+func (r *FeedbackTemplateRepository) ReplaceResponses(ctx context.Context, feedbackID uuid.UUID, responses []model.FeedbackResponse) error {
+	_, err := r.dbc.ExecuteCommand(ctx, `DELETE FROM feedback_response WHERE feedback_id = $1`, feedbackID)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO feedback_response (feedback_id, field_id, score, text, selected)
+			  VALUES (:feedback_id, :field_id, :score, :text, :selected)`
+	for _, response := range responses {
+		response.FeedbackID = feedbackID
+		if _, err := r.dbc.NamedExec(ctx, query, response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetResponsesForFeedback returns the stored answers of the given feedback,
+// in template field order.
+func (r *FeedbackTemplateRepository) GetResponsesForFeedback(ctx context.Context, feedbackIDs []uuid.UUID) ([]model.FeedbackResponse, error) {
+	ids := make([]string, len(feedbackIDs))
+	for i, id := range feedbackIDs {
+		ids[i] = id.String()
+	}
+	var responses []model.FeedbackResponse
+	query := `
+		SELECT fr.*, f.key AS field_key
+		FROM feedback_response fr
+		JOIN feedback_template_field f ON fr.field_id = f.id
+		WHERE fr.feedback_id = ANY($1::uuid[])
+		ORDER BY fr.feedback_id, f.position ASC`
+	err := r.dbc.Select(ctx, &responses, query, pq.StringArray(ids))
+	return responses, err
+}
+
+// GetFieldResponseStats counts the answers to each of the template's fields
+// on feedback matching the filter, averaging the scores.
+func (r *FeedbackTemplateRepository) GetFieldResponseStats(ctx context.Context, templateID uuid.UUID, filter model.SatisfactionAnalyticsFilter) ([]model.FieldResponseStats, error) {
+	args := []interface{}{templateID}
+	query := `
+		SELECT fr.field_id, COUNT(*) AS responses, AVG(fr.score)::float8 AS average
+		FROM feedback_response fr
+		JOIN session_feedback sf ON fr.feedback_id = sf.id
+		JOIN slot s ON sf.slot_id = s.id
+		WHERE sf.template_id = $1 AND ` + satisfactionConditions(filter, &args) + `
+		GROUP BY fr.field_id`
+	var stats []model.FieldResponseStats
+	err := r.dbc.Select(ctx, &stats, query, args...)
+	return stats, err
+}
+
+// GetFieldValueCounts counts how often each score was given, or each option
+// selected, per field of the template on feedback matching the filter.
+func (r *FeedbackTemplateRepository) GetFieldValueCounts(ctx context.Context, templateID uuid.UUID, filter model.SatisfactionAnalyticsFilter) ([]model.FieldValueCount, error) {
+	args := []interface{}{templateID}
+	query := `
+		SELECT fr.field_id, v.value, COUNT(*) AS count
+		FROM feedback_response fr
+		JOIN session_feedback sf ON fr.feedback_id = sf.id
+		JOIN slot s ON sf.slot_id = s.id
+		CROSS JOIN LATERAL (
+			SELECT fr.score::text WHERE fr.score IS NOT NULL
+			UNION ALL
+			SELECT unnest(fr.selected)
+		) AS v(value)
+		WHERE sf.template_id = $1 AND ` + satisfactionConditions(filter, &args) + `
+		GROUP BY fr.field_id, v.value`
+	var counts []model.FieldValueCount
+	err := r.dbc.Select(ctx, &counts, query, args...)
+	return counts, err
+}
+
+// CountTemplateFeedback counts feedback given against the template that
+// matches the filter.
+func (r *FeedbackTemplateRepository) CountTemplateFeedback(ctx context.Context, templateID uuid.UUID, filter model.SatisfactionAnalyticsFilter) (int, error) {
+	args := []interface{}{templateID}
+	query := `
+		SELECT COUNT(*)
+		FROM session_feedback sf
+		JOIN slot s ON sf.slot_id = s.id
+		WHERE sf.template_id = $1 AND ` + satisfactionConditions(filter, &args)
+	var count int
+	err := r.dbc.GetSingleEntity(ctx, &count, query, args...)
+	return count, err
+}
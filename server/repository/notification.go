@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type NotificationRepository struct {
+	dbc db.DbClient
+}
+
+func NewNotificationRepository(dbc db.DbClient) *NotificationRepository {
+	return &NotificationRepository{dbc: dbc}
+}
+
+func (r *NotificationRepository) CreateNotification(ctx context.Context, notification model.Notification) error {
+	query := `INSERT INTO notification (id, user_id, message, created_at)
+			  VALUES (:id, :user_id, :message, :created_at)`
+	_, err := r.dbc.NamedExec(ctx, query, notification)
+	return err
+}
+
+func (r *NotificationRepository) GetNotificationsForUser(ctx context.Context, userID uuid.UUID, offset, pagesize int) ([]model.Notification, int, error) {
+	var totalCount int
+	query := `SELECT COUNT(*) FROM notification WHERE user_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var notifications []model.Notification
+	query = `
+		SELECT *
+		FROM notification
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &notifications, query, userID, pagesize, offset)
+	return notifications, totalCount, err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type PaymentRepository struct {
+	dbc db.DbClient
+}
+
+func NewPaymentRepository(dbc db.DbClient) *PaymentRepository {
+	return &PaymentRepository{dbc: dbc}
+}
+
+func (r *PaymentRepository) CreateIntent(ctx context.Context, intent model.PaymentIntent) error {
+	query := `INSERT INTO payment_intent (id, slot_id, student_id, amount_cents, currency, provider_ref, status, failure_reason, created_at, updated_at)
+			  VALUES (:id, :slot_id, :student_id, :amount_cents, :currency, :provider_ref, :status, :failure_reason, :created_at, :updated_at)`
+	_, err := r.dbc.NamedExec(ctx, query, intent)
+	return err
+}
+
+// GetCapturedIntent returns the payment currently held for the student's seat
+// in the slot. It returns sql.ErrNoRows when the seat was not paid for.
+func (r *PaymentRepository) GetCapturedIntent(ctx context.Context, slotID, studentID uuid.UUID) (*model.PaymentIntent, error) {
+	var intent model.PaymentIntent
+	query := `SELECT * FROM payment_intent WHERE slot_id = $1 AND student_id = $2 AND status = 'captured'`
+	err := r.dbc.GetSingleEntity(ctx, &intent, query, slotID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+func (r *PaymentRepository) UpdateIntentStatus(ctx context.Context, id uuid.UUID, status model.PaymentStatus, failureReason string) error {
+	query := `UPDATE payment_intent SET status = $2, failure_reason = $3, updated_at = NOW() WHERE id = $1`
+	_, err := r.dbc.ExecuteCommand(ctx, query, id, status, failureReason)
+	return err
+}
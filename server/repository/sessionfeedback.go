@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/cargoreligion/booking/server/infrastructure/db"
 	"github.com/cargoreligion/booking/server/model"
 	"github.com/google/uuid"
@@ -14,24 +18,93 @@ func NewSessionFeedbackRepository(dbc db.DbClient) *SessionFeedbackRepository {
 	return &SessionFeedbackRepository{dbc: dbc}
 }
 
-func (r *SessionFeedbackRepository) CreateSessionFeedback(feedback model.SessionFeedback) error {
-	query := `INSERT INTO session_feedback (id, slot_id, coach_id, student_id, satisfaction, notes, created_at) 
-			  VALUES (:id, :slot_id, :coach_id, :student_id, :satisfaction, :notes, :created_at)`
-	_, err := r.dbc.NamedExec(query, feedback)
+func (r *SessionFeedbackRepository) CreateSessionFeedback(ctx context.Context, feedback model.SessionFeedback) error {
+	query := `INSERT INTO session_feedback (id, slot_id, coach_id, student_id, satisfaction, notes, created_at, template_id) 
+			  VALUES (:id, :slot_id, :coach_id, :student_id, :satisfaction, :notes, :created_at, :template_id)`
+	_, err := r.dbc.NamedExec(ctx, query, feedback)
 	return err
 }
 
-func (r *SessionFeedbackRepository) GetPastSessionFeedback(coachID uuid.UUID) ([]model.SessionFeedback, error) {
+func (r *SessionFeedbackRepository) GetSessionFeedbackByID(ctx context.Context, id uuid.UUID) (*model.SessionFeedback, error) {
+	var feedback model.SessionFeedback
+	query := `SELECT * FROM session_feedback WHERE id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &feedback, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+// GetSessionFeedbackByIDForUpdate returns the feedback, locking it until the
+// transaction ends so concurrent edits get consecutive revisions.
+func (r *SessionFeedbackRepository) GetSessionFeedbackByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.SessionFeedback, error) {
+	var feedback model.SessionFeedback
+	query := `SELECT * FROM session_feedback WHERE id = $1 FOR UPDATE`
+	err := r.dbc.GetSingleEntity(ctx, &feedback, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+// GetSessionFeedbackForBooking returns the feedback on the student's session
+// in the slot, or sql.ErrNoRows if there is none yet.
+func (r *SessionFeedbackRepository) GetSessionFeedbackForBooking(ctx context.Context, slotID, studentID uuid.UUID) (*model.SessionFeedback, error) {
+	var feedback model.SessionFeedback
+	query := `SELECT * FROM session_feedback WHERE slot_id = $1 AND student_id = $2`
+	err := r.dbc.GetSingleEntity(ctx, &feedback, query, slotID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+func (r *SessionFeedbackRepository) UpdateSessionFeedback(ctx context.Context, feedback model.SessionFeedback) error {
+	query := `UPDATE session_feedback
+			  SET satisfaction = :satisfaction, notes = :notes, updated_at = :updated_at, template_id = :template_id
+			  WHERE id = :id`
+	_, err := r.dbc.NamedExec(ctx, query, feedback)
+	return err
+}
+
+// CreateRevision appends a version to the feedback's history, numbered after
+// the latest one. The caller must hold the feedback's row lock.
+func (r *SessionFeedbackRepository) CreateRevision(ctx context.Context, revision *model.SessionFeedbackRevision) error {
+	query := `SELECT COALESCE(MAX(revision), 0) + 1 FROM session_feedback_revision WHERE feedback_id = $1`
+	if err := r.dbc.GetSingleEntity(ctx, &revision.Revision, query, revision.FeedbackID); err != nil {
+		return err
+	}
+	query = `INSERT INTO session_feedback_revision (id, feedback_id, revision, satisfaction, notes, author_id, created_at, template_id, responses)
+			  VALUES (:id, :feedback_id, :revision, :satisfaction, :notes, :author_id, :created_at, :template_id, :responses)`
+	_, err := r.dbc.NamedExec(ctx, query, revision)
+	return err
+}
+
+// GetRevisions returns the feedback's history, oldest first.
+func (r *SessionFeedbackRepository) GetRevisions(ctx context.Context, feedbackID uuid.UUID) ([]model.SessionFeedbackRevision, error) {
+	var revisions []model.SessionFeedbackRevision
+	query := `
+			SELECT r.*, u.name AS author_name
+			FROM session_feedback_revision r
+			JOIN stepful_user u ON r.author_id = u.id
+			WHERE r.feedback_id = $1
+			ORDER BY r.revision ASC
+			`
+	err := r.dbc.Select(ctx, &revisions, query, feedbackID)
+	return revisions, err
+}
+
+func (r *SessionFeedbackRepository) GetPastSessionFeedback(ctx context.Context, coachID uuid.UUID) ([]model.SessionFeedback, error) {
 	var feedbacks []model.SessionFeedback
 	query := `SELECT sf.* FROM session_feedback sf
 			  JOIN slot s ON sf.slot_id = s.id
-			  WHERE s.coach_id = $1 AND ((s.end_time < NOW() AND s.status = 'active') OR s.status = 'ended')
+			  WHERE s.coach_id = $1 AND (s.status = 'completed' OR (s.status = 'booked' AND s.end_time < NOW()))
 			  ORDER BY s.start_time DESC`
-	err := r.dbc.Select(&feedbacks, query, coachID)
+	err := r.dbc.Select(ctx, &feedbacks, query, coachID)
 	return feedbacks, err
 }
 
-func (r *SessionFeedbackRepository) GetStudentsWithSessionsByCoach(coachID uuid.UUID) ([]model.User, error) {
+func (r *SessionFeedbackRepository) GetStudentsWithSessionsByCoach(ctx context.Context, coachID uuid.UUID) ([]model.User, error) {
 	var students []model.User
 	query := `
 			SELECT DISTINCT u.* 
@@ -40,11 +113,11 @@ func (r *SessionFeedbackRepository) GetStudentsWithSessionsByCoach(coachID uuid.
 			WHERE sf.coach_id = $1
 			ORDER BY u.name ASC
 			`
-	err := r.dbc.Select(&students, query, coachID)
+	err := r.dbc.Select(ctx, &students, query, coachID)
 	return students, err
 }
 
-func (r *SessionFeedbackRepository) GetSessionsForStudent(studentId, coachId uuid.UUID) ([]model.SessionFeedback, error) {
+func (r *SessionFeedbackRepository) GetSessionsForStudent(ctx context.Context, studentId, coachId uuid.UUID) ([]model.SessionFeedback, error) {
 	var feedbacks []model.SessionFeedback
 	query := `
 			SELECT sf.*
@@ -52,6 +125,157 @@ func (r *SessionFeedbackRepository) GetSessionsForStudent(studentId, coachId uui
 			WHERE sf.student_id = $1 AND sf.coach_id = $2
 			ORDER BY sf.created_at DESC
 			`
-	err := r.dbc.Select(&feedbacks, query, studentId, coachId)
+	err := r.dbc.Select(ctx, &feedbacks, query, studentId, coachId)
 	return feedbacks, err
 }
+
+// satisfactionConditions turns the filter into a WHERE clause over
+// session_feedback sf joined to its slot s, appending the values to args.
+func satisfactionConditions(filter model.SatisfactionAnalyticsFilter, args *[]interface{}) string {
+	arg := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+	conditions := []string{
+		"s.start_time >= " + arg(filter.From),
+		"s.start_time < " + arg(filter.To),
+	}
+	if filter.CoachID != nil {
+		conditions = append(conditions, "sf.coach_id = "+arg(*filter.CoachID))
+	}
+	if filter.StudentID != nil {
+		conditions = append(conditions, "sf.student_id = "+arg(*filter.StudentID))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// GetSatisfactionDistribution counts feedback by satisfaction score. Scores
+// nobody gave are left out.
+func (r *SessionFeedbackRepository) GetSatisfactionDistribution(ctx context.Context, filter model.SatisfactionAnalyticsFilter) ([]model.ScoreCount, error) {
+	var args []interface{}
+	query := `
+		SELECT sf.satisfaction AS score, COUNT(*) AS count
+		FROM session_feedback sf
+		JOIN slot s ON sf.slot_id = s.id
+		WHERE ` + satisfactionConditions(filter, &args) + `
+		GROUP BY sf.satisfaction
+		ORDER BY sf.satisfaction ASC`
+	var counts []model.ScoreCount
+	err := r.dbc.Select(ctx, &counts, query, args...)
+	return counts, err
+}
+
+// GetWeeklySatisfaction averages satisfaction per week for each coach, or
+// for each student when byStudent is set. Weeks start on Monday, UTC.
+func (r *SessionFeedbackRepository) GetWeeklySatisfaction(ctx context.Context, filter model.SatisfactionAnalyticsFilter, byStudent bool) ([]model.WeeklySatisfaction, error) {
+	subject := "sf.coach_id"
+	if byStudent {
+		subject = "sf.student_id"
+	}
+	var args []interface{}
+	where := satisfactionConditions(filter, &args)
+	query := fmt.Sprintf(`
+		WITH weekly AS (
+			SELECT
+				%s AS subject_id,
+				date_trunc('week', s.start_time AT TIME ZONE 'UTC') AS week_start,
+				COUNT(*) AS sessions,
+				SUM(sf.satisfaction) AS total
+			FROM session_feedback sf
+			JOIN slot s ON sf.slot_id = s.id
+			WHERE %s
+			GROUP BY 1, 2
+		)
+		SELECT
+			w.subject_id,
+			u.name AS subject_name,
+			w.week_start,
+			w.sessions,
+			w.total::float8 / w.sessions AS average,
+			(SUM(w.total) OVER rolling)::float8 / (SUM(w.sessions) OVER rolling) AS rolling_average
+		FROM weekly w
+		JOIN stepful_user u ON w.subject_id = u.id
+		WINDOW rolling AS (
+			PARTITION BY w.subject_id
+			ORDER BY w.week_start
+			RANGE BETWEEN INTERVAL '%d weeks' PRECEDING AND CURRENT ROW
+		)
+		ORDER BY u.name ASC, w.subject_id, w.week_start ASC`,
+		subject, where, model.SatisfactionRollingWeeks-1)
+	var weeks []model.WeeklySatisfaction
+	err := r.dbc.Select(ctx, &weeks, query, args...)
+	return weeks, err
+}
+
+// GetDecliningStudents returns students with at least minSessions rated
+// sessions whose satisfaction trends down, steepest decline first. The trend
+// is the least-squares slope of score against session time, in points per
+// week.
+func (r *SessionFeedbackRepository) GetDecliningStudents(ctx context.Context, filter model.SatisfactionAnalyticsFilter, minSessions int) ([]model.DecliningStudent, error) {
+	var args []interface{}
+	where := satisfactionConditions(filter, &args)
+	args = append(args, minSessions)
+	query := fmt.Sprintf(`
+		WITH trends AS (
+			SELECT
+				sf.student_id,
+				COUNT(*) AS sessions,
+				AVG(sf.satisfaction)::float8 AS average,
+				(array_agg(sf.satisfaction ORDER BY s.start_time ASC))[1] AS first_satisfaction,
+				(array_agg(sf.satisfaction ORDER BY s.start_time DESC))[1] AS latest_satisfaction,
+				regr_slope(sf.satisfaction, EXTRACT(EPOCH FROM s.start_time) / 604800) AS trend_per_week
+			FROM session_feedback sf
+			JOIN slot s ON sf.slot_id = s.id
+			WHERE %s
+			GROUP BY sf.student_id
+			HAVING COUNT(*) >= $%d
+		)
+		SELECT t.*, u.name AS student_name
+		FROM trends t
+		JOIN stepful_user u ON t.student_id = u.id
+		WHERE t.trend_per_week < 0
+		ORDER BY t.trend_per_week ASC, u.name ASC`,
+		where, len(args))
+	var students []model.DecliningStudent
+	err := r.dbc.Select(ctx, &students, query, args...)
+	return students, err
+}
+
+// SearchNotes finds the coach's feedback whose notes match the web-search
+// style query, best matches first. The notes are HTML-escaped before
+// highlighting so the snippet is safe to render with its <mark> tags.
+func (r *SessionFeedbackRepository) SearchNotes(ctx context.Context, coachID uuid.UUID, search string, offset, pagesize int) ([]model.FeedbackSearchResult, int, error) {
+	from := `
+		FROM session_feedback sf
+		JOIN slot s ON sf.slot_id = s.id
+		JOIN stepful_user u ON sf.student_id = u.id
+		CROSS JOIN websearch_to_tsquery('english', $2) q
+		WHERE sf.coach_id = $1 AND to_tsvector('english', sf.notes) @@ q`
+
+	var totalCount int
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, `SELECT COUNT(*) `+from, coachID, search)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var results []model.FeedbackSearchResult
+	query := `
+		SELECT
+			sf.id AS feedback_id,
+			sf.slot_id,
+			sf.student_id,
+			u.name AS student_name,
+			s.start_time,
+			s.end_time,
+			sf.satisfaction,
+			sf.created_at,
+			ts_rank_cd(to_tsvector('english', sf.notes), q)::float8 AS rank,
+			ts_headline('english',
+				replace(replace(replace(sf.notes, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=10') AS snippet
+		` + from + `
+		ORDER BY rank DESC, s.start_time DESC, sf.id ASC
+		LIMIT $3 OFFSET $4`
+	err = r.dbc.Select(ctx, &results, query, coachID, search, pagesize, offset)
+	return results, totalCount, err
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type SessionRatingRepository struct {
+	dbc db.DbClient
+}
+
+func NewSessionRatingRepository(dbc db.DbClient) *SessionRatingRepository {
+	return &SessionRatingRepository{dbc: dbc}
+}
+
+func (r *SessionRatingRepository) CreateRating(ctx context.Context, rating model.SessionRating) error {
+	query := `INSERT INTO session_rating (id, slot_id, coach_id, student_id, rating, comments, useful, created_at)
+			  VALUES (:id, :slot_id, :coach_id, :student_id, :rating, :comments, :useful, :created_at)`
+	_, err := r.dbc.NamedExec(ctx, query, rating)
+	return err
+}
+
+// GetRatingForBooking returns the student's rating of their session in the
+// slot, or sql.ErrNoRows if they have not rated it.
+func (r *SessionRatingRepository) GetRatingForBooking(ctx context.Context, slotID, studentID uuid.UUID) (*model.SessionRating, error) {
+	var rating model.SessionRating
+	query := `SELECT * FROM session_rating WHERE slot_id = $1 AND student_id = $2`
+	err := r.dbc.GetSingleEntity(ctx, &rating, query, slotID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+func (r *SessionRatingRepository) GetRatingsByStudent(ctx context.Context, studentID uuid.UUID, offset, pagesize int) ([]model.SessionRating, int, error) {
+	var totalCount int
+	query := `SELECT COUNT(*) FROM session_rating WHERE student_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, studentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var ratings []model.SessionRating
+	query = `
+		SELECT * FROM session_rating
+		WHERE student_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &ratings, query, studentID, pagesize, offset)
+	return ratings, totalCount, err
+}
+
+// GetCoachRatingCounts returns how many of the coach's ratings gave each
+// star value. Star values nobody gave are left out.
+func (r *SessionRatingRepository) GetCoachRatingCounts(ctx context.Context, coachID uuid.UUID) ([]model.RatingCount, error) {
+	var counts []model.RatingCount
+	query := `
+		SELECT rating, COUNT(*) AS count, COUNT(*) FILTER (WHERE useful) AS useful_count
+		FROM session_rating
+		WHERE coach_id = $1
+		GROUP BY rating
+		ORDER BY rating ASC`
+	err := r.dbc.Select(ctx, &counts, query, coachID)
+	return counts, err
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type SessionTypeRepository struct {
+	dbc db.DbClient
+}
+
+func NewSessionTypeRepository(dbc db.DbClient) *SessionTypeRepository {
+	return &SessionTypeRepository{dbc: dbc}
+}
+
+func (r *SessionTypeRepository) CreateSessionType(ctx context.Context, sessionType model.SessionType) error {
+	query := `INSERT INTO session_type (id, coach_id, name, duration_minutes, description, price_cents, currency)
+			  VALUES (:id, :coach_id, :name, :duration_minutes, :description, :price_cents, :currency)`
+	_, err := r.dbc.NamedExec(ctx, query, sessionType)
+	return err
+}
+
+func (r *SessionTypeRepository) GetSessionTypeByID(ctx context.Context, id uuid.UUID) (*model.SessionType, error) {
+	var sessionType model.SessionType
+	query := `SELECT * FROM session_type WHERE id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &sessionType, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionType, nil
+}
+
+func (r *SessionTypeRepository) GetSessionTypesByCoach(ctx context.Context, coachID uuid.UUID) ([]model.SessionType, error) {
+	var sessionTypes []model.SessionType
+	query := `SELECT * FROM session_type WHERE coach_id = $1 ORDER BY duration_minutes ASC, name ASC`
+	err := r.dbc.Select(ctx, &sessionTypes, query, coachID)
+	return sessionTypes, err
+}
@@ -1,11 +1,15 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cargoreligion/booking/server/infrastructure/db"
 	"github.com/cargoreligion/booking/server/model"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type SlotRepository struct {
@@ -16,162 +20,438 @@ func NewSlotRepository(dbc db.DbClient) *SlotRepository {
 	return &SlotRepository{dbc: dbc}
 }
 
-func (r *SlotRepository) CreateSlot(slot model.Slot) (uuid.UUID, error) {
-	query := `INSERT INTO slot (id, coach_id, start_time, end_time, booked) 
-			  VALUES (:id, :coach_id, :start_time, :end_time, :booked)
+func (r *SlotRepository) CreateSlot(ctx context.Context, slot model.Slot) (uuid.UUID, error) {
+	query := `INSERT INTO slot (id, coach_id, session_type_id, availability_rule_id, start_time, end_time, booked, status, capacity) 
+			  VALUES (:id, :coach_id, :session_type_id, :availability_rule_id, :start_time, :end_time, :booked, :status, :capacity)
 			  RETURNING id`
 	var id uuid.UUID
-	err := r.dbc.NamedGetSingleEntity(&id, query, slot)
+	err := r.dbc.NamedGetSingleEntity(ctx, &id, query, slot)
 	return id, err
 }
 
-func (r *SlotRepository) GetUpcomingSlots(coachID uuid.UUID, offset, pagesize int) ([]model.Slot, int, error) {
+func (r *SlotRepository) GetUpcomingSlots(ctx context.Context, coachID uuid.UUID, offset, pagesize int) ([]model.Slot, int, error) {
 	var totalCount int
 	query := `SELECT COUNT(*) FROM slot WHERE coach_id = $1 AND start_time > NOW()`
-	err := r.dbc.GetSingleEntity(&totalCount, query, coachID)
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, coachID)
 	if err != nil {
 		return nil, 0, err
 	}
 	var slots []model.Slot
 	query = `
 		SELECT 
-			* 
+			s.*,
+			st.name AS session_type_name,
+			s.capacity - (SELECT COUNT(*) FROM booking b WHERE b.slot_id = s.id) AS seats_remaining
 		FROM 
-			slot 
+			slot s
+			LEFT JOIN session_type st ON s.session_type_id = st.id
 		WHERE 
-			coach_id = $1 AND 
-			start_time > NOW() 
+			s.coach_id = $1 AND 
+			s.start_time > NOW() 
 		ORDER BY 
-			start_time ASC
+			s.start_time ASC
 		LIMIT $2 OFFSET $3`
-	err = r.dbc.Select(&slots, query, coachID, pagesize, offset)
+	err = r.dbc.Select(ctx, &slots, query, coachID, pagesize, offset)
 	return slots, totalCount, err
 }
 
-func (r *SlotRepository) GetAvailableSlots(coachID uuid.UUID, offset, pagesize int) ([]model.Slot, int, error) {
+// GetAvailableSlots returns the coach's open or partly booked slots with a
+// seat left, starting after earliest and, when latest is set, no later than
+// latest. Slots the viewer is holding in checkout are included even when the
+// hold took the last seat.
+func (r *SlotRepository) GetAvailableSlots(ctx context.Context, coachID, viewerID uuid.UUID, earliest time.Time, latest *time.Time, offset, pagesize int) ([]model.Slot, int, error) {
+	conditions := `
+		s.coach_id = $1 AND
+		s.status IN ('open', 'booked') AND
+		(s.booked = false OR EXISTS (
+			SELECT 1 FROM booking b
+			WHERE b.slot_id = s.id AND b.student_id = $2 AND b.status = 'checkout'
+		)) AND
+		s.start_time > $3 AND
+		($4::timestamptz IS NULL OR s.start_time <= $4)`
 	var totalCount int
-	query := `SELECT COUNT(*) FROM slot WHERE coach_id = $1 AND booked = false AND start_time > NOW()`
-	err := r.dbc.GetSingleEntity(&totalCount, query, coachID)
+	query := `SELECT COUNT(*) FROM slot s WHERE ` + conditions
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, coachID, viewerID, earliest, latest)
 	if err != nil {
 		return nil, 0, err
 	}
 	var slots []model.Slot
 	query = `
 		SELECT 
-			* 
+			s.*,
+			st.name AS session_type_name,
+			s.capacity - (SELECT COUNT(*) FROM booking b WHERE b.slot_id = s.id) AS seats_remaining
 		FROM 
-			slot 
-		WHERE 
-			coach_id = $1 AND
-			booked = false AND 
-			start_time > NOW() 
+			slot s
+			LEFT JOIN session_type st ON s.session_type_id = st.id
+		WHERE ` + conditions + `
 		ORDER BY 
-			start_time ASC
-			LIMIT $2 OFFSET $3
+			s.start_time ASC
+			LIMIT $5 OFFSET $6
 		`
-	err = r.dbc.Select(&slots, query, coachID, pagesize, offset)
+	err = r.dbc.Select(ctx, &slots, query, coachID, viewerID, earliest, latest, pagesize, offset)
+	return slots, totalCount, err
+}
+
+// SearchAvailableSlots finds bookable slots across all coaches that match the
+// criteria.
+func (r *SlotRepository) SearchAvailableSlots(ctx context.Context, criteria model.SlotSearchCriteria, offset, pagesize int) ([]model.Slot, int, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{
+		"s.status IN ('open', 'booked')",
+		"s.booked = false",
+		"s.start_time > NOW()",
+		// Respect each coach's minimum lead time and booking horizon
+		`NOT EXISTS (
+			SELECT 1 FROM coach_profile p
+			WHERE p.coach_id = s.coach_id AND (
+				s.start_time <= NOW() + make_interval(mins => p.min_booking_lead_minutes) OR
+				(p.max_booking_horizon_days > 0 AND s.start_time > NOW() + make_interval(days => p.max_booking_horizon_days))
+			)
+		)`,
+	}
+	if criteria.From != nil {
+		conditions = append(conditions, "s.start_time >= "+arg(*criteria.From))
+	}
+	if criteria.To != nil {
+		conditions = append(conditions, "s.start_time < "+arg(*criteria.To))
+	}
+	if len(criteria.Weekdays) > 0 || criteria.StartMinute != nil || criteria.EndMinute != nil {
+		localStart := "(s.start_time AT TIME ZONE " + arg(criteria.TimeZone) + ")"
+		startMinute := "(EXTRACT(HOUR FROM " + localStart + ") * 60 + EXTRACT(MINUTE FROM " + localStart + "))"
+		if len(criteria.Weekdays) > 0 {
+			weekdays := make(pq.Int64Array, len(criteria.Weekdays))
+			for i, weekday := range criteria.Weekdays {
+				weekdays[i] = int64(weekday)
+			}
+			conditions = append(conditions, "EXTRACT(DOW FROM "+localStart+") = ANY("+arg(weekdays)+"::int[])")
+		}
+		if criteria.StartMinute != nil {
+			conditions = append(conditions, startMinute+" >= "+arg(*criteria.StartMinute))
+		}
+		if criteria.EndMinute != nil {
+			conditions = append(conditions, startMinute+" + EXTRACT(EPOCH FROM (s.end_time - s.start_time)) / 60 <= "+arg(*criteria.EndMinute))
+		}
+	}
+	if criteria.MinDurationMinutes > 0 {
+		conditions = append(conditions, "EXTRACT(EPOCH FROM (s.end_time - s.start_time)) / 60 >= "+arg(criteria.MinDurationMinutes))
+	}
+	if criteria.MaxDurationMinutes > 0 {
+		conditions = append(conditions, "EXTRACT(EPOCH FROM (s.end_time - s.start_time)) / 60 <= "+arg(criteria.MaxDurationMinutes))
+	}
+	if len(criteria.CoachIDs) > 0 {
+		coachIDs := make(pq.StringArray, len(criteria.CoachIDs))
+		for i, id := range criteria.CoachIDs {
+			coachIDs[i] = id.String()
+		}
+		conditions = append(conditions, "s.coach_id = ANY("+arg(coachIDs)+"::uuid[])")
+	}
+	if criteria.CoachName != "" {
+		conditions = append(conditions, "u.name ILIKE '%' || "+arg(criteria.CoachName)+" || '%'")
+	}
+	if criteria.SessionTypeName != "" {
+		conditions = append(conditions, "st.name ILIKE '%' || "+arg(criteria.SessionTypeName)+" || '%'")
+	}
+	where := strings.Join(conditions, " AND ")
+
+	from := `
+		FROM slot s
+		JOIN stepful_user u ON s.coach_id = u.id
+		LEFT JOIN session_type st ON s.session_type_id = st.id
+		WHERE ` + where
+
+	var totalCount int
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, "SELECT COUNT(*)"+from, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	order := "ASC"
+	if criteria.Sort == model.SlotSearchSortLatest {
+		order = "DESC"
+	}
+	query := `
+		SELECT
+			s.*,
+			u.name AS coach_name,
+			st.name AS session_type_name,
+			s.capacity - (SELECT COUNT(*) FROM booking b WHERE b.slot_id = s.id) AS seats_remaining` + from + `
+		ORDER BY s.start_time ` + order + `, s.id
+		LIMIT ` + arg(pagesize) + ` OFFSET ` + arg(offset)
+
+	var slots []model.Slot
+	err = r.dbc.Select(ctx, &slots, query, args...)
 	return slots, totalCount, err
 }
 
-func (r *SlotRepository) GetSlotByID(id uuid.UUID) (*model.Slot, error) {
+func (r *SlotRepository) GetSlotByID(ctx context.Context, id uuid.UUID) (*model.Slot, error) {
 	var slot model.Slot
 	query := `SELECT * FROM slot WHERE id = $1`
-	err := r.dbc.GetSingleEntity(&slot, query, id)
+	err := r.dbc.GetSingleEntity(ctx, &slot, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+// GetSlotByIDForUpdate reads the slot and locks its row until the enclosing
+// transaction ends.
+func (r *SlotRepository) GetSlotByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.Slot, error) {
+	var slot model.Slot
+	query := `SELECT * FROM slot WHERE id = $1 FOR UPDATE`
+	err := r.dbc.GetSingleEntity(ctx, &slot, query, id)
 	if err != nil {
 		return nil, err
 	}
 	return &slot, nil
 }
 
-func (r *SlotRepository) UpdateSlot(slot model.Slot) error {
-	query := `UPDATE slot SET student_id = :student_id, booked = :booked WHERE id = :id`
-	_, err := r.dbc.NamedExec(query, slot)
+func (r *SlotRepository) UpdateSlot(ctx context.Context, slot model.Slot) error {
+	query := `UPDATE slot SET booked = :booked, status = :status, capacity = :capacity WHERE id = :id`
+	_, err := r.dbc.NamedExec(ctx, query, slot)
+	return err
+}
+
+// DeleteFutureOpenSlotsForRule removes the open, not yet started slots
+// materialized from an availability rule. Booked and cancelled slots, and open
+// slots with seats held or awaiting approval, are left alone.
+func (r *SlotRepository) DeleteFutureOpenSlotsForRule(ctx context.Context, ruleID uuid.UUID) (int64, error) {
+	query := `
+		DELETE FROM slot
+		WHERE availability_rule_id = $1 AND status = 'open' AND start_time > NOW()
+		AND NOT EXISTS (SELECT 1 FROM booking b WHERE b.slot_id = slot.id)`
+	result, err := r.dbc.ExecuteCommand(ctx, query, ruleID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// BookSlot gives the student a seat only if the slot is still bookable, has
+// not started and has a seat left. The seat count is not locked by the
+// statement itself, so the caller must hold the slot row lock (see
+// GetSlotByIDForUpdate) in the same transaction; otherwise two callers can
+// both take the last seat. The slot's booked flag and status are then brought
+// up to date.
+func (r *SlotRepository) BookSlot(ctx context.Context, slotID, studentID uuid.UUID) (bool, error) {
+	return r.takeSeat(ctx, slotID, studentID, model.BookingStatusConfirmed, nil)
+}
+
+// HoldSeat is BookSlot for a seat that is only reserved for the student until
+// expiresAt. Held seats count towards the slot's capacity.
+func (r *SlotRepository) HoldSeat(ctx context.Context, slotID, studentID uuid.UUID, expiresAt time.Time) (bool, error) {
+	return r.takeSeat(ctx, slotID, studentID, model.BookingStatusHeld, &expiresAt)
+}
+
+// ReserveSeat is HoldSeat for a student in checkout. Like BookSlot, both
+// require the slot row lock.
+func (r *SlotRepository) ReserveSeat(ctx context.Context, slotID, studentID uuid.UUID, expiresAt time.Time) (bool, error) {
+	return r.takeSeat(ctx, slotID, studentID, model.BookingStatusCheckout, &expiresAt)
+}
+
+func (r *SlotRepository) takeSeat(ctx context.Context, slotID, studentID uuid.UUID, status model.BookingStatus, expiresAt *time.Time) (bool, error) {
+	query := `
+		INSERT INTO booking (id, slot_id, student_id, status, expires_at, created_at)
+		SELECT $1, s.id, $3, $4, $5, NOW()
+		FROM slot s
+		WHERE s.id = $2
+		AND s.status IN ('open', 'booked')
+		AND s.start_time > NOW()
+		AND (SELECT COUNT(*) FROM booking b WHERE b.slot_id = s.id) < s.capacity
+		ON CONFLICT (slot_id, student_id) DO NOTHING`
+	result, err := r.dbc.ExecuteCommand(ctx, query, uuid.New(), slotID, studentID, status, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows != 1 {
+		return false, nil
+	}
+	return true, r.refreshOccupancy(ctx, slotID)
+}
+
+// RescheduleSlot moves an open slot. The slot is detached from its
+// availability rule so that later edits to the rule do not undo the move.
+func (r *SlotRepository) RescheduleSlot(ctx context.Context, slot model.Slot) error {
+	query := `UPDATE slot
+			  SET start_time = :start_time, end_time = :end_time, session_type_id = :session_type_id, capacity = :capacity, availability_rule_id = NULL
+			  WHERE id = :id`
+	_, err := r.dbc.NamedExec(ctx, query, slot)
+	return err
+}
+
+func (r *SlotRepository) DeleteSlot(ctx context.Context, slotID uuid.UUID) error {
+	_, err := r.dbc.ExecuteCommand(ctx, `DELETE FROM slot WHERE id = $1`, slotID)
+	return err
+}
+
+// ReleaseBooking gives up the student's seat so it can be booked again.
+func (r *SlotRepository) ReleaseBooking(ctx context.Context, slotID, studentID uuid.UUID) error {
+	query := `DELETE FROM booking WHERE slot_id = $1 AND student_id = $2`
+	if _, err := r.dbc.ExecuteCommand(ctx, query, slotID, studentID); err != nil {
+		return err
+	}
+	return r.refreshOccupancy(ctx, slotID)
+}
+
+// refreshOccupancy recomputes the booked flag and status of an open or booked
+// slot from its bookings. Every seat counts towards capacity, but only
+// confirmed bookings make the slot booked; held, pending and checkout seats
+// leave it open.
+func (r *SlotRepository) refreshOccupancy(ctx context.Context, slotID uuid.UUID) error {
+	query := `
+		UPDATE slot s
+		SET booked = c.taken >= s.capacity,
+			status = CASE WHEN c.confirmed > 0 THEN 'booked' ELSE 'open' END
+		FROM (
+			SELECT COUNT(*) AS taken, COUNT(*) FILTER (WHERE status = 'confirmed') AS confirmed
+			FROM booking
+			WHERE slot_id = $1
+		) c
+		WHERE s.id = $1 AND s.status IN ('open', 'booked')`
+	_, err := r.dbc.ExecuteCommand(ctx, query, slotID)
 	return err
 }
 
-func (r *SlotRepository) BookSlot(slotID, studentID uuid.UUID) error {
-	query := `UPDATE slot SET student_id = $1, booked = true WHERE id = $2 AND booked = false`
-	_, err := r.dbc.NamedExec(query, map[string]interface{}{
-		"slot_id":    slotID,
-		"student_id": studentID,
-	})
+// CancelSlot takes a slot out of circulation. Its bookings are kept so both
+// sides can still see the cancelled session.
+func (r *SlotRepository) CancelSlot(ctx context.Context, slotID uuid.UUID) error {
+	query := `UPDATE slot SET booked = false, status = 'cancelled' WHERE id = $1`
+	_, err := r.dbc.ExecuteCommand(ctx, query, slotID)
 	return err
 }
 
-func (r *SlotRepository) HasOverlappingSlot(coachID uuid.UUID, startTime, endTime time.Time) (bool, error) {
+// SetSlotStatus moves a slot to status without touching its bookings.
+func (r *SlotRepository) SetSlotStatus(ctx context.Context, slotID uuid.UUID, status model.SlotStatus) error {
+	query := `UPDATE slot SET status = $2 WHERE id = $1`
+	_, err := r.dbc.ExecuteCommand(ctx, query, slotID, status)
+	return err
+}
+
+// CompleteEndedSlots marks every booked slot with a confirmed booking whose
+// end time has passed as completed and returns how many were updated.
+func (r *SlotRepository) CompleteEndedSlots(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE slot SET status = 'completed'
+		WHERE status = 'booked' AND end_time <= NOW()
+		AND EXISTS (SELECT 1 FROM booking b WHERE b.slot_id = slot.id AND b.status = 'confirmed')`
+	result, err := r.dbc.ExecuteCommand(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *SlotRepository) HasOverlappingSlot(ctx context.Context, coachID uuid.UUID, startTime, endTime time.Time) (bool, error) {
+	return r.HasOverlappingSlotExcluding(ctx, coachID, startTime, endTime, uuid.Nil)
+}
+
+// HasOverlappingSlotExcluding is HasOverlappingSlot ignoring one slot, so a
+// slot being moved is not reported as overlapping itself. Cancelled slots
+// never overlap.
+func (r *SlotRepository) HasOverlappingSlotExcluding(ctx context.Context, coachID uuid.UUID, startTime, endTime time.Time, excludeSlotID uuid.UUID) (bool, error) {
 	var count int
 	query := `
 		SELECT COUNT(*) 
 		FROM slot
 		WHERE coach_id = $1 
+		AND id <> $4
+		AND status <> 'cancelled'
 		AND (
 			(start_time <= $2 AND end_time > $2) OR
 			(start_time < $3 AND end_time >= $3) OR
 			(start_time >= $2 AND end_time <= $3)
 		)`
-	err := r.dbc.GetSingleEntity(&count, query, coachID, startTime, endTime)
+	err := r.dbc.GetSingleEntity(ctx, &count, query, coachID, startTime, endTime, excludeSlotID)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func (r *SlotRepository) HasOverlappingBooking(studentID uuid.UUID, startTime, endTime time.Time) (bool, error) {
+func (r *SlotRepository) HasOverlappingBooking(ctx context.Context, studentID uuid.UUID, startTime, endTime time.Time) (bool, error) {
+	return r.HasOverlappingBookingExcluding(ctx, studentID, startTime, endTime, uuid.Nil)
+}
+
+// HasOverlappingBookingExcluding is HasOverlappingBooking ignoring one slot,
+// so a booking being moved is not reported as overlapping itself.
+func (r *SlotRepository) HasOverlappingBookingExcluding(ctx context.Context, studentID uuid.UUID, startTime, endTime time.Time, excludeSlotID uuid.UUID) (bool, error) {
 	var count int
 	query := `
 		SELECT COUNT(*) 
-		FROM slot
-		WHERE student_id = $1 
-		AND booked = true
+		FROM booking b
+		JOIN slot s ON b.slot_id = s.id
+		WHERE b.student_id = $1 
+		AND s.status = 'booked'
+		AND s.id <> $4
 		AND (
-			(start_time <= $2 AND end_time > $2) OR
-			(start_time < $3 AND end_time >= $3) OR
-			(start_time >= $2 AND end_time <= $3)
+			(s.start_time <= $2 AND s.end_time > $2) OR
+			(s.start_time < $3 AND s.end_time >= $3) OR
+			(s.start_time >= $2 AND s.end_time <= $3)
 		)`
-	err := r.dbc.GetSingleEntity(&count, query, studentID, startTime, endTime)
+	err := r.dbc.GetSingleEntity(ctx, &count, query, studentID, startTime, endTime, excludeSlotID)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func (r *SlotRepository) GetUpcomingBookingsForStudent(studentID uuid.UUID, offset, pagesize int) ([]model.Slot, int, error) {
+func (r *SlotRepository) GetUpcomingBookingsForStudent(ctx context.Context, studentID uuid.UUID, offset, pagesize int) ([]model.Slot, int, error) {
 	var totalCount int
-	query := `SELECT COUNT(*) FROM slot WHERE student_id = $1 AND booked = true AND start_time > NOW()`
-	err := r.dbc.GetSingleEntity(&totalCount, query, studentID)
+	query := `
+		SELECT COUNT(*)
+		FROM booking b
+		JOIN slot s ON b.slot_id = s.id
+		WHERE b.student_id = $1 AND b.status = 'confirmed' AND s.status = 'booked' AND s.start_time > NOW()`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, studentID)
 	if err != nil {
 		return nil, 0, err
 	}
 	var slots []model.Slot
 	query = `
-		SELECT s.*, u.name as coach_name
-		FROM slot s
+		SELECT
+			s.*,
+			u.name as coach_name,
+			st.name AS session_type_name,
+			s.capacity - (SELECT COUNT(*) FROM booking bc WHERE bc.slot_id = s.id) AS seats_remaining
+		FROM booking b
+		JOIN slot s ON b.slot_id = s.id
 		JOIN stepful_user u ON s.coach_id = u.id
-		WHERE s.student_id = $1 
+		LEFT JOIN session_type st ON s.session_type_id = st.id
+		WHERE b.student_id = $1 
+		AND b.status = 'confirmed'
 		AND s.start_time > $2
-		AND s.booked = true
+		AND s.status = 'booked'
 		ORDER BY s.start_time ASC
 		LIMIT $3 OFFSET $4
 	`
-	err = r.dbc.Select(&slots, query, studentID, time.Now(), pagesize, offset)
+	err = r.dbc.Select(ctx, &slots, query, studentID, time.Now(), pagesize, offset)
 	return slots, totalCount, err
 }
 
-func (r *SlotRepository) GetSlotDetails(slotID uuid.UUID) (*model.SlotDetails, error) {
+func (r *SlotRepository) GetSlotDetails(ctx context.Context, slotID uuid.UUID) (*model.SlotDetails, error) {
 	var slotDetails model.SlotDetails
 	query := `
         SELECT 
             s.*,
             c.name AS coach_name,
             c.phone_number AS coach_phone_number,
-            st.name AS student_name,
-            st.phone_number AS student_phone_number
+            t.name AS session_type_name,
+            s.capacity - (SELECT COUNT(*) FROM booking b WHERE b.slot_id = s.id) AS seats_remaining
         FROM slot s
         JOIN stepful_user c ON s.coach_id = c.id
-        LEFT JOIN stepful_user st ON s.student_id = st.id
+        LEFT JOIN session_type t ON s.session_type_id = t.id
         WHERE s.id = $1
     `
-	err := r.dbc.GetSingleEntity(&slotDetails, query, slotID)
+	err := r.dbc.GetSingleEntity(ctx, &slotDetails, query, slotID)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/cargoreligion/booking/server/infrastructure/db"
 	"github.com/cargoreligion/booking/server/model"
 	"github.com/google/uuid"
@@ -14,19 +16,27 @@ func NewUserRepository(dbc db.DbClient) *UserRepository {
 	return &UserRepository{dbc: dbc}
 }
 
-func (r *UserRepository) GetUserByID(id uuid.UUID) (*model.User, error) {
+func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
 	var user model.User
 	query := `SELECT id, name, phone_number, user_role FROM stepful_user WHERE id = $1`
-	err := r.dbc.GetSingleEntity(&user, query, id)
+	err := r.dbc.GetSingleEntity(ctx, &user, query, id)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) GetAllUsers() ([]model.User, error) {
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]model.User, error) {
 	var users []model.User
 	query := `SELECT id, name, phone_number, user_role FROM stepful_user`
-	err := r.dbc.Select(&users, query)
+	err := r.dbc.Select(ctx, &users, query)
 	return users, err
 }
+
+// LockUser locks the user's row until the enclosing transaction ends, which
+// serializes concurrent operations on behalf of the same user.
+func (r *UserRepository) LockUser(ctx context.Context, id uuid.UUID) error {
+	var lockedID uuid.UUID
+	query := `SELECT id FROM stepful_user WHERE id = $1 FOR UPDATE`
+	return r.dbc.GetSingleEntity(ctx, &lockedID, query, id)
+}
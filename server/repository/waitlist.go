@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+type WaitlistRepository struct {
+	dbc db.DbClient
+}
+
+func NewWaitlistRepository(dbc db.DbClient) *WaitlistRepository {
+	return &WaitlistRepository{dbc: dbc}
+}
+
+func (r *WaitlistRepository) CreateEntry(ctx context.Context, entry model.WaitlistEntry) error {
+	query := `INSERT INTO waitlist_entry (id, student_id, coach_id, slot_id, week_start, status, created_at)
+			  VALUES (:id, :student_id, :coach_id, :slot_id, :week_start, :status, :created_at)`
+	_, err := r.dbc.NamedExec(ctx, query, entry)
+	return err
+}
+
+func (r *WaitlistRepository) GetEntryByID(ctx context.Context, id uuid.UUID) (*model.WaitlistEntry, error) {
+	var entry model.WaitlistEntry
+	query := `SELECT * FROM waitlist_entry WHERE id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &entry, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *WaitlistRepository) GetEntriesForStudent(ctx context.Context, studentID uuid.UUID) ([]model.WaitlistEntry, error) {
+	var entries []model.WaitlistEntry
+	query := `
+		SELECT *
+		FROM waitlist_entry
+		WHERE student_id = $1 AND status <> 'left'
+		ORDER BY created_at DESC`
+	err := r.dbc.Select(ctx, &entries, query, studentID)
+	return entries, err
+}
+
+// HasWaitingEntry reports whether the student is already waiting for the
+// slot, or for the coach's week when slotID is nil.
+func (r *WaitlistRepository) HasWaitingEntry(ctx context.Context, studentID, coachID uuid.UUID, slotID *uuid.UUID, weekStart *time.Time) (bool, error) {
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM waitlist_entry
+		WHERE student_id = $1
+		AND coach_id = $2
+		AND status = 'waiting'
+		AND slot_id IS NOT DISTINCT FROM $3
+		AND week_start IS NOT DISTINCT FROM $4`
+	err := r.dbc.GetSingleEntity(ctx, &count, query, studentID, coachID, slotID, weekStart)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetWaitingEntriesForSlot returns the students waiting for the slot itself or
+// for any slot with its coach in its week, first come first served.
+func (r *WaitlistRepository) GetWaitingEntriesForSlot(ctx context.Context, slotID, coachID uuid.UUID, weekStart time.Time) ([]model.WaitlistEntry, error) {
+	var entries []model.WaitlistEntry
+	query := `
+		SELECT *
+		FROM waitlist_entry
+		WHERE status = 'waiting'
+		AND (slot_id = $1 OR (slot_id IS NULL AND coach_id = $2 AND week_start = $3))
+		ORDER BY created_at ASC
+		FOR UPDATE`
+	err := r.dbc.Select(ctx, &entries, query, slotID, coachID, weekStart)
+	return entries, err
+}
+
+func (r *WaitlistRepository) UpdateEntryStatus(ctx context.Context, id uuid.UUID, status model.WaitlistStatus) error {
+	_, err := r.dbc.ExecuteCommand(ctx, `UPDATE waitlist_entry SET status = $2 WHERE id = $1`, id, status)
+	return err
+}
+
+func (r *WaitlistRepository) CreatePromotion(ctx context.Context, promotion model.WaitlistPromotion) error {
+	query := `INSERT INTO waitlist_promotion (id, entry_id, slot_id, student_id, status, expires_at, created_at)
+			  VALUES (:id, :entry_id, :slot_id, :student_id, :status, :expires_at, :created_at)`
+	_, err := r.dbc.NamedExec(ctx, query, promotion)
+	return err
+}
+
+func (r *WaitlistRepository) GetPromotionByID(ctx context.Context, id uuid.UUID) (*model.WaitlistPromotion, error) {
+	var promotion model.WaitlistPromotion
+	query := `SELECT * FROM waitlist_promotion WHERE id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &promotion, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// GetPromotionByIDForUpdate reads the promotion and locks its row until the
+// enclosing transaction ends.
+func (r *WaitlistRepository) GetPromotionByIDForUpdate(ctx context.Context, id uuid.UUID) (*model.WaitlistPromotion, error) {
+	var promotion model.WaitlistPromotion
+	query := `SELECT * FROM waitlist_promotion WHERE id = $1 FOR UPDATE`
+	err := r.dbc.GetSingleEntity(ctx, &promotion, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func (r *WaitlistRepository) GetPromotionsForStudent(ctx context.Context, studentID uuid.UUID, offset, pagesize int) ([]model.WaitlistPromotion, int, error) {
+	var totalCount int
+	query := `SELECT COUNT(*) FROM waitlist_promotion WHERE student_id = $1`
+	err := r.dbc.GetSingleEntity(ctx, &totalCount, query, studentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var promotions []model.WaitlistPromotion
+	query = `
+		SELECT *
+		FROM waitlist_promotion
+		WHERE student_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+	err = r.dbc.Select(ctx, &promotions, query, studentID, pagesize, offset)
+	return promotions, totalCount, err
+}
+
+// GetExpiredPromotions returns offers whose hold window has passed without an
+// answer.
+func (r *WaitlistRepository) GetExpiredPromotions(ctx context.Context) ([]model.WaitlistPromotion, error) {
+	var promotions []model.WaitlistPromotion
+	query := `
+		SELECT *
+		FROM waitlist_promotion
+		WHERE status = 'offered' AND expires_at <= NOW()
+		ORDER BY expires_at ASC`
+	err := r.dbc.Select(ctx, &promotions, query)
+	return promotions, err
+}
+
+func (r *WaitlistRepository) ResolvePromotion(ctx context.Context, id uuid.UUID, status model.PromotionStatus, resolvedAt time.Time) error {
+	query := `UPDATE waitlist_promotion SET status = $2, resolved_at = $3 WHERE id = $1`
+	_, err := r.dbc.ExecuteCommand(ctx, query, id, status, resolvedAt)
+	return err
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+type AttendanceService struct {
+	dbc         db.DbClient
+	bookingRepo *repository.BookingRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewAttendanceService(dbc db.DbClient, bookingRepo *repository.BookingRepository, userRepo *repository.UserRepository) *AttendanceService {
+	return &AttendanceService{
+		dbc:         dbc,
+		bookingRepo: bookingRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// MarkAttendance records whether a student's session took place. Only the
+// slot's coach can mark it, and only once the session has ended. studentID
+// may be omitted for a slot with a single attendee.
+func (s *AttendanceService) MarkAttendance(ctx context.Context, coachID, slotID uuid.UUID, studentID *uuid.UUID, attendance model.AttendanceStatus) (*model.Booking, error) {
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return nil, &ErrNotAuthorized{UserID: coachID.String(), Action: "mark attendance"}
+	}
+	if !attendance.IsValid() {
+		return nil, &ErrInvalidAttendance{Attendance: string(attendance)}
+	}
+
+	var booking *model.Booking
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+		slot, err := slotRepo.GetSlotByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: slotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+		if slot.CoachID != coachID {
+			return &ErrSlotNotAssignedToCoach{SlotID: slotID.String(), CoachID: coachID.String()}
+		}
+		if slot.EndTime.After(time.Now()) {
+			return &ErrSessionNotEnded{SlotID: slotID.String()}
+		}
+		// A cancelled slot keeps its bookings, but there was no session
+		if slot.Status == model.SlotStatusCancelled || slot.Status == model.SlotStatusOpen {
+			return &ErrSlotNotBooked{SlotID: slotID.String()}
+		}
+
+		bookingRepo := repository.NewBookingRepository(tx)
+		attendee, err := findAttendee(ctx, bookingRepo, slot, studentID)
+		if err != nil {
+			return err
+		}
+		// Requests and holds that never became bookings were not sessions
+		if attendee.Status != model.BookingStatusConfirmed {
+			return &ErrBookingNotFound{SlotID: slotID.String(), StudentID: attendee.StudentID.String()}
+		}
+
+		if err := bookingRepo.MarkAttendance(ctx, slotID, attendee.StudentID, attendance, coachID, time.Now()); err != nil {
+			return fmt.Errorf("error marking attendance: %w", err)
+		}
+		if err := syncSlotAttendance(ctx, slotRepo, bookingRepo, slot); err != nil {
+			return err
+		}
+		booking, err = bookingRepo.GetBooking(ctx, slotID, attendee.StudentID)
+		if err != nil {
+			return fmt.Errorf("error fetching booking: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// GetNoShowSummary counts a student's no-shows. Students can see their own,
+// coaches those of students who have booked with them, and program leads any
+// student's.
+func (s *AttendanceService) GetNoShowSummary(ctx context.Context, userID, studentID uuid.UUID) (*model.NoShowSummary, error) {
+	if userID != studentID {
+		user, err := s.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching user: %w", err)
+		}
+		switch user.Role {
+		case model.RoleProgramLead:
+		case model.RoleCoach:
+			booked, err := s.bookingRepo.HasBookedWithCoach(ctx, studentID, userID)
+			if err != nil {
+				return nil, fmt.Errorf("error checking student's bookings: %w", err)
+			}
+			if !booked {
+				return nil, &ErrNotAuthorized{UserID: userID.String(), Action: "view no-shows of a student they have not coached"}
+			}
+		default:
+			return nil, &ErrNotAuthorized{UserID: userID.String(), Action: "view another student's no-shows"}
+		}
+	}
+	student, err := s.userRepo.GetUserByID(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if student.Role != model.RoleStudent {
+		return nil, &ErrNotStudent{UserID: studentID.String()}
+	}
+
+	summary, err := s.bookingRepo.GetNoShowSummary(ctx, studentID, time.Now().Add(-model.NoShowWindow))
+	if err != nil {
+		return nil, fmt.Errorf("error counting no-shows: %w", err)
+	}
+	return summary, nil
+}
+
+// syncSlotAttendance sets an ended slot's status from its attendance: it is
+// a no-show once every booked student's session is marked as missed, and
+// completed otherwise.
+func syncSlotAttendance(ctx context.Context, slotRepo *repository.SlotRepository, bookingRepo *repository.BookingRepository, slot *model.Slot) error {
+	attendees, err := bookingRepo.GetBookingsForSlots(ctx, []uuid.UUID{slot.ID})
+	if err != nil {
+		return fmt.Errorf("error fetching attendees: %w", err)
+	}
+	status := model.SlotStatusNoShow
+	for _, attendee := range attendees {
+		if attendee.Status != model.BookingStatusConfirmed {
+			continue
+		}
+		if attendee.Attendance == nil || !attendee.Attendance.IsNoShow() {
+			status = model.SlotStatusCompleted
+			break
+		}
+	}
+	if status == slot.Status {
+		return nil
+	}
+	if err := checkSlotTransition(slot, status); err != nil {
+		return err
+	}
+	if err := slotRepo.SetSlotStatus(ctx, slot.ID, status); err != nil {
+		return fmt.Errorf("error updating slot status: %w", err)
+	}
+	slot.Status = status
+	return nil
+}
+
+// findAttendee returns the booking a coach's action on the slot is about.
+// studentID may be nil when the slot has a single attendee.
+func findAttendee(ctx context.Context, bookingRepo *repository.BookingRepository, slot *model.Slot, studentID *uuid.UUID) (*model.Booking, error) {
+	attendees, err := bookingRepo.GetBookingsForSlots(ctx, []uuid.UUID{slot.ID})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attendees: %w", err)
+	}
+	switch {
+	case len(attendees) == 0:
+		return nil, &ErrSlotNotBooked{SlotID: slot.ID.String()}
+	case studentID == nil && len(attendees) > 1:
+		return nil, &ErrStudentRequired{SlotID: slot.ID.String()}
+	case studentID == nil:
+		return &attendees[0], nil
+	}
+	for i := range attendees {
+		if attendees[i].StudentID == *studentID {
+			return &attendees[i], nil
+		}
+	}
+	return nil, &ErrBookingNotFound{SlotID: slot.ID.String(), StudentID: studentID.String()}
+}
+
+// checkNoShowPolicy refuses a booking with the slot's coach when the student
+// has missed more sessions recently than the coach allows.
+func checkNoShowPolicy(ctx context.Context, tx db.DbClient, slot *model.Slot, studentID uuid.UUID) error {
+	profile, err := getCoachProfileOrDefault(ctx, repository.NewCoachProfileRepository(tx), slot.CoachID)
+	if err != nil {
+		return err
+	}
+	if profile.MaxStudentNoShows == nil {
+		return nil
+	}
+	summary, err := repository.NewBookingRepository(tx).GetNoShowSummary(ctx, studentID, time.Now().Add(-model.NoShowWindow))
+	if err != nil {
+		return fmt.Errorf("error counting no-shows: %w", err)
+	}
+	if summary.RecentNoShows > *profile.MaxStudentNoShows {
+		return &ErrTooManyNoShows{StudentID: studentID.String(), NoShows: summary.RecentNoShows, Limit: *profile.MaxStudentNoShows}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+// Open-ended rules are only materialized this far ahead.
+const availabilityRuleHorizon = 12 * 7 * 24 * time.Hour
+
+// AvailabilityRuleInput describes a rule either as an RRULE or as a set of
+// weekdays repeated for a number of weeks. Exactly one form must be used.
+type AvailabilityRuleInput struct {
+	SessionTypeID *uuid.UUID
+	RRule         string
+	Weekdays      []time.Weekday
+	Weeks         int
+	StartDate     time.Time
+	StartMinute   int
+	EndMinute     int
+}
+
+type AvailabilityRuleService struct {
+	dbc              db.DbClient
+	ruleRepo         *repository.AvailabilityRuleRepository
+	userRepo         *repository.UserRepository
+	sessionTypeRepo  *repository.SessionTypeRepository
+	coachProfileRepo *repository.CoachProfileRepository
+}
+
+func NewAvailabilityRuleService(
+	dbc db.DbClient,
+	ruleRepo *repository.AvailabilityRuleRepository,
+	userRepo *repository.UserRepository,
+	sessionTypeRepo *repository.SessionTypeRepository,
+	coachProfileRepo *repository.CoachProfileRepository,
+) *AvailabilityRuleService {
+	return &AvailabilityRuleService{
+		dbc:              dbc,
+		ruleRepo:         ruleRepo,
+		userRepo:         userRepo,
+		sessionTypeRepo:  sessionTypeRepo,
+		coachProfileRepo: coachProfileRepo,
+	}
+}
+
+func (s *AvailabilityRuleService) CreateAvailabilityRule(ctx context.Context, coachID uuid.UUID, input AvailabilityRuleInput) (*model.AvailabilityRuleResult, error) {
+	if err := s.checkCoach(ctx, coachID, "create availability rules"); err != nil {
+		return nil, err
+	}
+
+	rrule, err := s.validateInput(ctx, coachID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule := model.AvailabilityRule{
+		ID:            uuid.New(),
+		CoachID:       coachID,
+		SessionTypeID: input.SessionTypeID,
+		RRule:         rrule,
+		StartDate:     input.StartDate,
+		StartMinute:   input.StartMinute,
+		EndMinute:     input.EndMinute,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	result := &model.AvailabilityRuleResult{Rule: rule}
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		if err := repository.NewUserRepository(tx).LockUser(ctx, coachID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+		if err := repository.NewAvailabilityRuleRepository(tx).CreateAvailabilityRule(ctx, rule); err != nil {
+			return fmt.Errorf("error creating availability rule: %w", err)
+		}
+
+		result.SlotsCreated, result.SlotsSkipped, err = s.materialize(ctx, repository.NewSlotRepository(tx), rule)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *AvailabilityRuleService) GetAvailabilityRules(ctx context.Context, coachID uuid.UUID) ([]model.AvailabilityRule, error) {
+	if err := s.checkCoach(ctx, coachID, "view availability rules"); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.ruleRepo.GetAvailabilityRulesByCoach(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching availability rules: %w", err)
+	}
+	if rules == nil {
+		rules = []model.AvailabilityRule{} // Return an empty slice instead of nil
+	}
+	return rules, nil
+}
+
+// UpdateAvailabilityRule replaces the rule and regenerates its future open
+// slots. Slots that are already booked stay where they are.
+func (s *AvailabilityRuleService) UpdateAvailabilityRule(ctx context.Context, coachID, ruleID uuid.UUID, input AvailabilityRuleInput) (*model.AvailabilityRuleResult, error) {
+	rule, err := s.getOwnedRule(ctx, coachID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	rrule, err := s.validateInput(ctx, coachID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.SessionTypeID = input.SessionTypeID
+	rule.RRule = rrule
+	rule.StartDate = input.StartDate
+	rule.StartMinute = input.StartMinute
+	rule.EndMinute = input.EndMinute
+	rule.UpdatedAt = time.Now()
+
+	result := &model.AvailabilityRuleResult{Rule: *rule}
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+
+		if err := repository.NewUserRepository(tx).LockUser(ctx, coachID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		removed, err := slotRepo.DeleteFutureOpenSlotsForRule(ctx, rule.ID)
+		if err != nil {
+			return fmt.Errorf("error removing slots for availability rule: %w", err)
+		}
+		result.SlotsRemoved = int(removed)
+
+		if err := repository.NewAvailabilityRuleRepository(tx).UpdateAvailabilityRule(ctx, *rule); err != nil {
+			return fmt.Errorf("error updating availability rule: %w", err)
+		}
+
+		result.SlotsCreated, result.SlotsSkipped, err = s.materialize(ctx, slotRepo, *rule)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteAvailabilityRule removes the rule and its future open slots and
+// returns how many slots were removed. Booked and past slots are kept.
+func (s *AvailabilityRuleService) DeleteAvailabilityRule(ctx context.Context, coachID, ruleID uuid.UUID) (int, error) {
+	rule, err := s.getOwnedRule(ctx, coachID, ruleID)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		removed, err = repository.NewSlotRepository(tx).DeleteFutureOpenSlotsForRule(ctx, rule.ID)
+		if err != nil {
+			return fmt.Errorf("error removing slots for availability rule: %w", err)
+		}
+		if err := repository.NewAvailabilityRuleRepository(tx).DeleteAvailabilityRule(ctx, rule.ID); err != nil {
+			return fmt.Errorf("error deleting availability rule: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(removed), nil
+}
+
+func (s *AvailabilityRuleService) checkCoach(ctx context.Context, userID uuid.UUID, action string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return &ErrNotAuthorized{UserID: userID.String(), Action: action}
+	}
+	return nil
+}
+
+func (s *AvailabilityRuleService) getOwnedRule(ctx context.Context, coachID, ruleID uuid.UUID) (*model.AvailabilityRule, error) {
+	rule, err := s.ruleRepo.GetAvailabilityRuleByID(ctx, ruleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &ErrAvailabilityRuleNotFound{RuleID: ruleID.String()}
+		}
+		return nil, fmt.Errorf("error fetching availability rule: %w", err)
+	}
+	if rule.CoachID != coachID {
+		return nil, &ErrAvailabilityRuleNotFound{RuleID: ruleID.String()}
+	}
+	return rule, nil
+}
+
+// validateInput checks the rule's window and session type and returns the
+// RRULE to store.
+func (s *AvailabilityRuleService) validateInput(ctx context.Context, coachID uuid.UUID, input AvailabilityRuleInput) (string, error) {
+	if input.StartDate.IsZero() {
+		return "", &ErrInvalidAvailabilityRule{Reason: "start date is required"}
+	}
+	if input.StartMinute < 0 || input.EndMinute > 24*60 || input.StartMinute >= input.EndMinute {
+		return "", &ErrInvalidAvailabilityRule{Reason: "window must start before it ends and stay within the day"}
+	}
+	if input.StartMinute%15 != 0 || input.EndMinute%15 != 0 {
+		return "", &ErrInvalidAvailabilityRule{Reason: "window must start and end on 15-minute increments"}
+	}
+	if input.SessionTypeID != nil {
+		if _, err := getCoachSessionType(ctx, s.sessionTypeRepo, coachID, *input.SessionTypeID); err != nil {
+			return "", err
+		}
+	}
+
+	rrule := input.RRule
+	switch {
+	case rrule != "" && len(input.Weekdays) > 0:
+		return "", &ErrInvalidAvailabilityRule{Reason: "specify either an rrule or weekdays, not both"}
+	case rrule == "":
+		var err error
+		rrule, err = weeklyRRule(input.Weekdays, input.StartDate, input.Weeks)
+		if err != nil {
+			return "", &ErrInvalidAvailabilityRule{Reason: err.Error()}
+		}
+	}
+	if _, err := parseRRule(rrule); err != nil {
+		return "", &ErrInvalidAvailabilityRule{Reason: err.Error()}
+	}
+	return rrule, nil
+}
+
+// materialize expands the rule into slots, applying the same validations and
+// overlap checks as CreateSlot. Occurrences that fail them are skipped.
+func (s *AvailabilityRuleService) materialize(ctx context.Context, slotRepo *repository.SlotRepository, rule model.AvailabilityRule) (created, skipped int, err error) {
+	profile, err := getCoachProfileOrDefault(ctx, s.coachProfileRepo, rule.CoachID)
+	if err != nil {
+		return 0, 0, err
+	}
+	loc, err := loadCoachLocation(profile.TimeZone)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	duration := defaultSlotDuration
+	if rule.SessionTypeID != nil {
+		sessionType, err := getCoachSessionType(ctx, s.sessionTypeRepo, rule.CoachID, *rule.SessionTypeID)
+		if err != nil {
+			return 0, 0, err
+		}
+		duration = sessionType.Duration()
+	}
+	durationMinutes := int(duration / time.Minute)
+
+	recur, err := parseRRule(rule.RRule)
+	if err != nil {
+		return 0, 0, &ErrInvalidAvailabilityRule{Reason: err.Error()}
+	}
+
+	// DATE columns come back as UTC midnight; reinterpret in the coach's zone
+	year, month, day := rule.StartDate.Date()
+	dtstart := time.Date(year, month, day, 0, 0, 0, 0, loc)
+
+	for _, date := range recur.occurrences(dtstart, loc, time.Now().Add(availabilityRuleHorizon)) {
+		y, m, d := date.Date()
+		windowEnd := time.Date(y, m, d, 0, rule.EndMinute, 0, 0, loc)
+		for minute := rule.StartMinute; minute+durationMinutes <= rule.EndMinute; minute += durationMinutes {
+			startTime := time.Date(y, m, d, 0, minute, 0, 0, loc)
+			endTime := startTime.Add(duration)
+			if endTime.After(windowEnd) {
+				break
+			}
+
+			if validateSlotTimes(profile, loc, startTime, endTime) != nil {
+				skipped++
+				continue
+			}
+
+			hasOverlap, err := slotRepo.HasOverlappingSlot(ctx, rule.CoachID, startTime, endTime)
+			if err != nil {
+				return created, skipped, fmt.Errorf("error checking for overlapping slots: %w", err)
+			}
+			if hasOverlap {
+				skipped++
+				continue
+			}
+
+			ruleID := rule.ID
+			slot := model.Slot{
+				ID:                 uuid.New(),
+				CoachID:            rule.CoachID,
+				SessionTypeID:      rule.SessionTypeID,
+				AvailabilityRuleID: &ruleID,
+				StartTime:          startTime.UTC(),
+				EndTime:            endTime.UTC(),
+				Booked:             false,
+				Status:             model.SlotStatusOpen,
+				Capacity:           1,
+			}
+			if _, err := slotRepo.CreateSlot(ctx, slot); err != nil {
+				return created, skipped, fmt.Errorf("error creating slot: %w", err)
+			}
+			created++
+		}
+	}
+
+	return created, skipped, nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+// BookingRequestService handles bookings that wait for the coach's approval.
+// Students create requests through SlotService.BookSlot when the coach has
+// approval mode turned on.
+type BookingRequestService struct {
+	dbc             db.DbClient
+	bookingRepo     *repository.BookingRepository
+	userRepo        *repository.UserRepository
+	paymentRepo     *repository.PaymentRepository
+	paymentProvider PaymentProvider
+}
+
+func NewBookingRequestService(
+	dbc db.DbClient,
+	bookingRepo *repository.BookingRepository,
+	userRepo *repository.UserRepository,
+	paymentRepo *repository.PaymentRepository,
+	paymentProvider PaymentProvider,
+) *BookingRequestService {
+	return &BookingRequestService{
+		dbc:             dbc,
+		bookingRepo:     bookingRepo,
+		userRepo:        userRepo,
+		paymentRepo:     paymentRepo,
+		paymentProvider: paymentProvider,
+	}
+}
+
+// GetBookingRequests returns open requests: those waiting on the coach, or
+// those the student is waiting on.
+func (s *BookingRequestService) GetBookingRequests(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]model.BookingRequest, int, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	var requests []model.BookingRequest
+	var totalCount int
+	switch user.Role {
+	case model.RoleCoach:
+		requests, totalCount, err = s.bookingRepo.GetPendingRequestsForCoach(ctx, userID, offset, pageSize)
+	case model.RoleStudent:
+		requests, totalCount, err = s.bookingRepo.GetPendingRequestsForStudent(ctx, userID, offset, pageSize)
+	default:
+		return nil, 0, &ErrNotAuthorized{UserID: userID.String(), Action: "view booking requests"}
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching booking requests: %w", err)
+	}
+	if requests == nil {
+		requests = []model.BookingRequest{} // Return an empty slice instead of nil
+	}
+	return requests, totalCount, nil
+}
+
+// ApproveBookingRequest confirms the student's seat. The student already paid
+// when they made the request.
+func (s *BookingRequestService) ApproveBookingRequest(ctx context.Context, coachID, bookingID uuid.UUID) error {
+	return s.answerRequest(ctx, coachID, bookingID, func(tx db.DbClient, slot *model.Slot, booking *model.Booking) error {
+		if err := repository.NewBookingRepository(tx).ConfirmBooking(ctx, slot.ID, booking.StudentID); err != nil {
+			return fmt.Errorf("error confirming booking: %w", err)
+		}
+		message := fmt.Sprintf("Your booking request for the session on %s was approved.",
+			slot.StartTime.UTC().Format("Mon Jan 2 2006 15:04 MST"))
+		return notify(ctx, repository.NewNotificationRepository(tx), booking.StudentID, message)
+	})
+}
+
+// DeclineBookingRequest turns the student down, refunds them and frees the
+// seat.
+func (s *BookingRequestService) DeclineBookingRequest(ctx context.Context, coachID, bookingID uuid.UUID, reason string) error {
+	reason = strings.TrimSpace(reason)
+	payments := newBookingPayments(s.paymentProvider)
+	err := s.answerRequest(ctx, coachID, bookingID, func(tx db.DbClient, slot *model.Slot, booking *model.Booking) error {
+		message := fmt.Sprintf("Your booking request for the session on %s was declined.",
+			slot.StartTime.UTC().Format("Mon Jan 2 2006 15:04 MST"))
+		if reason != "" {
+			message = fmt.Sprintf("%s Reason: %s", message, reason)
+		}
+		return closeBookingRequest(ctx, tx, payments, slot, booking, model.BookingEventRequestDeclined, coachID, reason, message)
+	})
+	payments.settle(ctx, s.paymentRepo, err)
+	return err
+}
+
+// answerRequest locks the slot and runs fn on the coach's open request in a
+// transaction. Requests for other coaches' slots are reported as not found.
+func (s *BookingRequestService) answerRequest(ctx context.Context, coachID, bookingID uuid.UUID, fn func(tx db.DbClient, slot *model.Slot, booking *model.Booking) error) error {
+	booking, err := s.bookingRepo.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ErrBookingRequestNotFound{BookingID: bookingID.String()}
+		}
+		return fmt.Errorf("error fetching booking: %w", err)
+	}
+
+	return s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, booking.SlotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrBookingRequestNotFound{BookingID: bookingID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+		if slot.CoachID != coachID {
+			return &ErrBookingRequestNotFound{BookingID: bookingID.String()}
+		}
+
+		// Bookings only change under the slot's lock, so this read is current
+		booking, err := repository.NewBookingRepository(tx).GetBookingByID(ctx, bookingID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrBookingRequestClosed{BookingID: bookingID.String(), Reason: "it was already declined or has expired"}
+			}
+			return fmt.Errorf("error fetching booking: %w", err)
+		}
+		if booking.Status != model.BookingStatusPending {
+			return &ErrBookingRequestClosed{BookingID: bookingID.String(), Reason: "it was already approved"}
+		}
+		if booking.ExpiresAt != nil && !booking.ExpiresAt.After(time.Now()) {
+			return &ErrBookingRequestClosed{BookingID: bookingID.String(), Reason: "it has expired"}
+		}
+		return fn(tx, slot, booking)
+	})
+}
+
+// ExpireBookingRequests releases the seats of requests the coach did not
+// answer in time and returns how many expired. It is run periodically by the
+// scheduler.
+func (s *BookingRequestService) ExpireBookingRequests(ctx context.Context) (int, error) {
+	bookings, err := s.bookingRepo.GetExpiredRequests(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching expired booking requests: %w", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, booking := range bookings {
+		resolved := false
+		payments := newBookingPayments(s.paymentProvider)
+		err := s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+			slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, booking.SlotID)
+			if err != nil {
+				// The slot and its bookings are gone
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil
+				}
+				return fmt.Errorf("error fetching slot: %w", err)
+			}
+			locked, err := repository.NewBookingRepository(tx).GetBookingByID(ctx, booking.ID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil
+				}
+				return fmt.Errorf("error fetching booking: %w", err)
+			}
+			// Answered while we were waiting for the lock
+			if locked.Status != model.BookingStatusPending {
+				return nil
+			}
+
+			message := fmt.Sprintf("Your booking request for the session on %s expired before the coach answered it.",
+				slot.StartTime.UTC().Format("Mon Jan 2 2006 15:04 MST"))
+			if err := closeBookingRequest(ctx, tx, payments, slot, locked, model.BookingEventRequestExpired, slot.CoachID, "", message); err != nil {
+				return err
+			}
+			resolved = true
+			return nil
+		})
+		payments.settle(ctx, s.paymentRepo, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("booking request %s: %w", booking.ID, err))
+			continue
+		}
+		if resolved {
+			expired++
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+// requestApprovalIfRequired turns the student's new seat in the slot into a
+// pending request when the coach approves bookings, and tells the coach. It
+// reports whether it did. Requests never outlive the start of the session.
+func requestApprovalIfRequired(ctx context.Context, tx db.DbClient, slot *model.Slot, studentID uuid.UUID) (bool, error) {
+	profile, err := getCoachProfileOrDefault(ctx, repository.NewCoachProfileRepository(tx), slot.CoachID)
+	if err != nil {
+		return false, err
+	}
+	if !profile.RequiresBookingApproval {
+		return false, nil
+	}
+
+	expiresAt := time.Now().Add(profile.BookingRequestTTL())
+	if expiresAt.After(slot.StartTime) {
+		expiresAt = slot.StartTime
+	}
+	if err := repository.NewBookingRepository(tx).MarkPending(ctx, slot.ID, studentID, expiresAt); err != nil {
+		return false, fmt.Errorf("error creating booking request: %w", err)
+	}
+
+	message := fmt.Sprintf("You have a new booking request for the session on %s.",
+		slot.StartTime.UTC().Format("Mon Jan 2 2006 15:04 MST"))
+	if err := notify(ctx, repository.NewNotificationRepository(tx), slot.CoachID, message); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// closeBookingRequest ends a request without a booking: the seat is released
+// and offered to the waitlist, the student is refunded and told why, and the
+// outcome is kept in the booking history. The caller must hold the slot's
+// row lock.
+func closeBookingRequest(ctx context.Context, tx db.DbClient, payments *bookingPayments, slot *model.Slot, booking *model.Booking, event model.BookingEvent, actorID uuid.UUID, reason, message string) error {
+	if err := repository.NewSlotRepository(tx).ReleaseBooking(ctx, slot.ID, booking.StudentID); err != nil {
+		return fmt.Errorf("error releasing booking: %w", err)
+	}
+	if _, err := payments.refundSeat(ctx, tx, slot.ID, booking.StudentID, actorID, "Booking request not accepted"); err != nil {
+		return err
+	}
+
+	entry := model.BookingHistory{
+		ID:        uuid.New(),
+		SlotID:    slot.ID,
+		CoachID:   slot.CoachID,
+		StudentID: booking.StudentID,
+		Event:     event,
+		ActorID:   actorID,
+		Reason:    reason,
+		StartTime: slot.StartTime,
+		EndTime:   slot.EndTime,
+		CreatedAt: time.Now(),
+	}
+	if err := repository.NewBookingHistoryRepository(tx).CreateBookingHistory(ctx, entry); err != nil {
+		return fmt.Errorf("error recording booking request outcome: %w", err)
+	}
+	if err := notify(ctx, repository.NewNotificationRepository(tx), booking.StudentID, message); err != nil {
+		return err
+	}
+
+	return promoteFromWaitlist(ctx, tx, slot)
+}
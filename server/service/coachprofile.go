@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxCoachBioLength    = 2000
+	maxCoachProfileTags  = 20
+	maxCoachTagLength    = 50
+	maxDirectoryLookDays = 90
+	// Booking requests can wait for the coach between 15 minutes and a week.
+	minBookingRequestTTLMinutes = 15
+	maxBookingRequestTTLMinutes = 7 * 24 * 60
+	// Limits on the booking window coaches can set.
+	maxBookingLeadMinutes = 30 * 24 * 60
+	maxBookingHorizonDays = 365
+)
+
+type CoachProfileService struct {
+	dbc              db.DbClient
+	coachProfileRepo *repository.CoachProfileRepository
+	userRepo         *repository.UserRepository
+}
+
+func NewCoachProfileService(
+	dbc db.DbClient,
+	coachProfileRepo *repository.CoachProfileRepository,
+	userRepo *repository.UserRepository,
+) *CoachProfileService {
+	return &CoachProfileService{
+		dbc:              dbc,
+		coachProfileRepo: coachProfileRepo,
+		userRepo:         userRepo,
+	}
+}
+
+func (s *CoachProfileService) GetCoachProfile(ctx context.Context, coachID uuid.UUID) (*model.CoachProfile, error) {
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return nil, &ErrNotCoach{UserID: coachID.String()}
+	}
+
+	return getCoachProfileOrDefault(ctx, s.coachProfileRepo, coachID)
+}
+
+// UpdateCoachProfile replaces the coach's settings with profile. The profile's
+// CoachID is ignored; coaches can only update their own profile.
+func (s *CoachProfileService) UpdateCoachProfile(ctx context.Context, coachID uuid.UUID, profile model.CoachProfile) (*model.CoachProfile, error) {
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return nil, &ErrNotAuthorized{UserID: coachID.String(), Action: "update a coach profile"}
+	}
+
+	// Profiles saved before approval mode existed leave the expiry unset
+	if profile.BookingRequestTTLMinutes == 0 {
+		profile.BookingRequestTTLMinutes = model.DefaultBookingRequestTTLMinutes
+	}
+	if err := validateCoachProfile(profile); err != nil {
+		return nil, err
+	}
+
+	profile.CoachID = coachID
+	profile.Specialties = normalizeProfileTags(profile.Specialties, true)
+	profile.Languages = normalizeProfileTags(profile.Languages, false)
+	workingHours := profile.WorkingHours
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		coachProfileRepo := repository.NewCoachProfileRepository(tx)
+		if err := coachProfileRepo.UpsertCoachProfile(ctx, profile); err != nil {
+			return fmt.Errorf("error saving coach profile: %w", err)
+		}
+		if err := coachProfileRepo.ReplaceWorkingHours(ctx, coachID, workingHours); err != nil {
+			return fmt.Errorf("error saving working hours: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return getCoachProfileOrDefault(ctx, s.coachProfileRepo, coachID)
+}
+
+// GetCoachDirectory lists coaches, never students, for students choosing whom
+// to book with. Specialty matching ignores case.
+func (s *CoachProfileService) GetCoachDirectory(ctx context.Context, filter model.CoachDirectoryFilter, page, pageSize int) ([]model.CoachDirectoryEntry, int, error) {
+	if filter.AvailableWithinDays < 0 || filter.AvailableWithinDays > maxDirectoryLookDays {
+		return nil, 0, &ErrInvalidCoachDirectoryFilter{Reason: fmt.Sprintf("availability window must be between 0 and %d days", maxDirectoryLookDays)}
+	}
+	var availableBefore *time.Time
+	if filter.AvailableWithinDays > 0 {
+		before := time.Now().AddDate(0, 0, filter.AvailableWithinDays)
+		availableBefore = &before
+	}
+
+	offset := (page - 1) * pageSize
+	coaches, totalCount, err := s.coachProfileRepo.GetCoachDirectory(ctx, strings.ToLower(strings.TrimSpace(filter.Specialty)), availableBefore, offset, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching coach directory: %w", err)
+	}
+	if coaches == nil {
+		coaches = []model.CoachDirectoryEntry{} // Return an empty slice instead of nil
+	}
+	return coaches, totalCount, nil
+}
+
+// getCoachProfileOrDefault falls back to the default schedule for coaches who
+// have never saved a profile.
+func getCoachProfileOrDefault(ctx context.Context, repo *repository.CoachProfileRepository, coachID uuid.UUID) (*model.CoachProfile, error) {
+	profile, err := repo.GetCoachProfile(ctx, coachID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			defaultProfile := model.DefaultCoachProfile(coachID)
+			return &defaultProfile, nil
+		}
+		return nil, fmt.Errorf("error fetching coach profile: %w", err)
+	}
+	if profile.WorkingHours == nil {
+		profile.WorkingHours = []model.WorkingHours{} // Return an empty slice instead of nil
+	}
+	return profile, nil
+}
+
+func loadCoachLocation(timeZone string) (*time.Location, error) {
+	// time.LoadLocation treats "" as UTC; a coach must name a zone explicitly
+	if timeZone == "" {
+		return nil, &ErrInvalidTimeZone{TimeZone: timeZone}
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, &ErrInvalidTimeZone{TimeZone: timeZone}
+	}
+	return loc, nil
+}
+
+func validateCoachProfile(profile model.CoachProfile) error {
+	if _, err := loadCoachLocation(profile.TimeZone); err != nil {
+		return err
+	}
+	if profile.MinCancellationNoticeMinutes < 0 {
+		return &ErrInvalidCoachProfile{Reason: "minimum cancellation notice cannot be negative"}
+	}
+	if profile.BookingRequestTTLMinutes < minBookingRequestTTLMinutes || profile.BookingRequestTTLMinutes > maxBookingRequestTTLMinutes {
+		return &ErrInvalidCoachProfile{Reason: fmt.Sprintf("booking request expiry must be between %d and %d minutes", minBookingRequestTTLMinutes, maxBookingRequestTTLMinutes)}
+	}
+	if profile.MinBookingLeadMinutes < 0 || profile.MinBookingLeadMinutes > maxBookingLeadMinutes {
+		return &ErrInvalidCoachProfile{Reason: fmt.Sprintf("minimum booking lead time must be between 0 and %d minutes", maxBookingLeadMinutes)}
+	}
+	if profile.MaxBookingHorizonDays < 0 || profile.MaxBookingHorizonDays > maxBookingHorizonDays {
+		return &ErrInvalidCoachProfile{Reason: fmt.Sprintf("booking horizon must be between 0 (no limit) and %d days", maxBookingHorizonDays)}
+	}
+	if profile.MaxBookingHorizonDays > 0 && profile.MinBookingLeadMinutes >= profile.MaxBookingHorizonDays*24*60 {
+		return &ErrInvalidCoachProfile{Reason: "booking horizon must be longer than the minimum lead time"}
+	}
+	if profile.MaxStudentNoShows != nil && *profile.MaxStudentNoShows < 0 {
+		return &ErrInvalidCoachProfile{Reason: "maximum student no-shows cannot be negative"}
+	}
+	if utf8.RuneCountInString(profile.Bio) > maxCoachBioLength {
+		return &ErrInvalidCoachProfile{Reason: fmt.Sprintf("bio cannot be longer than %d characters", maxCoachBioLength)}
+	}
+	if profile.PhotoURL != "" {
+		u, err := url.Parse(profile.PhotoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ErrInvalidCoachProfile{Reason: "photo URL must be an absolute http or https URL"}
+		}
+	}
+	for field, tags := range map[string][]string{"specialties": profile.Specialties, "languages": profile.Languages} {
+		if len(tags) > maxCoachProfileTags {
+			return &ErrInvalidCoachProfile{Reason: fmt.Sprintf("at most %d %s are allowed", maxCoachProfileTags, field)}
+		}
+		for _, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || utf8.RuneCountInString(tag) > maxCoachTagLength {
+				return &ErrInvalidCoachProfile{Reason: fmt.Sprintf("%s must be between 1 and %d characters", field, maxCoachTagLength)}
+			}
+		}
+	}
+	return validateWorkingHours(profile.WorkingHours)
+}
+
+// normalizeProfileTags trims tags and drops duplicates, keeping the first
+// spelling. Specialties are lower-cased so the directory can match them
+// exactly; languages keep the coach's capitalization.
+func normalizeProfileTags(tags []string, lower bool) pq.StringArray {
+	normalized := pq.StringArray{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if lower {
+			tag = strings.ToLower(tag)
+		}
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func validateWorkingHours(hours []model.WorkingHours) error {
+	sorted := make([]model.WorkingHours, len(hours))
+	copy(sorted, hours)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Weekday != sorted[j].Weekday {
+			return sorted[i].Weekday < sorted[j].Weekday
+		}
+		return sorted[i].StartMinute < sorted[j].StartMinute
+	})
+
+	for i, h := range sorted {
+		if h.Weekday < time.Sunday || h.Weekday > time.Saturday {
+			return &ErrInvalidWorkingHours{Reason: fmt.Sprintf("weekday %d must be between 0 (Sunday) and 6 (Saturday)", h.Weekday)}
+		}
+		if h.StartMinute < 0 || h.EndMinute > 24*60 || h.StartMinute >= h.EndMinute {
+			return &ErrInvalidWorkingHours{Reason: fmt.Sprintf("%s window must start before it ends and stay within the day", h.Weekday)}
+		}
+		if h.StartMinute%15 != 0 || h.EndMinute%15 != 0 {
+			return &ErrInvalidWorkingHours{Reason: fmt.Sprintf("%s window must start and end on 15-minute increments", h.Weekday)}
+		}
+		if i > 0 && sorted[i-1].Weekday == h.Weekday && sorted[i-1].EndMinute > h.StartMinute {
+			return &ErrInvalidWorkingHours{Reason: fmt.Sprintf("%s windows overlap", h.Weekday)}
+		}
+	}
+	return nil
+}
+
+// withinWorkingHours reports whether [start, end) falls entirely inside one of
+// the coach's working windows. Windows are anchored to the local calendar day
+// of start with time.Date, so a 9:00-17:00 window stays 9:00-17:00 on the wall
+// clock across DST transitions even though the day is 23 or 25 hours long.
+func withinWorkingHours(hours []model.WorkingHours, loc *time.Location, start, end time.Time) bool {
+	localStart := start.In(loc)
+	year, month, day := localStart.Date()
+	for _, h := range hours {
+		if h.Weekday != localStart.Weekday() {
+			continue
+		}
+		windowStart := time.Date(year, month, day, 0, h.StartMinute, 0, 0, loc)
+		windowEnd := time.Date(year, month, day, 0, h.EndMinute, 0, 0, loc)
+		if !start.Before(windowStart) && !end.After(windowEnd) {
+			return true
+		}
+	}
+	return false
+}
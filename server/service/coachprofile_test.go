@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cargoreligion/booking/server/model"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestValidateWorkingHours(t *testing.T) {
+	tests := []struct {
+		name    string
+		hours   []model.WorkingHours
+		wantErr bool
+	}{
+		{name: "none", hours: nil},
+		{
+			name: "separate and adjacent windows",
+			hours: []model.WorkingHours{
+				{Weekday: time.Monday, StartMinute: 13 * 60, EndMinute: 17 * 60},
+				{Weekday: time.Monday, StartMinute: 9 * 60, EndMinute: 12 * 60},
+				{Weekday: time.Monday, StartMinute: 12 * 60, EndMinute: 13 * 60},
+				{Weekday: time.Tuesday, StartMinute: 9 * 60, EndMinute: 17 * 60},
+			},
+		},
+		{
+			name: "whole day",
+			hours: []model.WorkingHours{
+				{Weekday: time.Sunday, StartMinute: 0, EndMinute: 24 * 60},
+				{Weekday: time.Saturday, StartMinute: 0, EndMinute: 24 * 60},
+			},
+		},
+		{
+			name:    "weekday before Sunday",
+			hours:   []model.WorkingHours{{Weekday: -1, StartMinute: 540, EndMinute: 600}},
+			wantErr: true,
+		},
+		{
+			name:    "weekday after Saturday",
+			hours:   []model.WorkingHours{{Weekday: 7, StartMinute: 540, EndMinute: 600}},
+			wantErr: true,
+		},
+		{
+			name:    "starts before midnight",
+			hours:   []model.WorkingHours{{Weekday: time.Monday, StartMinute: -15, EndMinute: 600}},
+			wantErr: true,
+		},
+		{
+			name:    "ends after midnight",
+			hours:   []model.WorkingHours{{Weekday: time.Monday, StartMinute: 23 * 60, EndMinute: 25 * 60}},
+			wantErr: true,
+		},
+		{
+			name:    "zero length",
+			hours:   []model.WorkingHours{{Weekday: time.Monday, StartMinute: 600, EndMinute: 600}},
+			wantErr: true,
+		},
+		{
+			name:    "ends before it starts",
+			hours:   []model.WorkingHours{{Weekday: time.Monday, StartMinute: 600, EndMinute: 540}},
+			wantErr: true,
+		},
+		{
+			name:    "off the 15-minute grid",
+			hours:   []model.WorkingHours{{Weekday: time.Monday, StartMinute: 545, EndMinute: 600}},
+			wantErr: true,
+		},
+		{
+			name: "overlapping",
+			hours: []model.WorkingHours{
+				{Weekday: time.Monday, StartMinute: 12 * 60, EndMinute: 17 * 60},
+				{Weekday: time.Monday, StartMinute: 9 * 60, EndMinute: 13 * 60},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overlapping the whole day",
+			hours: []model.WorkingHours{
+				{Weekday: time.Friday, StartMinute: 0, EndMinute: 24 * 60},
+				{Weekday: time.Friday, StartMinute: 9 * 60, EndMinute: 10 * 60},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWorkingHours(tt.hours)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("validateWorkingHours() = %v, want nil", err)
+				}
+				return
+			}
+			var errInvalidWorkingHours *ErrInvalidWorkingHours
+			if !errors.As(err, &errInvalidWorkingHours) {
+				t.Errorf("validateWorkingHours() = %v, want *ErrInvalidWorkingHours", err)
+			}
+		})
+	}
+}
+
+func TestWithinWorkingHours(t *testing.T) {
+	losAngeles := loadLocation(t, "America/Los_Angeles")
+	berlin := loadLocation(t, "Europe/Berlin")
+
+	// Sunday is the transition day in both zones. The early window spans the
+	// missing or repeated hour.
+	hours := []model.WorkingHours{
+		{Weekday: time.Saturday, StartMinute: 22 * 60, EndMinute: 24 * 60},
+		{Weekday: time.Sunday, StartMinute: 0, EndMinute: 4 * 60},
+		{Weekday: time.Sunday, StartMinute: 9 * 60, EndMinute: 17 * 60},
+		{Weekday: time.Monday, StartMinute: 10 * 60, EndMinute: 10 * 60},
+	}
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		start    time.Time
+		duration time.Duration
+		want     bool
+	}{
+		// US spring forward, Sunday March 10 2024: 2:00 becomes 3:00
+		{"US spring forward day", losAngeles, time.Date(2024, 3, 10, 9, 0, 0, 0, losAngeles), 8 * time.Hour, true},
+		{"US spring forward past window", losAngeles, time.Date(2024, 3, 10, 16, 30, 0, 0, losAngeles), time.Hour, false},
+		{"US spring forward short night", losAngeles, time.Date(2024, 3, 10, 0, 0, 0, 0, losAngeles), 3 * time.Hour, true},
+		{"US spring forward night too long", losAngeles, time.Date(2024, 3, 10, 0, 0, 0, 0, losAngeles), 3*time.Hour + 15*time.Minute, false},
+		// US fall back, Sunday November 3 2024: 2:00 becomes 1:00
+		{"US fall back day", losAngeles, time.Date(2024, 11, 3, 9, 0, 0, 0, losAngeles), 8 * time.Hour, true},
+		{"US fall back long night", losAngeles, time.Date(2024, 11, 3, 0, 0, 0, 0, losAngeles), 5 * time.Hour, true},
+		{"US fall back night too long", losAngeles, time.Date(2024, 11, 3, 0, 0, 0, 0, losAngeles), 5*time.Hour + 15*time.Minute, false},
+		// EU spring forward, Sunday March 31 2024: 2:00 becomes 3:00
+		{"EU spring forward day", berlin, time.Date(2024, 3, 31, 9, 0, 0, 0, berlin), 8 * time.Hour, true},
+		{"EU spring forward before window", berlin, time.Date(2024, 3, 31, 8, 30, 0, 0, berlin), time.Hour, false},
+		{"EU spring forward short night", berlin, time.Date(2024, 3, 31, 0, 0, 0, 0, berlin), 3 * time.Hour, true},
+		{"EU spring forward night too long", berlin, time.Date(2024, 3, 31, 0, 0, 0, 0, berlin), 3*time.Hour + 15*time.Minute, false},
+		// EU fall back, Sunday October 27 2024: 3:00 becomes 2:00
+		{"EU fall back day", berlin, time.Date(2024, 10, 27, 9, 0, 0, 0, berlin), 8 * time.Hour, true},
+		{"EU fall back long night", berlin, time.Date(2024, 10, 27, 0, 0, 0, 0, berlin), 5 * time.Hour, true},
+		{"EU fall back night too long", berlin, time.Date(2024, 10, 27, 0, 0, 0, 0, berlin), 5*time.Hour + 15*time.Minute, false},
+		// The US has already sprung forward; Berlin has not
+		{"Berlin on US spring forward day", berlin, time.Date(2024, 3, 10, 9, 0, 0, 0, berlin), 8 * time.Hour, true},
+		// Saturday 22:00-24:00 and Sunday 0:00-4:00 are separate windows
+		{"ends at midnight", berlin, time.Date(2024, 6, 1, 23, 0, 0, 0, berlin), time.Hour, true},
+		{"crosses midnight", berlin, time.Date(2024, 6, 1, 23, 0, 0, 0, berlin), 2 * time.Hour, false},
+		// Sunday 16:30 in Los Angeles is already Monday in UTC
+		{"weekday in coach's zone", losAngeles, time.Date(2024, 6, 2, 23, 30, 0, 0, time.UTC), 30 * time.Minute, true},
+		{"weekday in other zone", berlin, time.Date(2024, 6, 2, 23, 30, 0, 0, time.UTC), 30 * time.Minute, false},
+		{"zero-length window", berlin, time.Date(2024, 6, 3, 10, 0, 0, 0, berlin), time.Hour, false},
+		{"no working day", losAngeles, time.Date(2024, 6, 4, 10, 0, 0, 0, losAngeles), time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withinWorkingHours(hours, tt.loc, tt.start, tt.start.Add(tt.duration))
+			if got != tt.want {
+				t.Errorf("withinWorkingHours(%s, %s) = %t, want %t", tt.start.In(tt.loc), tt.duration, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+// The most credits a single grant can add, which catches typos like 1000
+// for a 10-session package.
+const maxCreditGrant = 100
+
+type CreditService struct {
+	dbc         db.DbClient
+	creditRepo  *repository.CreditRepository
+	bookingRepo *repository.BookingRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewCreditService(dbc db.DbClient, creditRepo *repository.CreditRepository, bookingRepo *repository.BookingRepository, userRepo *repository.UserRepository) *CreditService {
+	return &CreditService{
+		dbc:         dbc,
+		creditRepo:  creditRepo,
+		bookingRepo: bookingRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// GrantCredits adds a package of session credits to a student's balance.
+// Program leads can grant credits to any student. Coaches can only top up
+// students who have booked with them before.
+func (s *CreditService) GrantCredits(ctx context.Context, actorID, studentID uuid.UUID, amount int, note string) (*model.CreditLedgerEntry, error) {
+	actor, err := s.userRepo.GetUserByID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if actor.Role != model.RoleCoach && actor.Role != model.RoleProgramLead {
+		return nil, &ErrNotAuthorized{UserID: actorID.String(), Action: "grant session credits"}
+	}
+	if err := s.checkStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+	if actor.Role == model.RoleCoach {
+		booked, err := s.bookingRepo.HasBookedWithCoach(ctx, studentID, actorID)
+		if err != nil {
+			return nil, fmt.Errorf("error checking student's bookings: %w", err)
+		}
+		if !booked {
+			return nil, &ErrNotAuthorized{UserID: actorID.String(), Action: "grant credits to a student they have not coached"}
+		}
+	}
+	if amount < 1 || amount > maxCreditGrant {
+		return nil, &ErrInvalidCreditGrant{Reason: fmt.Sprintf("amount must be between 1 and %d", maxCreditGrant)}
+	}
+
+	entry := model.CreditLedgerEntry{
+		ID:        uuid.New(),
+		StudentID: studentID,
+		Kind:      model.CreditEntryGrant,
+		Amount:    amount,
+		ActorID:   &actorID,
+		Note:      strings.TrimSpace(note),
+		CreatedAt: time.Now(),
+	}
+	if err := s.creditRepo.CreateEntry(ctx, entry); err != nil {
+		return nil, fmt.Errorf("error granting credits: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetBalance returns a student's credit balance. Students can see their own
+// balance, coaches the balances of students who have booked with them, and
+// program leads any student's.
+func (s *CreditService) GetBalance(ctx context.Context, userID, studentID uuid.UUID) (*model.CreditBalance, error) {
+	if err := s.checkCanView(ctx, userID, studentID); err != nil {
+		return nil, err
+	}
+	balance, err := s.creditRepo.GetBalance(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching credit balance: %w", err)
+	}
+	return &model.CreditBalance{StudentID: studentID, Balance: balance}, nil
+}
+
+// GetLedger returns a student's credit history, newest first, with the same
+// visibility rules as GetBalance.
+func (s *CreditService) GetLedger(ctx context.Context, userID, studentID uuid.UUID, page, pageSize int) ([]model.CreditLedgerEntry, int, error) {
+	if err := s.checkCanView(ctx, userID, studentID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	entries, totalCount, err := s.creditRepo.GetEntriesForStudent(ctx, studentID, offset, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching credit ledger: %w", err)
+	}
+	if entries == nil {
+		entries = []model.CreditLedgerEntry{} // Return an empty slice instead of nil
+	}
+	return entries, totalCount, nil
+}
+
+func (s *CreditService) checkCanView(ctx context.Context, userID, studentID uuid.UUID) error {
+	if userID == studentID {
+		return s.checkStudent(ctx, studentID)
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	switch user.Role {
+	case model.RoleProgramLead:
+	case model.RoleCoach:
+		booked, err := s.bookingRepo.HasBookedWithCoach(ctx, studentID, userID)
+		if err != nil {
+			return fmt.Errorf("error checking student's bookings: %w", err)
+		}
+		if !booked {
+			return &ErrNotAuthorized{UserID: userID.String(), Action: "view credits of a student they have not coached"}
+		}
+	default:
+		return &ErrNotAuthorized{UserID: userID.String(), Action: "view another student's credits"}
+	}
+	return s.checkStudent(ctx, studentID)
+}
+
+func (s *CreditService) checkStudent(ctx context.Context, studentID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByID(ctx, studentID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleStudent {
+		return &ErrNotStudent{UserID: studentID.String()}
+	}
+	return nil
+}
+
+// debitCredit spends one of the student's credits on a seat in the slot. The
+// caller must hold the student's row lock so concurrent bookings cannot
+// spend the same credit twice.
+func debitCredit(ctx context.Context, tx db.DbClient, studentID, slotID uuid.UUID) error {
+	creditRepo := repository.NewCreditRepository(tx)
+	balance, err := creditRepo.GetBalance(ctx, studentID)
+	if err != nil {
+		return fmt.Errorf("error fetching credit balance: %w", err)
+	}
+	if balance <= 0 {
+		return &ErrInsufficientCredits{StudentID: studentID.String(), Balance: balance}
+	}
+
+	entry := model.CreditLedgerEntry{
+		ID:        uuid.New(),
+		StudentID: studentID,
+		Kind:      model.CreditEntryDebit,
+		Amount:    -1,
+		SlotID:    &slotID,
+		ActorID:   &studentID,
+		CreatedAt: time.Now(),
+	}
+	if err := creditRepo.CreateEntry(ctx, entry); err != nil {
+		return fmt.Errorf("error debiting credit: %w", err)
+	}
+	return nil
+}
+
+// refundCredit returns whatever the student spent on the slot and reports
+// whether there was anything to refund. Bookings made before credits were
+// tracked have nothing to give back.
+func refundCredit(ctx context.Context, tx db.DbClient, studentID, slotID, actorID uuid.UUID, note string) (bool, error) {
+	creditRepo := repository.NewCreditRepository(tx)
+	spent, err := creditRepo.GetSlotBalance(ctx, studentID, slotID)
+	if err != nil {
+		return false, fmt.Errorf("error fetching slot credits: %w", err)
+	}
+	if spent >= 0 {
+		return false, nil
+	}
+
+	entry := model.CreditLedgerEntry{
+		ID:        uuid.New(),
+		StudentID: studentID,
+		Kind:      model.CreditEntryRefund,
+		Amount:    -spent,
+		SlotID:    &slotID,
+		ActorID:   &actorID,
+		Note:      note,
+		CreatedAt: time.Now(),
+	}
+	if err := creditRepo.CreateEntry(ctx, entry); err != nil {
+		return false, fmt.Errorf("error refunding credit: %w", err)
+	}
+	return true, nil
+}
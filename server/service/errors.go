@@ -1,6 +1,11 @@
 package service
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	"github.com/cargoreligion/booking/server/model"
+)
 
 type ErrSlotNotFound struct {
 	SlotID string
@@ -67,3 +72,462 @@ type ErrSlotNotAssignedToCoach struct {
 func (e *ErrSlotNotAssignedToCoach) Error() string {
 	return fmt.Sprintf("slot with ID %s is not assigned to coach with ID %s", e.SlotID, e.CoachID)
 }
+
+type ErrSessionTypeNotFound struct {
+	SessionTypeID string
+}
+
+func (e *ErrSessionTypeNotFound) Error() string {
+	return fmt.Sprintf("session type with ID %s not found", e.SessionTypeID)
+}
+
+type ErrInvalidSessionType struct {
+	Reason string
+}
+
+func (e *ErrInvalidSessionType) Error() string {
+	return fmt.Sprintf("invalid session type: %s", e.Reason)
+}
+
+type ErrInvalidSlotDuration struct {
+	Reason string
+}
+
+func (e *ErrInvalidSlotDuration) Error() string {
+	return fmt.Sprintf("invalid slot duration: %s", e.Reason)
+}
+
+type ErrInvalidTimeZone struct {
+	TimeZone string
+}
+
+func (e *ErrInvalidTimeZone) Error() string {
+	return fmt.Sprintf("time zone %q is not a valid IANA time zone", e.TimeZone)
+}
+
+type ErrInvalidWorkingHours struct {
+	Reason string
+}
+
+func (e *ErrInvalidWorkingHours) Error() string {
+	return fmt.Sprintf("invalid working hours: %s", e.Reason)
+}
+
+type ErrInvalidCoachProfile struct {
+	Reason string
+}
+
+func (e *ErrInvalidCoachProfile) Error() string {
+	return fmt.Sprintf("invalid coach profile: %s", e.Reason)
+}
+
+type ErrInvalidCoachDirectoryFilter struct {
+	Reason string
+}
+
+func (e *ErrInvalidCoachDirectoryFilter) Error() string {
+	return fmt.Sprintf("invalid coach directory filter: %s", e.Reason)
+}
+
+type ErrSlotStartInPast struct {
+	StartTime time.Time
+}
+
+func (e *ErrSlotStartInPast) Error() string {
+	return fmt.Sprintf("cannot create a slot in the past (start time %s)", e.StartTime.Format(time.RFC3339))
+}
+
+type ErrSlotStartIncrement struct {
+	StartTime time.Time
+}
+
+func (e *ErrSlotStartIncrement) Error() string {
+	return fmt.Sprintf("slot must start at 15-minute increments (e.g., 9:00, 9:15, 9:30, 9:45), got %s", e.StartTime.Format("15:04:05"))
+}
+
+type ErrOutsideWorkingHours struct {
+	CoachID   string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (e *ErrOutsideWorkingHours) Error() string {
+	return fmt.Sprintf("slot from %s to %s is outside the working hours of coach with ID %s",
+		e.StartTime.Format("Mon 2006-01-02 15:04 MST"), e.EndTime.Format("15:04 MST"), e.CoachID)
+}
+
+type ErrOverlappingSlot struct {
+	CoachID string
+}
+
+func (e *ErrOverlappingSlot) Error() string {
+	return fmt.Sprintf("slot overlaps with an existing slot for coach with ID %s", e.CoachID)
+}
+
+type ErrAvailabilityRuleNotFound struct {
+	RuleID string
+}
+
+func (e *ErrAvailabilityRuleNotFound) Error() string {
+	return fmt.Sprintf("availability rule with ID %s not found", e.RuleID)
+}
+
+type ErrInvalidAvailabilityRule struct {
+	Reason string
+}
+
+func (e *ErrInvalidAvailabilityRule) Error() string {
+	return fmt.Sprintf("invalid availability rule: %s", e.Reason)
+}
+
+type ErrBookingNotFound struct {
+	SlotID    string
+	StudentID string
+}
+
+func (e *ErrBookingNotFound) Error() string {
+	return fmt.Sprintf("student with ID %s has no booking for slot with ID %s", e.StudentID, e.SlotID)
+}
+
+type ErrCancellationTooLate struct {
+	SlotID string
+	Notice time.Duration
+}
+
+func (e *ErrCancellationTooLate) Error() string {
+	return fmt.Sprintf("slot with ID %s can no longer be cancelled; cancellations require %s notice", e.SlotID, e.Notice)
+}
+
+type ErrSlotBooked struct {
+	SlotID string
+}
+
+func (e *ErrSlotBooked) Error() string {
+	return fmt.Sprintf("slot with ID %s is booked; cancel the booking and notify the student instead", e.SlotID)
+}
+
+type ErrSlotNotBooked struct {
+	SlotID string
+}
+
+func (e *ErrSlotNotBooked) Error() string {
+	return fmt.Sprintf("slot with ID %s is not booked", e.SlotID)
+}
+
+type ErrSlotStarted struct {
+	SlotID string
+}
+
+func (e *ErrSlotStarted) Error() string {
+	return fmt.Sprintf("slot with ID %s has already started and can no longer be changed", e.SlotID)
+}
+
+type ErrReasonRequired struct {
+	Action string
+}
+
+func (e *ErrReasonRequired) Error() string {
+	return fmt.Sprintf("a reason is required to %s", e.Action)
+}
+
+type ErrInvalidSlotTransition struct {
+	SlotID string
+	From   model.SlotStatus
+	To     model.SlotStatus
+}
+
+func (e *ErrInvalidSlotTransition) Error() string {
+	return fmt.Sprintf("slot with ID %s cannot change from %s to %s", e.SlotID, e.From, e.To)
+}
+
+type ErrSlotNotOpen struct {
+	SlotID string
+	Status model.SlotStatus
+}
+
+func (e *ErrSlotNotOpen) Error() string {
+	return fmt.Sprintf("slot with ID %s is %s and can no longer be changed", e.SlotID, e.Status)
+}
+
+type ErrInvalidCapacity struct {
+	Capacity int
+	Max      int
+}
+
+func (e *ErrInvalidCapacity) Error() string {
+	return fmt.Sprintf("capacity %d is invalid; slots hold between 1 and %d students", e.Capacity, e.Max)
+}
+
+type ErrStudentRequired struct {
+	SlotID string
+}
+
+func (e *ErrStudentRequired) Error() string {
+	return fmt.Sprintf("slot with ID %s has several attendees; specify the student", e.SlotID)
+}
+
+type ErrSlotHasSeats struct {
+	SlotID string
+}
+
+func (e *ErrSlotHasSeats) Error() string {
+	return fmt.Sprintf("slot with ID %s still has seats; book it instead of joining the waitlist", e.SlotID)
+}
+
+type ErrAlreadyWaitlisted struct {
+	StudentID string
+}
+
+func (e *ErrAlreadyWaitlisted) Error() string {
+	return fmt.Sprintf("student with ID %s is already on this waitlist", e.StudentID)
+}
+
+type ErrInvalidWaitlistRequest struct {
+	Reason string
+}
+
+func (e *ErrInvalidWaitlistRequest) Error() string {
+	return fmt.Sprintf("cannot join waitlist: %s", e.Reason)
+}
+
+type ErrWaitlistEntryNotFound struct {
+	EntryID string
+}
+
+func (e *ErrWaitlistEntryNotFound) Error() string {
+	return fmt.Sprintf("waitlist entry with ID %s not found", e.EntryID)
+}
+
+type ErrPromotionNotFound struct {
+	PromotionID string
+}
+
+func (e *ErrPromotionNotFound) Error() string {
+	return fmt.Sprintf("waitlist promotion with ID %s not found", e.PromotionID)
+}
+
+type ErrPromotionClosed struct {
+	PromotionID string
+	Status      model.PromotionStatus
+}
+
+func (e *ErrPromotionClosed) Error() string {
+	return fmt.Sprintf("waitlist promotion with ID %s is %s and can no longer be answered", e.PromotionID, e.Status)
+}
+
+type ErrInvalidSlotSearch struct {
+	Reason string
+}
+
+func (e *ErrInvalidSlotSearch) Error() string {
+	return fmt.Sprintf("invalid slot search: %s", e.Reason)
+}
+
+type ErrInsufficientCredits struct {
+	StudentID string
+	Balance   int
+}
+
+func (e *ErrInsufficientCredits) Error() string {
+	return fmt.Sprintf("student with ID %s has %d session credits left and cannot book", e.StudentID, e.Balance)
+}
+
+type ErrInvalidCreditGrant struct {
+	Reason string
+}
+
+func (e *ErrInvalidCreditGrant) Error() string {
+	return fmt.Sprintf("invalid credit grant: %s", e.Reason)
+}
+
+type ErrPaymentFailed struct {
+	SlotID string
+	Stage  string
+	Err    error
+}
+
+func (e *ErrPaymentFailed) Error() string {
+	return fmt.Sprintf("payment for slot %s failed to %s: %v", e.SlotID, e.Stage, e.Err)
+}
+
+func (e *ErrPaymentFailed) Unwrap() error {
+	return e.Err
+}
+
+type ErrBookingRequestNotFound struct {
+	BookingID string
+}
+
+func (e *ErrBookingRequestNotFound) Error() string {
+	return fmt.Sprintf("booking request with ID %s not found", e.BookingID)
+}
+
+type ErrBookingRequestClosed struct {
+	BookingID string
+	Reason    string
+}
+
+func (e *ErrBookingRequestClosed) Error() string {
+	return fmt.Sprintf("booking request with ID %s can no longer be answered: %s", e.BookingID, e.Reason)
+}
+
+type ErrCheckoutHoldExists struct {
+	SlotID string
+}
+
+func (e *ErrCheckoutHoldExists) Error() string {
+	return fmt.Sprintf("a seat in slot %s is already held for you; book or release it first", e.SlotID)
+}
+
+// ErrBookingTooSoon is returned when a slot starts within the coach's minimum
+// booking lead time.
+type ErrBookingTooSoon struct {
+	SlotID   string
+	LeadTime time.Duration
+}
+
+func (e *ErrBookingTooSoon) Error() string {
+	return fmt.Sprintf("slot %s must be booked at least %s before it starts", e.SlotID, e.LeadTime)
+}
+
+// ErrBookingTooFarAhead is returned when a slot starts beyond the coach's
+// booking horizon.
+type ErrBookingTooFarAhead struct {
+	SlotID      string
+	HorizonDays int
+}
+
+func (e *ErrBookingTooFarAhead) Error() string {
+	return fmt.Sprintf("slot %s cannot be booked more than %d days ahead", e.SlotID, e.HorizonDays)
+}
+
+type ErrInvalidAttendance struct {
+	Attendance string
+}
+
+func (e *ErrInvalidAttendance) Error() string {
+	return fmt.Sprintf("invalid attendance %q; use attended, student_no_show or coach_no_show", e.Attendance)
+}
+
+// ErrSessionNotEnded is returned for actions that need the session to be
+// over, such as marking attendance.
+type ErrSessionNotEnded struct {
+	SlotID string
+}
+
+func (e *ErrSessionNotEnded) Error() string {
+	return fmt.Sprintf("session in slot %s has not ended yet", e.SlotID)
+}
+
+// ErrNoShowSession is returned for feedback on a session that did not take
+// place.
+type ErrNoShowSession struct {
+	SlotID     string
+	StudentID  string
+	Attendance model.AttendanceStatus
+}
+
+func (e *ErrNoShowSession) Error() string {
+	return fmt.Sprintf("session in slot %s for student %s was marked %s", e.SlotID, e.StudentID, e.Attendance)
+}
+
+// ErrTooManyNoShows is returned when the coach's no-show policy blocks the
+// student from booking.
+type ErrTooManyNoShows struct {
+	StudentID string
+	NoShows   int
+	Limit     int
+}
+
+func (e *ErrTooManyNoShows) Error() string {
+	return fmt.Sprintf("student %s missed %d sessions in the last 30 days, more than the coach allows (%d)", e.StudentID, e.NoShows, e.Limit)
+}
+
+// ErrFeedbackExists is returned when the session already has feedback, which
+// should be updated instead.
+type ErrFeedbackExists struct {
+	SlotID     string
+	StudentID  string
+	FeedbackID string
+}
+
+func (e *ErrFeedbackExists) Error() string {
+	return fmt.Sprintf("session in slot %s for student %s already has feedback %s; update it instead", e.SlotID, e.StudentID, e.FeedbackID)
+}
+
+type ErrFeedbackNotFound struct {
+	FeedbackID string
+}
+
+func (e *ErrFeedbackNotFound) Error() string {
+	return fmt.Sprintf("session feedback with ID %s not found", e.FeedbackID)
+}
+
+type ErrInvalidSessionRating struct {
+	Reason string
+}
+
+func (e *ErrInvalidSessionRating) Error() string {
+	return fmt.Sprintf("invalid session rating: %s", e.Reason)
+}
+
+type ErrSessionAlreadyRated struct {
+	SlotID string
+}
+
+func (e *ErrSessionAlreadyRated) Error() string {
+	return fmt.Sprintf("session in slot %s has already been rated", e.SlotID)
+}
+
+type ErrInvalidAnalyticsFilter struct {
+	Reason string
+}
+
+func (e *ErrInvalidAnalyticsFilter) Error() string {
+	return fmt.Sprintf("invalid analytics filter: %s", e.Reason)
+}
+
+type ErrFeedbackTemplateNotFound struct {
+	TemplateID string
+}
+
+func (e *ErrFeedbackTemplateNotFound) Error() string {
+	return fmt.Sprintf("feedback template with ID %s not found", e.TemplateID)
+}
+
+// ErrFeedbackTemplateArchived is returned for new feedback against a
+// template that is no longer in use.
+type ErrFeedbackTemplateArchived struct {
+	TemplateID string
+}
+
+func (e *ErrFeedbackTemplateArchived) Error() string {
+	return fmt.Sprintf("feedback template %s has been archived", e.TemplateID)
+}
+
+type ErrInvalidFeedbackTemplate struct {
+	Reason string
+}
+
+func (e *ErrInvalidFeedbackTemplate) Error() string {
+	return fmt.Sprintf("invalid feedback template: %s", e.Reason)
+}
+
+// ErrInvalidFeedbackResponse is returned when feedback does not fit its
+// template.
+type ErrInvalidFeedbackResponse struct {
+	Field  string
+	Reason string
+}
+
+func (e *ErrInvalidFeedbackResponse) Error() string {
+	return fmt.Sprintf("feedback field %q %s", e.Field, e.Reason)
+}
+
+type ErrInvalidSearchQuery struct {
+	Reason string
+}
+
+func (e *ErrInvalidSearchQuery) Error() string {
+	return fmt.Sprintf("invalid search: %s", e.Reason)
+}
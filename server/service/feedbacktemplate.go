@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	maxTemplateFields   = 30
+	maxFieldOptions     = 20
+	maxFeedbackTextSize = 5000
+	// Score fields without bounds use the same 1-5 scale as satisfaction
+	defaultMinScore = 1
+	defaultMaxScore = 5
+)
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+type FeedbackTemplateService struct {
+	dbc                  db.DbClient
+	feedbackTemplateRepo *repository.FeedbackTemplateRepository
+	userRepo             *repository.UserRepository
+}
+
+func NewFeedbackTemplateService(dbc db.DbClient, feedbackTemplateRepo *repository.FeedbackTemplateRepository, userRepo *repository.UserRepository) *FeedbackTemplateService {
+	return &FeedbackTemplateService{
+		dbc:                  dbc,
+		feedbackTemplateRepo: feedbackTemplateRepo,
+		userRepo:             userRepo,
+	}
+}
+
+// CreateTemplate defines a new feedback template. Only program leads can
+// create templates, and their fields cannot be changed afterwards.
+func (s *FeedbackTemplateService) CreateTemplate(ctx context.Context, userID uuid.UUID, template model.FeedbackTemplate) (*model.FeedbackTemplate, error) {
+	if err := s.checkProgramLead(ctx, userID, "create feedback templates"); err != nil {
+		return nil, err
+	}
+
+	template.ID = uuid.New()
+	template.Active = true
+	template.CreatedBy = userID
+	template.CreatedAt = time.Now()
+	if err := validateFeedbackTemplate(&template); err != nil {
+		return nil, err
+	}
+
+	err := s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		if err := repository.NewFeedbackTemplateRepository(tx).CreateTemplate(ctx, template); err != nil {
+			return fmt.Errorf("error creating feedback template: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetTemplates lists the templates coaches can use, with their fields.
+// Archived templates are included only when asked for.
+func (s *FeedbackTemplateService) GetTemplates(ctx context.Context, includeArchived bool) ([]model.FeedbackTemplate, error) {
+	templates, err := s.feedbackTemplateRepo.GetTemplates(ctx, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching feedback templates: %w", err)
+	}
+	if len(templates) == 0 {
+		return []model.FeedbackTemplate{}, nil // Return an empty slice instead of nil
+	}
+
+	ids := make([]uuid.UUID, len(templates))
+	for i, template := range templates {
+		ids[i] = template.ID
+	}
+	fields, err := s.feedbackTemplateRepo.GetFieldsForTemplates(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching feedback template fields: %w", err)
+	}
+	for i := range templates {
+		templates[i].Fields = []model.FeedbackTemplateField{}
+		for _, field := range fields {
+			if field.TemplateID == templates[i].ID {
+				templates[i].Fields = append(templates[i].Fields, field)
+			}
+		}
+	}
+	return templates, nil
+}
+
+func (s *FeedbackTemplateService) GetTemplate(ctx context.Context, templateID uuid.UUID) (*model.FeedbackTemplate, error) {
+	return getFeedbackTemplate(ctx, s.feedbackTemplateRepo, templateID)
+}
+
+// ArchiveTemplate stops coaches from using the template for new feedback.
+// Feedback already given against it is kept and still reported on.
+func (s *FeedbackTemplateService) ArchiveTemplate(ctx context.Context, userID, templateID uuid.UUID) error {
+	if err := s.checkProgramLead(ctx, userID, "archive feedback templates"); err != nil {
+		return err
+	}
+	if _, err := getFeedbackTemplate(ctx, s.feedbackTemplateRepo, templateID); err != nil {
+		return err
+	}
+	if err := s.feedbackTemplateRepo.SetTemplateActive(ctx, templateID, false); err != nil {
+		return fmt.Errorf("error archiving feedback template: %w", err)
+	}
+	return nil
+}
+
+// GetTemplateReport aggregates the answers to each of the template's fields
+// on feedback in the filter's range. As with satisfaction analytics, coaches
+// see only their own sessions.
+func (s *FeedbackTemplateService) GetTemplateReport(ctx context.Context, userID, templateID uuid.UUID, filter model.SatisfactionAnalyticsFilter) (*model.FeedbackTemplateReport, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if err := scopeAnalyticsFilter(user, &filter); err != nil {
+		return nil, err
+	}
+	template, err := getFeedbackTemplate(ctx, s.feedbackTemplateRepo, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.feedbackTemplateRepo.CountTemplateFeedback(ctx, templateID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error counting template feedback: %w", err)
+	}
+	stats, err := s.feedbackTemplateRepo.GetFieldResponseStats(ctx, templateID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching field responses: %w", err)
+	}
+	counts, err := s.feedbackTemplateRepo.GetFieldValueCounts(ctx, templateID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching field answers: %w", err)
+	}
+
+	report := model.FeedbackTemplateReport{
+		TemplateID: templateID,
+		From:       filter.From,
+		To:         filter.To,
+		Sessions:   sessions,
+		Fields:     make([]model.FeedbackFieldReport, len(template.Fields)),
+	}
+	for i, field := range template.Fields {
+		fieldReport := model.FeedbackFieldReport{
+			FieldID:      field.ID,
+			Key:          field.Key,
+			Label:        field.Label,
+			Kind:         field.Kind,
+			Distribution: map[string]int{},
+		}
+		// Every possible answer is listed, even if nobody gave it
+		switch field.Kind {
+		case model.FeedbackFieldScore:
+			for score := *field.MinScore; score <= *field.MaxScore; score++ {
+				fieldReport.Distribution[strconv.Itoa(score)] = 0
+			}
+		case model.FeedbackFieldChoice, model.FeedbackFieldChecklist:
+			for _, option := range field.Options {
+				fieldReport.Distribution[option] = 0
+			}
+		}
+		for _, stat := range stats {
+			if stat.FieldID == field.ID {
+				fieldReport.Responses = stat.Responses
+				fieldReport.Average = stat.Average
+			}
+		}
+		for _, count := range counts {
+			if count.FieldID == field.ID {
+				fieldReport.Distribution[count.Value] = count.Count
+			}
+		}
+		report.Fields[i] = fieldReport
+	}
+	return &report, nil
+}
+
+func (s *FeedbackTemplateService) checkProgramLead(ctx context.Context, userID uuid.UUID, action string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleProgramLead {
+		return &ErrNotAuthorized{UserID: userID.String(), Action: action}
+	}
+	return nil
+}
+
+func getFeedbackTemplate(ctx context.Context, repo *repository.FeedbackTemplateRepository, templateID uuid.UUID) (*model.FeedbackTemplate, error) {
+	template, err := repo.GetTemplate(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &ErrFeedbackTemplateNotFound{TemplateID: templateID.String()}
+		}
+		return nil, fmt.Errorf("error fetching feedback template: %w", err)
+	}
+	if template.Fields == nil {
+		template.Fields = []model.FeedbackTemplateField{} // Return an empty slice instead of nil
+	}
+	return template, nil
+}
+
+// validateFeedbackTemplate checks a new template and normalizes its fields:
+// they are numbered in the order given and score bounds default to 1-5.
+func validateFeedbackTemplate(template *model.FeedbackTemplate) error {
+	template.Name = strings.TrimSpace(template.Name)
+	template.Description = strings.TrimSpace(template.Description)
+	if template.Name == "" {
+		return &ErrInvalidFeedbackTemplate{Reason: "name is required"}
+	}
+	if len(template.Fields) == 0 || len(template.Fields) > maxTemplateFields {
+		return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("a template needs between 1 and %d fields", maxTemplateFields)}
+	}
+
+	keys := make(map[string]bool, len(template.Fields))
+	for i := range template.Fields {
+		field := &template.Fields[i]
+		field.ID = uuid.New()
+		field.TemplateID = template.ID
+		field.Position = i + 1
+		field.Key = strings.TrimSpace(field.Key)
+		field.Label = strings.TrimSpace(field.Label)
+
+		if !fieldKeyPattern.MatchString(field.Key) {
+			return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("field key %q must be lower case letters, digits and underscores, starting with a letter", field.Key)}
+		}
+		if keys[field.Key] {
+			return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("field key %q is used more than once", field.Key)}
+		}
+		keys[field.Key] = true
+		if field.Label == "" {
+			return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("field %q needs a label", field.Key)}
+		}
+
+		switch field.Kind {
+		case model.FeedbackFieldScore:
+			if len(field.Options) > 0 {
+				return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("score field %q cannot have options", field.Key)}
+			}
+			if field.MinScore == nil {
+				min := defaultMinScore
+				field.MinScore = &min
+			}
+			if field.MaxScore == nil {
+				max := defaultMaxScore
+				field.MaxScore = &max
+			}
+			if *field.MinScore >= *field.MaxScore {
+				return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("score field %q needs a minimum below its maximum", field.Key)}
+			}
+		case model.FeedbackFieldChoice, model.FeedbackFieldChecklist:
+			if field.MinScore != nil || field.MaxScore != nil {
+				return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("field %q has score bounds but is not a score", field.Key)}
+			}
+			minOptions := 1
+			if field.Kind == model.FeedbackFieldChoice {
+				minOptions = 2
+			}
+			if len(field.Options) < minOptions || len(field.Options) > maxFieldOptions {
+				return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("%s field %q needs between %d and %d options", field.Kind, field.Key, minOptions, maxFieldOptions)}
+			}
+			seen := make(map[string]bool, len(field.Options))
+			for j, option := range field.Options {
+				option = strings.TrimSpace(option)
+				if option == "" || seen[option] {
+					return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("options of field %q must be distinct and not blank", field.Key)}
+				}
+				seen[option] = true
+				field.Options[j] = option
+			}
+		case model.FeedbackFieldText:
+			if len(field.Options) > 0 || field.MinScore != nil || field.MaxScore != nil {
+				return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("text field %q cannot have options or score bounds", field.Key)}
+			}
+		default:
+			return &ErrInvalidFeedbackTemplate{Reason: fmt.Sprintf("field %q has unknown kind %q", field.Key, field.Kind)}
+		}
+		if field.Options == nil {
+			field.Options = []string{}
+		}
+	}
+	return nil
+}
+
+// validateRubricAnswers checks answers against the template's fields and
+// returns them as responses to store. Every required field must be
+// answered, and only with the part matching its kind.
+func validateRubricAnswers(template *model.FeedbackTemplate, answers map[string]model.FeedbackAnswer) ([]model.FeedbackResponse, error) {
+	for key := range answers {
+		if _, ok := template.FieldByKey(key); !ok {
+			return nil, &ErrInvalidFeedbackResponse{Field: key, Reason: "is not a field of the template"}
+		}
+	}
+
+	var responses []model.FeedbackResponse
+	for _, field := range template.Fields {
+		answer, answered := answers[field.Key]
+		if answered && field.Kind == model.FeedbackFieldText && answer.Text != nil {
+			text := strings.TrimSpace(*answer.Text)
+			answer.Text = &text
+			if text == "" {
+				answer.Text = nil
+			}
+		}
+		empty := answer.Score == nil && answer.Text == nil && len(answer.Selected) == 0
+		if !answered || empty {
+			if field.Required {
+				return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: "is required"}
+			}
+			continue
+		}
+
+		response := model.FeedbackResponse{FieldID: field.ID, FieldKey: field.Key}
+		switch field.Kind {
+		case model.FeedbackFieldScore:
+			if answer.Score == nil || answer.Text != nil || len(answer.Selected) > 0 {
+				return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: "takes a score only"}
+			}
+			if *answer.Score < *field.MinScore || *answer.Score > *field.MaxScore {
+				return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: fmt.Sprintf("score must be between %d and %d", *field.MinScore, *field.MaxScore)}
+			}
+			response.Score = answer.Score
+		case model.FeedbackFieldText:
+			if answer.Text == nil || answer.Score != nil || len(answer.Selected) > 0 {
+				return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: "takes text only"}
+			}
+			if utf8.RuneCountInString(*answer.Text) > maxFeedbackTextSize {
+				return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: fmt.Sprintf("must be at most %d characters", maxFeedbackTextSize)}
+			}
+			response.Text = answer.Text
+		case model.FeedbackFieldChoice, model.FeedbackFieldChecklist:
+			if answer.Score != nil || answer.Text != nil {
+				return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: "takes selected options only"}
+			}
+			if field.Kind == model.FeedbackFieldChoice && len(answer.Selected) != 1 {
+				return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: "takes exactly one option"}
+			}
+			seen := make(map[string]bool, len(answer.Selected))
+			for _, selected := range answer.Selected {
+				if seen[selected] || !containsOption(field.Options, selected) {
+					return nil, &ErrInvalidFeedbackResponse{Field: field.Key, Reason: fmt.Sprintf("%q is not an option or is selected twice", selected)}
+				}
+				seen[selected] = true
+			}
+			response.Selected = answer.Selected
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func containsOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/google/uuid"
+)
+
+func TestValidateRubricAnswers(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	strPtr := func(s string) *string { return &s }
+
+	template := &model.FeedbackTemplate{
+		ID: uuid.New(),
+		Fields: []model.FeedbackTemplateField{
+			{ID: uuid.New(), Key: "overall", Kind: model.FeedbackFieldScore, Required: true, MinScore: intPtr(1), MaxScore: intPtr(5)},
+			{ID: uuid.New(), Key: "level", Kind: model.FeedbackFieldChoice, Required: true, Options: []string{"beginner", "intermediate", "advanced"}},
+			{ID: uuid.New(), Key: "summary", Kind: model.FeedbackFieldText, Required: true},
+			{ID: uuid.New(), Key: "notes", Kind: model.FeedbackFieldText},
+			{ID: uuid.New(), Key: "topics", Kind: model.FeedbackFieldChecklist, Options: []string{"arrays", "graphs", "recursion"}},
+			{ID: uuid.New(), Key: "pace", Kind: model.FeedbackFieldChoice, Options: []string{"slow", "right", "fast"}},
+		},
+	}
+
+	// withAnswers returns the required answers with the given ones added or
+	// replaced. A zero FeedbackAnswer removes the key.
+	withAnswers := func(answers map[string]model.FeedbackAnswer) map[string]model.FeedbackAnswer {
+		merged := map[string]model.FeedbackAnswer{
+			"overall": {Score: intPtr(4)},
+			"level":   {Selected: []string{"intermediate"}},
+			"summary": {Text: strPtr("Solid session")},
+		}
+		for key, answer := range answers {
+			if reflect.DeepEqual(answer, model.FeedbackAnswer{}) {
+				delete(merged, key)
+				continue
+			}
+			merged[key] = answer
+		}
+		return merged
+	}
+
+	tests := []struct {
+		name      string
+		answers   map[string]model.FeedbackAnswer
+		wantKeys  []string
+		wantField string
+	}{
+		{
+			name:     "required fields only",
+			answers:  withAnswers(nil),
+			wantKeys: []string{"overall", "level", "summary"},
+		},
+		{
+			name: "every field",
+			answers: withAnswers(map[string]model.FeedbackAnswer{
+				"notes":  {Text: strPtr("Practice recursion")},
+				"topics": {Selected: []string{"recursion", "arrays"}},
+				"pace":   {Selected: []string{"right"}},
+			}),
+			wantKeys: []string{"overall", "level", "summary", "notes", "topics", "pace"},
+		},
+		{
+			name:     "score at the bounds",
+			answers:  withAnswers(map[string]model.FeedbackAnswer{"overall": {Score: intPtr(5)}}),
+			wantKeys: []string{"overall", "level", "summary"},
+		},
+		{
+			name:     "optional fields sent empty",
+			answers:  withAnswers(map[string]model.FeedbackAnswer{"notes": {Text: strPtr("")}, "topics": {Selected: []string{}}}),
+			wantKeys: []string{"overall", "level", "summary"},
+		},
+		{
+			name:     "whitespace-only optional text is dropped",
+			answers:  withAnswers(map[string]model.FeedbackAnswer{"notes": {Text: strPtr(" \n\t ")}}),
+			wantKeys: []string{"overall", "level", "summary"},
+		},
+		{
+			name:      "missing required score",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"overall": {}}),
+			wantField: "overall",
+		},
+		{
+			name:      "missing required choice",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"level": {Selected: []string{}}}),
+			wantField: "level",
+		},
+		{
+			name:      "whitespace-only required text",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"summary": {Text: strPtr("   ")}}),
+			wantField: "summary",
+		},
+		{
+			name:      "score below range",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"overall": {Score: intPtr(0)}}),
+			wantField: "overall",
+		},
+		{
+			name:      "score above range",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"overall": {Score: intPtr(6)}}),
+			wantField: "overall",
+		},
+		{
+			name:      "score with text",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"overall": {Score: intPtr(3), Text: strPtr("ok")}}),
+			wantField: "overall",
+		},
+		{
+			name:     "optional choice with no selection",
+			answers:  withAnswers(map[string]model.FeedbackAnswer{"pace": {Selected: []string{}}}),
+			wantKeys: []string{"overall", "level", "summary"},
+		},
+		{
+			name:      "choice with two selections",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"level": {Selected: []string{"beginner", "advanced"}}}),
+			wantField: "level",
+		},
+		{
+			name:      "choice of an unknown option",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"pace": {Selected: []string{"glacial"}}}),
+			wantField: "pace",
+		},
+		{
+			name:      "duplicate checklist option",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"topics": {Selected: []string{"graphs", "graphs"}}}),
+			wantField: "topics",
+		},
+		{
+			name:      "unknown checklist option",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"topics": {Selected: []string{"Graphs"}}}),
+			wantField: "topics",
+		},
+		{
+			name:      "checklist with text",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"topics": {Selected: []string{"graphs"}, Text: strPtr("graphs")}}),
+			wantField: "topics",
+		},
+		{
+			name:      "text with a score",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"notes": {Text: strPtr("fine"), Score: intPtr(3)}}),
+			wantField: "notes",
+		},
+		{
+			name:      "text too long",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"notes": {Text: strPtr(strings.Repeat("a", maxFeedbackTextSize+1))}}),
+			wantField: "notes",
+		},
+		{
+			name:      "unknown field key",
+			answers:   withAnswers(map[string]model.FeedbackAnswer{"punctuality": {Score: intPtr(3)}}),
+			wantField: "punctuality",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses, err := validateRubricAnswers(template, tt.answers)
+			if tt.wantField != "" {
+				var errInvalidFeedbackResponse *ErrInvalidFeedbackResponse
+				if !errors.As(err, &errInvalidFeedbackResponse) {
+					t.Fatalf("validateRubricAnswers() error = %v, want *ErrInvalidFeedbackResponse", err)
+				}
+				if errInvalidFeedbackResponse.Field != tt.wantField {
+					t.Errorf("error for field %q, want %q", errInvalidFeedbackResponse.Field, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateRubricAnswers() error = %v", err)
+			}
+			var keys []string
+			for _, response := range responses {
+				field, _ := template.FieldByKey(response.FieldKey)
+				if response.FieldID != field.ID {
+					t.Errorf("response for %q has field ID %s, want %s", response.FieldKey, response.FieldID, field.ID)
+				}
+				keys = append(keys, response.FieldKey)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("responses for %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+// TestValidateRubricAnswersTrimsText checks that text answers are stored
+// without surrounding whitespace.
+func TestValidateRubricAnswersTrimsText(t *testing.T) {
+	text := "  Great progress on recursion \n"
+	template := &model.FeedbackTemplate{
+		Fields: []model.FeedbackTemplateField{{ID: uuid.New(), Key: "summary", Kind: model.FeedbackFieldText, Required: true}},
+	}
+	responses, err := validateRubricAnswers(template, map[string]model.FeedbackAnswer{"summary": {Text: &text}})
+	if err != nil {
+		t.Fatalf("validateRubricAnswers() error = %v", err)
+	}
+	if len(responses) != 1 || responses[0].Text == nil || *responses[0].Text != "Great progress on recursion" {
+		t.Errorf("validateRubricAnswers() = %+v, want the trimmed text", responses)
+	}
+	if text != "  Great progress on recursion \n" {
+		t.Errorf("submitted text was changed to %q", text)
+	}
+}
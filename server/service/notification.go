@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+}
+
+func NewNotificationService(notificationRepo *repository.NotificationRepository) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo}
+}
+
+func (s *NotificationService) GetNotifications(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]model.Notification, int, error) {
+	offset := (page - 1) * pageSize
+	notifications, totalCount, err := s.notificationRepo.GetNotificationsForUser(ctx, userID, offset, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching notifications: %w", err)
+	}
+	if notifications == nil {
+		notifications = []model.Notification{} // Return an empty slice instead of nil
+	}
+	return notifications, totalCount, nil
+}
+
+// notify stores a message for the user. Callers pass a repository bound to
+// their transaction so the message is only kept if the change commits.
+func notify(ctx context.Context, repo *repository.NotificationRepository, userID uuid.UUID, message string) error {
+	notification := model.Notification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	if err := repo.CreateNotification(ctx, notification); err != nil {
+		return fmt.Errorf("error creating notification: %w", err)
+	}
+	return nil
+}
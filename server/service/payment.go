@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// PaymentProvider charges students through a payment processor. reference is
+// our payment intent ID, which providers use to make retried calls
+// idempotent; the provider reference returned by Authorize identifies the
+// payment in later calls.
+type PaymentProvider interface {
+	Authorize(ctx context.Context, reference string, amountCents int, currency string) (string, error)
+	Capture(ctx context.Context, providerRef string) error
+	// Refund returns a captured payment in full, or releases an
+	// authorization that was never captured.
+	Refund(ctx context.Context, providerRef string) error
+}
+
+// bookingPayments tracks the payment steps of one booking transaction.
+// Provider calls cannot be rolled back with the database, so charges are
+// captured inside the transaction, where a failure rolls the booking back and
+// frees the seat, while refunds wait until the transaction has committed.
+// settle must be called once the transaction has finished.
+type bookingPayments struct {
+	provider PaymentProvider
+	captured []model.PaymentIntent
+	failed   []model.PaymentIntent
+	refunds  []model.PaymentIntent
+}
+
+func newBookingPayments(provider PaymentProvider) *bookingPayments {
+	return &bookingPayments{provider: provider}
+}
+
+// payForSeat charges the student for a seat in the slot: the session type's
+// price if it has one, otherwise one of their session credits.
+func (p *bookingPayments) payForSeat(ctx context.Context, tx db.DbClient, slot *model.Slot, studentID uuid.UUID) error {
+	var sessionType *model.SessionType
+	if slot.SessionTypeID != nil {
+		var err error
+		sessionType, err = repository.NewSessionTypeRepository(tx).GetSessionTypeByID(ctx, *slot.SessionTypeID)
+		if err != nil {
+			return fmt.Errorf("error fetching session type: %w", err)
+		}
+	}
+	if sessionType == nil || sessionType.PriceCents == 0 {
+		return debitCredit(ctx, tx, studentID, slot.ID)
+	}
+
+	now := time.Now()
+	intent := model.PaymentIntent{
+		ID:          uuid.New(),
+		SlotID:      slot.ID,
+		StudentID:   studentID,
+		AmountCents: sessionType.PriceCents,
+		Currency:    sessionType.Currency,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	providerRef, err := p.provider.Authorize(ctx, intent.ID.String(), intent.AmountCents, intent.Currency)
+	if err != nil {
+		p.fail(intent, err)
+		return &ErrPaymentFailed{SlotID: slot.ID.String(), Stage: "authorize", Err: err}
+	}
+	intent.ProviderRef = providerRef
+
+	if err := p.provider.Capture(ctx, providerRef); err != nil {
+		// Release the hold on the student's card. Returning the error rolls
+		// the booking back, which frees the seat.
+		if voidErr := p.provider.Refund(ctx, providerRef); voidErr != nil {
+			log.Error().Err(voidErr).Str("paymentIntentId", intent.ID.String()).Msg("Failed to release payment authorization")
+		}
+		p.fail(intent, err)
+		return &ErrPaymentFailed{SlotID: slot.ID.String(), Stage: "capture", Err: err}
+	}
+
+	intent.Status = model.PaymentStatusCaptured
+	p.captured = append(p.captured, intent)
+	if err := repository.NewPaymentRepository(tx).CreateIntent(ctx, intent); err != nil {
+		return fmt.Errorf("error recording payment: %w", err)
+	}
+	return nil
+}
+
+// refundSeat gives back what the student paid for their seat in the slot,
+// whether a credit or a payment, and reports whether there was anything to
+// give back. Payments are marked refunded now and returned by settle.
+func (p *bookingPayments) refundSeat(ctx context.Context, tx db.DbClient, slotID, studentID, actorID uuid.UUID, note string) (bool, error) {
+	refunded, err := refundCredit(ctx, tx, studentID, slotID, actorID, note)
+	if err != nil {
+		return false, err
+	}
+
+	paymentRepo := repository.NewPaymentRepository(tx)
+	intent, err := paymentRepo.GetCapturedIntent(ctx, slotID, studentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return refunded, nil
+		}
+		return false, fmt.Errorf("error fetching payment: %w", err)
+	}
+	if err := paymentRepo.UpdateIntentStatus(ctx, intent.ID, model.PaymentStatusRefunded, ""); err != nil {
+		return false, fmt.Errorf("error refunding payment: %w", err)
+	}
+	p.refunds = append(p.refunds, *intent)
+	return true, nil
+}
+
+func (p *bookingPayments) fail(intent model.PaymentIntent, err error) {
+	intent.Status = model.PaymentStatusFailed
+	intent.FailureReason = err.Error()
+	p.failed = append(p.failed, intent)
+}
+
+// settle finishes the payment steps once the booking transaction is over.
+// If it committed, pending refunds are sent to the provider; if not, the
+// charges it captured are refunded. Failed attempts are recorded either way.
+// Problems are logged rather than returned: the booking outcome is already
+// decided.
+func (p *bookingPayments) settle(ctx context.Context, paymentRepo *repository.PaymentRepository, txErr error) {
+	if txErr == nil {
+		for _, intent := range p.refunds {
+			if err := p.provider.Refund(ctx, intent.ProviderRef); err != nil {
+				log.Error().Err(err).Str("paymentIntentId", intent.ID.String()).Msg("Failed to refund payment")
+				if err := paymentRepo.UpdateIntentStatus(ctx, intent.ID, model.PaymentStatusRefundFailed, err.Error()); err != nil {
+					log.Error().Err(err).Str("paymentIntentId", intent.ID.String()).Msg("Failed to record refund failure")
+				}
+			}
+		}
+	} else {
+		// The captured intents were rolled back with the booking, so they
+		// are recorded again with their final status
+		for _, intent := range p.captured {
+			intent.Status = model.PaymentStatusRefunded
+			intent.FailureReason = "booking was not completed"
+			if err := p.provider.Refund(ctx, intent.ProviderRef); err != nil {
+				log.Error().Err(err).Str("paymentIntentId", intent.ID.String()).Msg("Failed to refund payment for rolled back booking")
+				intent.Status = model.PaymentStatusRefundFailed
+				intent.FailureReason = err.Error()
+			}
+			intent.UpdatedAt = time.Now()
+			p.record(ctx, paymentRepo, intent)
+		}
+	}
+
+	for _, intent := range p.failed {
+		p.record(ctx, paymentRepo, intent)
+	}
+}
+
+func (p *bookingPayments) record(ctx context.Context, paymentRepo *repository.PaymentRepository, intent model.PaymentIntent) {
+	if err := paymentRepo.CreateIntent(ctx, intent); err != nil {
+		log.Error().Err(err).Str("paymentIntentId", intent.ID.String()).Msg("Failed to record payment")
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrence is the subset of RFC 5545 RRULE that availability rules accept:
+// FREQ=DAILY or WEEKLY with optional INTERVAL, BYDAY (plain weekday codes),
+// COUNT and UNTIL. Occurrences are calendar days; the time of day comes from
+// the availability rule's window.
+type recurrence struct {
+	freq     string
+	interval int
+	byDay    []time.Weekday
+	count    int
+	until    *time.Time
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseRRule(rule string) (*recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	r := &recurrence{interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed RRULE part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(value)
+			if r.freq != "DAILY" && r.freq != "WEEKLY" {
+				return nil, fmt.Errorf("unsupported FREQ %q, only DAILY and WEEKLY are supported", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			r.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive integer")
+			}
+			r.count = n
+		case "UNTIL":
+			until, err := parseRRuleUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := rruleWeekdays[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", code)
+				}
+				r.byDay = append(r.byDay, day)
+			}
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				return nil, fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+	if r.freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if r.count > 0 && r.until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+	return r, nil
+}
+
+func parseRRuleUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %q must be a date (YYYYMMDD) or UTC date-time (YYYYMMDDTHHMMSSZ)", value)
+}
+
+// occurrences returns the local dates (at midnight in loc) on which the rule
+// fires, starting at dtstart and stopping at the COUNT/UNTIL limit or at
+// horizon, whichever comes first.
+func (r *recurrence) occurrences(dtstart time.Time, loc *time.Location, horizon time.Time) []time.Time {
+	year, month, day := dtstart.In(loc).Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+
+	// Without BYDAY a daily rule fires every day and a weekly rule on
+	// dtstart's weekday
+	byDay := r.byDay
+	if len(byDay) == 0 {
+		switch r.freq {
+		case "DAILY":
+			byDay = []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+		default:
+			byDay = []time.Weekday{start.Weekday()}
+		}
+	}
+	onDay := make(map[time.Weekday]bool, len(byDay))
+	for _, d := range byDay {
+		onDay[d] = true
+	}
+
+	// Weeks start on Monday (WKST=MO); interval is counted from dtstart's week
+	weekOffset := (int(start.Weekday()) + 6) % 7
+	firstMonday := start.AddDate(0, 0, -weekOffset)
+
+	var dates []time.Time
+	for i := 0; ; i++ {
+		// AddDate keeps wall-clock midnight across DST changes
+		date := start.AddDate(0, 0, i)
+		if date.After(horizon) {
+			break
+		}
+		if r.until != nil && dateAfter(date, *r.until) {
+			break
+		}
+		if r.count > 0 && len(dates) >= r.count {
+			break
+		}
+		if !onDay[date.Weekday()] {
+			continue
+		}
+		switch r.freq {
+		case "DAILY":
+			if i%r.interval != 0 {
+				continue
+			}
+		case "WEEKLY":
+			week := daysBetween(firstMonday, date) / 7
+			if week%r.interval != 0 {
+				continue
+			}
+		}
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// dateAfter compares the calendar date of a local time against an UNTIL bound.
+func dateAfter(date, until time.Time) bool {
+	y1, m1, d1 := date.Date()
+	y2, m2, d2 := until.Date()
+	return time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC).After(time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC))
+}
+
+func daysBetween(from, to time.Time) int {
+	y1, m1, d1 := from.Date()
+	y2, m2, d2 := to.Date()
+	a := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)
+	b := time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// weeklyRRule builds the RRULE for "these weekdays for N weeks from startDate".
+func weeklyRRule(weekdays []time.Weekday, startDate time.Time, weeks int) (string, error) {
+	if len(weekdays) == 0 {
+		return "", fmt.Errorf("at least one weekday is required")
+	}
+	if weeks < 1 {
+		return "", fmt.Errorf("weeks must be a positive integer")
+	}
+	codes := make([]string, 0, len(weekdays))
+	for _, d := range weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return "", fmt.Errorf("weekday %d must be between 0 (Sunday) and 6 (Saturday)", d)
+		}
+		codes = append(codes, strings.ToUpper(d.String()[:2]))
+	}
+	until := startDate.AddDate(0, 0, 7*weeks-1)
+	return fmt.Sprintf("FREQ=WEEKLY;BYDAY=%s;UNTIL=%s", strings.Join(codes, ","), until.Format("20060102")), nil
+}
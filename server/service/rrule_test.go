@@ -0,0 +1,328 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	until := func(value string) *time.Time {
+		parsed, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", value, err)
+		}
+		return &parsed
+	}
+
+	tests := []struct {
+		rule    string
+		want    *recurrence
+		wantErr bool
+	}{
+		{
+			rule: "FREQ=WEEKLY;BYDAY=TU,TH",
+			want: &recurrence{freq: "WEEKLY", interval: 1, byDay: []time.Weekday{time.Tuesday, time.Thursday}},
+		},
+		{
+			rule: "RRULE:FREQ=DAILY;INTERVAL=2;COUNT=5",
+			want: &recurrence{freq: "DAILY", interval: 2, count: 5},
+		},
+		{
+			rule: "FREQ=WEEKLY;INTERVAL=3;BYDAY=FR,MO",
+			want: &recurrence{freq: "WEEKLY", interval: 3, byDay: []time.Weekday{time.Friday, time.Monday}},
+		},
+		{
+			rule: "FREQ=WEEKLY;UNTIL=20240331",
+			want: &recurrence{freq: "WEEKLY", interval: 1, until: until("20240331T000000Z")},
+		},
+		{
+			rule: "FREQ=WEEKLY;UNTIL=20240331T235959Z",
+			want: &recurrence{freq: "WEEKLY", interval: 1, until: until("20240331T235959Z")},
+		},
+		{
+			rule: "freq=daily;wkst=mo;",
+			want: &recurrence{freq: "DAILY", interval: 1},
+		},
+		{rule: "", wantErr: true},
+		{rule: "BYDAY=MO", wantErr: true},
+		{rule: "FREQ", wantErr: true},
+		{rule: "FREQ=MONTHLY", wantErr: true},
+		{rule: "FREQ=YEARLY;BYDAY=MO", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=1", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=MO;BYSETPOS=-1", wantErr: true},
+		{rule: "FREQ=WEEKLY;WKST=SU", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=-1", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20240331", wantErr: true},
+		{rule: "FREQ=DAILY;UNTIL=2024-03-31", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := parseRRule(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseRRule() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRRule() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	losAngeles := loadLocation(t, "America/Los_Angeles")
+	berlin := loadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name    string
+		rule    string
+		loc     *time.Location
+		dtstart time.Time
+		want    []string
+	}{
+		{
+			name:    "daily with interval and count",
+			rule:    "FREQ=DAILY;INTERVAL=2;COUNT=4",
+			loc:     time.UTC,
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-01", "2024-01-03", "2024-01-05", "2024-01-07"},
+		},
+		{
+			name:    "weekly defaults to the start weekday",
+			rule:    "FREQ=WEEKLY;COUNT=3",
+			loc:     time.UTC,
+			dtstart: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-03", "2024-01-10", "2024-01-17"},
+		},
+		{
+			// Weeks start on Monday, so the Monday before a Wednesday start
+			// is skipped but the rest of that week counts
+			name:    "weekly with interval",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=5",
+			loc:     time.UTC,
+			dtstart: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-05", "2024-01-15", "2024-01-19", "2024-01-29", "2024-02-02"},
+		},
+		{
+			name:    "BYDAY order does not matter",
+			rule:    "FREQ=WEEKLY;BYDAY=TH,TU;COUNT=4",
+			loc:     time.UTC,
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-02", "2024-01-04", "2024-01-09", "2024-01-11"},
+		},
+		{
+			name:    "UNTIL date is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20240104",
+			loc:     berlin,
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, berlin),
+			want:    []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04"},
+		},
+		{
+			name:    "UNTIL UTC date-time bounds by date",
+			rule:    "FREQ=DAILY;UNTIL=20240104T090000Z",
+			loc:     losAngeles,
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, losAngeles),
+			want:    []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04"},
+		},
+		{
+			name:    "UNTIL before the start",
+			rule:    "FREQ=DAILY;UNTIL=20231231",
+			loc:     time.UTC,
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:    nil,
+		},
+		{
+			name:    "COUNT counts occurrences, not days",
+			rule:    "FREQ=WEEKLY;BYDAY=SA;COUNT=2",
+			loc:     time.UTC,
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-06", "2024-01-13"},
+		},
+		{
+			name:    "start time of day is ignored",
+			rule:    "FREQ=DAILY;COUNT=2",
+			loc:     losAngeles,
+			dtstart: time.Date(2024, 1, 1, 23, 30, 0, 0, losAngeles),
+			want:    []string{"2024-01-01", "2024-01-02"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recur, err := parseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRRule(%q) error = %v", tt.rule, err)
+			}
+			horizon := tt.dtstart.Add(availabilityRuleHorizon)
+			var got []string
+			for _, date := range recur.occurrences(tt.dtstart, tt.loc, horizon) {
+				got = append(got, date.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOccurrencesHorizon checks that rules without a limit, and rules whose
+// limit lies beyond it, stop at the 12-week horizon.
+func TestOccurrencesHorizon(t *testing.T) {
+	dtstart := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	horizon := dtstart.Add(availabilityRuleHorizon)
+
+	for _, rule := range []string{"FREQ=WEEKLY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=MO;COUNT=20", "FREQ=WEEKLY;BYDAY=MO;UNTIL=20241231"} {
+		t.Run(rule, func(t *testing.T) {
+			recur, err := parseRRule(rule)
+			if err != nil {
+				t.Fatalf("parseRRule() error = %v", err)
+			}
+			dates := recur.occurrences(dtstart, time.UTC, horizon)
+			if len(dates) != 13 {
+				t.Fatalf("got %d occurrences, want 13", len(dates))
+			}
+			if last := dates[len(dates)-1].Format("2006-01-02"); last != "2024-03-25" {
+				t.Errorf("last occurrence = %s, want 2024-03-25", last)
+			}
+		})
+	}
+}
+
+// TestOccurrencesAcrossDST checks that occurrences stay at local midnight when
+// the clocks change, so slots built from them keep their wall-clock time.
+func TestOccurrencesAcrossDST(t *testing.T) {
+	for _, name := range []string{"America/Los_Angeles", "Europe/Berlin"} {
+		t.Run(name, func(t *testing.T) {
+			loc := loadLocation(t, name)
+			// Both zones change clocks in March and in October or November
+			for _, month := range []time.Month{time.March, time.October} {
+				dtstart := time.Date(2024, month, 1, 0, 0, 0, 0, loc)
+				recur, err := parseRRule("FREQ=DAILY;COUNT=40")
+				if err != nil {
+					t.Fatalf("parseRRule() error = %v", err)
+				}
+				dates := recur.occurrences(dtstart, loc, dtstart.AddDate(0, 3, 0))
+				if len(dates) != 40 {
+					t.Fatalf("got %d occurrences, want 40", len(dates))
+				}
+				offsets := map[int]bool{}
+				for i, date := range dates {
+					if date.Hour() != 0 || date.Minute() != 0 {
+						t.Errorf("occurrence %d = %s, want local midnight", i, date)
+					}
+					if want := dtstart.AddDate(0, 0, i).Format("2006-01-02"); date.Format("2006-01-02") != want {
+						t.Errorf("occurrence %d = %s, want %s", i, date.Format("2006-01-02"), want)
+					}
+					_, offset := date.Zone()
+					offsets[offset] = true
+				}
+				if len(offsets) != 2 {
+					t.Errorf("occurrences from %s span %d UTC offsets, want 2", dtstart.Format("2006-01-02"), len(offsets))
+				}
+			}
+		})
+	}
+}
+
+func TestWeeklyRRule(t *testing.T) {
+	startDate := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		weekdays []time.Weekday
+		weeks    int
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "one week",
+			weekdays: []time.Weekday{time.Monday},
+			weeks:    1,
+			want:     "FREQ=WEEKLY;BYDAY=MO;UNTIL=20240310",
+		},
+		{
+			name:     "weekdays in the order given",
+			weekdays: []time.Weekday{time.Thursday, time.Sunday, time.Tuesday},
+			weeks:    2,
+			want:     "FREQ=WEEKLY;BYDAY=TH,SU,TU;UNTIL=20240317",
+		},
+		{name: "no weekdays", weeks: 1, wantErr: true},
+		{name: "no weeks", weekdays: []time.Weekday{time.Monday}, weeks: 0, wantErr: true},
+		{name: "weekday out of range", weekdays: []time.Weekday{time.Monday, 7}, weeks: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := weeklyRRule(tt.weekdays, startDate, tt.weeks)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("weeklyRRule() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("weeklyRRule() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("weeklyRRule() = %q, want %q", got, tt.want)
+			}
+			if _, err := parseRRule(got); err != nil {
+				t.Errorf("parseRRule(%q) error = %v", got, err)
+			}
+		})
+	}
+}
+
+// TestWeeklyRRuleTuesdayThursday follows "Tue/Thu 10:00-14:00 for 8 weeks"
+// from the rule to the local session windows, across the US clock change on
+// March 10.
+func TestWeeklyRRuleTuesdayThursday(t *testing.T) {
+	loc := loadLocation(t, "America/Los_Angeles")
+	startDate := time.Date(2024, 3, 4, 0, 0, 0, 0, loc)
+
+	rule, err := weeklyRRule([]time.Weekday{time.Tuesday, time.Thursday}, startDate, 8)
+	if err != nil {
+		t.Fatalf("weeklyRRule() error = %v", err)
+	}
+	if want := "FREQ=WEEKLY;BYDAY=TU,TH;UNTIL=20240428"; rule != want {
+		t.Fatalf("weeklyRRule() = %q, want %q", rule, want)
+	}
+	recur, err := parseRRule(rule)
+	if err != nil {
+		t.Fatalf("parseRRule() error = %v", err)
+	}
+
+	dates := recur.occurrences(startDate, loc, startDate.Add(availabilityRuleHorizon))
+	if len(dates) != 16 {
+		t.Fatalf("got %d occurrences, want 16", len(dates))
+	}
+	if first, last := dates[0].Format("2006-01-02"), dates[len(dates)-1].Format("2006-01-02"); first != "2024-03-05" || last != "2024-04-25" {
+		t.Errorf("occurrences run %s to %s, want 2024-03-05 to 2024-04-25", first, last)
+	}
+	for _, date := range dates {
+		if date.Weekday() != time.Tuesday && date.Weekday() != time.Thursday {
+			t.Errorf("occurrence on %s", date.Format("Mon 2006-01-02"))
+		}
+		// Build the window the way materializing a rule does
+		y, m, d := date.Date()
+		windowStart := time.Date(y, m, d, 0, 10*60, 0, 0, loc)
+		windowEnd := time.Date(y, m, d, 0, 14*60, 0, 0, loc)
+		if windowStart.Hour() != 10 || windowEnd.Hour() != 14 || windowEnd.Sub(windowStart) != 4*time.Hour {
+			t.Errorf("window on %s = %s to %s, want 10:00 to 14:00", date.Format("2006-01-02"), windowStart, windowEnd)
+		}
+	}
+	// The first week is in PST and the rest in PDT
+	if got := dates[0].Add(10 * time.Hour).UTC().Hour(); got != 18 {
+		t.Errorf("first window starts at %d:00 UTC, want 18:00", got)
+	}
+	y, m, d := dates[2].Date()
+	if got := time.Date(y, m, d, 10, 0, 0, 0, loc).UTC().Hour(); got != 17 {
+		t.Errorf("third window starts at %d:00 UTC, want 17:00", got)
+	}
+}
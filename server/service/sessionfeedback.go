@@ -1,73 +1,281 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/cargoreligion/booking/server/infrastructure/db"
 	"github.com/cargoreligion/booking/server/model"
 	"github.com/cargoreligion/booking/server/repository"
 	"github.com/google/uuid"
 )
 
+const (
+	// Analytics cover this many weeks up to now unless a range is given
+	defaultAnalyticsWeeks = 12
+	// A trend needs a few scores before a student counts as declining
+	minDecliningSessions = 3
+	maxNotesSearchLength = 200
+)
+
 type SessionFeedbackService struct {
-	sessionFeedbackRepo *repository.SessionFeedbackRepository
-	slotRepo            *repository.SlotRepository
-	userRepo            *repository.UserRepository
+	dbc                  db.DbClient
+	sessionFeedbackRepo  *repository.SessionFeedbackRepository
+	feedbackTemplateRepo *repository.FeedbackTemplateRepository
+	slotRepo             *repository.SlotRepository
+	userRepo             *repository.UserRepository
 }
 
 func NewSessionFeedbackService(
+	dbc db.DbClient,
 	sessionFeedbackRepo *repository.SessionFeedbackRepository,
+	feedbackTemplateRepo *repository.FeedbackTemplateRepository,
 	slotRepo *repository.SlotRepository,
 	userRepo *repository.UserRepository,
 ) *SessionFeedbackService {
 	return &SessionFeedbackService{
-		sessionFeedbackRepo: sessionFeedbackRepo,
-		slotRepo:            slotRepo,
-		userRepo:            userRepo,
+		dbc:                  dbc,
+		sessionFeedbackRepo:  sessionFeedbackRepo,
+		feedbackTemplateRepo: feedbackTemplateRepo,
+		slotRepo:             slotRepo,
+		userRepo:             userRepo,
 	}
 }
 
-func (s *SessionFeedbackService) CreateSessionFeedback(coachID uuid.UUID, slotID uuid.UUID, satisfaction int, notes string) error {
+// CreateSessionFeedback records the coach's feedback on a student's session
+// once it has ended. Each session gets one feedback record; later changes go
+// through UpdateSessionFeedback. studentID may be omitted for a slot with a
+// single attendee. rubric, when given, answers one of the active feedback
+// templates.
+func (s *SessionFeedbackService) CreateSessionFeedback(ctx context.Context, coachID uuid.UUID, slotID uuid.UUID, studentID *uuid.UUID, satisfaction int, notes string, rubric *model.RubricSubmission) (*model.SessionFeedback, error) {
 	// Check if the user is a coach
-	user, err := s.userRepo.GetUserByID(coachID)
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
 	if err != nil {
-		return fmt.Errorf("error fetching user: %w", err)
+		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
 	if user.Role != model.RoleCoach {
-		return &ErrNotAuthorized{UserID: coachID.String(), Action: "create session feedback"}
+		return nil, &ErrNotAuthorized{UserID: coachID.String(), Action: "create session feedback"}
 	}
 
-	// Check if the slot is assigned to this coach
-	slot, err := s.slotRepo.GetSlotByID(slotID)
+	var feedback model.SessionFeedback
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		// Check if the slot is assigned to this coach. The slot lock also
+		// serializes feedback on its sessions.
+		slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: slotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+		if slot.CoachID != coachID {
+			return &ErrSlotNotAssignedToCoach{SlotID: slotID.String(), CoachID: coachID.String()}
+		}
+		// A cancelled slot keeps its bookings, but there was no session
+		if slot.Status == model.SlotStatusCancelled || slot.Status == model.SlotStatusOpen {
+			return &ErrSlotNotBooked{SlotID: slotID.String()}
+		}
+		if slot.EndTime.After(time.Now()) {
+			return &ErrSessionNotEnded{SlotID: slotID.String()}
+		}
+
+		// Work out which attendee the feedback is about
+		attendee, err := findAttendee(ctx, repository.NewBookingRepository(tx), slot, studentID)
+		if err != nil {
+			return err
+		}
+		if attendee.Status != model.BookingStatusConfirmed {
+			return &ErrBookingNotFound{SlotID: slotID.String(), StudentID: attendee.StudentID.String()}
+		}
+		// There is nothing to give feedback on if the session did not happen
+		if attendee.Attendance != nil && attendee.Attendance.IsNoShow() {
+			return &ErrNoShowSession{SlotID: slotID.String(), StudentID: attendee.StudentID.String(), Attendance: *attendee.Attendance}
+		}
+
+		sessionFeedbackRepo := repository.NewSessionFeedbackRepository(tx)
+		existing, err := sessionFeedbackRepo.GetSessionFeedbackForBooking(ctx, slotID, attendee.StudentID)
+		if err == nil {
+			return &ErrFeedbackExists{SlotID: slotID.String(), StudentID: attendee.StudentID.String(), FeedbackID: existing.ID.String()}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error fetching session feedback: %w", err)
+		}
+
+		// Create the session feedback
+		feedback = model.SessionFeedback{
+			ID:           uuid.New(),
+			SlotID:       slotID,
+			CoachId:      coachID,
+			StudentId:    attendee.StudentID,
+			Satisfaction: satisfaction,
+			Notes:        notes,
+			CreatedAt:    time.Now(),
+		}
+		feedbackTemplateRepo := repository.NewFeedbackTemplateRepository(tx)
+		if rubric != nil {
+			if err := applyRubric(ctx, feedbackTemplateRepo, &feedback, *rubric); err != nil {
+				return err
+			}
+		}
+		err = sessionFeedbackRepo.CreateSessionFeedback(ctx, feedback)
+		if err != nil {
+			return fmt.Errorf("error creating session feedback: %w", err)
+		}
+		if err := feedbackTemplateRepo.ReplaceResponses(ctx, feedback.ID, feedback.Responses); err != nil {
+			return fmt.Errorf("error saving feedback responses: %w", err)
+		}
+		return recordFeedbackRevision(ctx, sessionFeedbackRepo, feedback, coachID, feedback.CreatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("error fetching slot: %w", err)
+		return nil, err
 	}
-	if slot.CoachID != coachID {
-		return &ErrSlotNotAssignedToCoach{SlotID: slotID.String(), CoachID: coachID.String()}
+	return &feedback, nil
+}
+
+// UpdateSessionFeedback changes the coach's feedback, keeping the previous
+// versions in its revision history. Without a rubric the template answers
+// are left as they are.
+func (s *SessionFeedbackService) UpdateSessionFeedback(ctx context.Context, coachID, feedbackID uuid.UUID, satisfaction int, notes string, rubric *model.RubricSubmission) (*model.SessionFeedback, error) {
+	var feedback *model.SessionFeedback
+	err := s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		sessionFeedbackRepo := repository.NewSessionFeedbackRepository(tx)
+		var err error
+		feedback, err = sessionFeedbackRepo.GetSessionFeedbackByIDForUpdate(ctx, feedbackID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrFeedbackNotFound{FeedbackID: feedbackID.String()}
+			}
+			return fmt.Errorf("error fetching session feedback: %w", err)
+		}
+		// Only the coach who gave the feedback can change it
+		if feedback.CoachId != coachID {
+			return &ErrNotAuthorized{UserID: coachID.String(), Action: "update this session feedback"}
+		}
+
+		feedbackTemplateRepo := repository.NewFeedbackTemplateRepository(tx)
+		if rubric != nil {
+			if err := applyRubric(ctx, feedbackTemplateRepo, feedback, *rubric); err != nil {
+				return err
+			}
+		} else if feedback.Responses, err = feedbackTemplateRepo.GetResponsesForFeedback(ctx, []uuid.UUID{feedbackID}); err != nil {
+			return fmt.Errorf("error fetching feedback responses: %w", err)
+		}
+
+		now := time.Now()
+		feedback.Satisfaction = satisfaction
+		feedback.Notes = notes
+		feedback.UpdatedAt = &now
+		if err := sessionFeedbackRepo.UpdateSessionFeedback(ctx, *feedback); err != nil {
+			return fmt.Errorf("error updating session feedback: %w", err)
+		}
+		if rubric != nil {
+			if err := feedbackTemplateRepo.ReplaceResponses(ctx, feedbackID, feedback.Responses); err != nil {
+				return fmt.Errorf("error saving feedback responses: %w", err)
+			}
+		}
+		return recordFeedbackRevision(ctx, sessionFeedbackRepo, *feedback, coachID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+// GetFeedbackRevisions returns every version of the feedback, oldest first.
+// Only the coach who gave it can see its history.
+func (s *SessionFeedbackService) GetFeedbackRevisions(ctx context.Context, coachID, feedbackID uuid.UUID) ([]model.SessionFeedbackRevision, error) {
+	feedback, err := s.sessionFeedbackRepo.GetSessionFeedbackByID(ctx, feedbackID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &ErrFeedbackNotFound{FeedbackID: feedbackID.String()}
+		}
+		return nil, fmt.Errorf("error fetching session feedback: %w", err)
+	}
+	if feedback.CoachId != coachID {
+		return nil, &ErrNotAuthorized{UserID: coachID.String(), Action: "view this session feedback's history"}
 	}
 
-	// Create the session feedback
-	feedback := model.SessionFeedback{
+	revisions, err := s.sessionFeedbackRepo.GetRevisions(ctx, feedbackID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching session feedback revisions: %w", err)
+	}
+	if revisions == nil {
+		revisions = []model.SessionFeedbackRevision{} // Return an empty slice instead of nil
+	}
+	return revisions, nil
+}
+
+func recordFeedbackRevision(ctx context.Context, sessionFeedbackRepo *repository.SessionFeedbackRepository, feedback model.SessionFeedback, authorID uuid.UUID, at time.Time) error {
+	revision := model.SessionFeedbackRevision{
 		ID:           uuid.New(),
-		SlotID:       slotID,
-		CoachId:      coachID,
-		StudentId:    *slot.StudentID,
-		Satisfaction: satisfaction,
-		Notes:        notes,
-		CreatedAt:    time.Now(),
+		FeedbackID:   feedback.ID,
+		Satisfaction: feedback.Satisfaction,
+		Notes:        feedback.Notes,
+		AuthorID:     authorID,
+		CreatedAt:    at,
+		TemplateID:   feedback.TemplateID,
+		Responses:    feedback.Responses,
+	}
+	if err := sessionFeedbackRepo.CreateRevision(ctx, &revision); err != nil {
+		return fmt.Errorf("error recording session feedback revision: %w", err)
 	}
+	return nil
+}
 
-	err = s.sessionFeedbackRepo.CreateSessionFeedback(feedback)
+// applyRubric checks the rubric against its template and sets the feedback's
+// template and responses. New answers must use an active template, but
+// feedback already given against an archived one can still be corrected.
+func applyRubric(ctx context.Context, feedbackTemplateRepo *repository.FeedbackTemplateRepository, feedback *model.SessionFeedback, rubric model.RubricSubmission) error {
+	template, err := getFeedbackTemplate(ctx, feedbackTemplateRepo, rubric.TemplateID)
+	if err != nil {
+		return err
+	}
+	current := feedback.TemplateID != nil && *feedback.TemplateID == template.ID
+	if !template.Active && !current {
+		return &ErrFeedbackTemplateArchived{TemplateID: template.ID.String()}
+	}
+	responses, err := validateRubricAnswers(template, rubric.Answers)
 	if err != nil {
-		return fmt.Errorf("error creating session feedback: %w", err)
+		return err
+	}
+	for i := range responses {
+		responses[i].FeedbackID = feedback.ID
 	}
+	feedback.TemplateID = &template.ID
+	feedback.Responses = responses
+	return nil
+}
 
+// attachResponses fills in the template answers of each feedback.
+func (s *SessionFeedbackService) attachResponses(ctx context.Context, feedbacks []model.SessionFeedback) error {
+	if len(feedbacks) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(feedbacks))
+	for i, feedback := range feedbacks {
+		ids[i] = feedback.ID
+	}
+	responses, err := s.feedbackTemplateRepo.GetResponsesForFeedback(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("error fetching feedback responses: %w", err)
+	}
+	for i := range feedbacks {
+		for _, response := range responses {
+			if response.FeedbackID == feedbacks[i].ID {
+				feedbacks[i].Responses = append(feedbacks[i].Responses, response)
+			}
+		}
+	}
 	return nil
 }
 
-func (s *SessionFeedbackService) GetPastSessionFeedbacks(coachID uuid.UUID) ([]model.SessionFeedback, error) {
+func (s *SessionFeedbackService) GetPastSessionFeedbacks(ctx context.Context, coachID uuid.UUID) ([]model.SessionFeedback, error) {
 	// Check if the user is a coach
-	user, err := s.userRepo.GetUserByID(coachID)
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
@@ -76,17 +284,20 @@ func (s *SessionFeedbackService) GetPastSessionFeedbacks(coachID uuid.UUID) ([]m
 	}
 
 	// Fetch the session feedback for this coach
-	feedbacks, err := s.sessionFeedbackRepo.GetPastSessionFeedback(coachID)
+	feedbacks, err := s.sessionFeedbackRepo.GetPastSessionFeedback(ctx, coachID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching session feedback: %w", err)
 	}
+	if err := s.attachResponses(ctx, feedbacks); err != nil {
+		return nil, err
+	}
 
 	return feedbacks, nil
 }
 
-func (s *SessionFeedbackService) GetStudentsWithSessionsByCoach(coachID uuid.UUID) ([]model.User, error) {
+func (s *SessionFeedbackService) GetStudentsWithSessionsByCoach(ctx context.Context, coachID uuid.UUID) ([]model.User, error) {
 	// Check if the user is a coach
-	user, err := s.userRepo.GetUserByID(coachID)
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
@@ -95,7 +306,7 @@ func (s *SessionFeedbackService) GetStudentsWithSessionsByCoach(coachID uuid.UUI
 	}
 
 	// Fetch the students with sessions for this coach
-	students, err := s.sessionFeedbackRepo.GetStudentsWithSessionsByCoach(coachID)
+	students, err := s.sessionFeedbackRepo.GetStudentsWithSessionsByCoach(ctx, coachID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching students with sessions: %w", err)
 	}
@@ -103,9 +314,9 @@ func (s *SessionFeedbackService) GetStudentsWithSessionsByCoach(coachID uuid.UUI
 	return students, nil
 }
 
-func (s *SessionFeedbackService) GetSessionsForStudent(studentID, coachID uuid.UUID) ([]model.SessionFeedback, error) {
+func (s *SessionFeedbackService) GetSessionsForStudent(ctx context.Context, studentID, coachID uuid.UUID) ([]model.SessionFeedback, error) {
 	// Check if the user is a coach
-	user, err := s.userRepo.GetUserByID(coachID)
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
@@ -113,9 +324,133 @@ func (s *SessionFeedbackService) GetSessionsForStudent(studentID, coachID uuid.U
 		return nil, &ErrNotAuthorized{UserID: coachID.String(), Action: "retrieve sessions for student"}
 	}
 	// Fetch the sessions for this student and coach
-	sessions, err := s.sessionFeedbackRepo.GetSessionsForStudent(studentID, coachID)
+	sessions, err := s.sessionFeedbackRepo.GetSessionsForStudent(ctx, studentID, coachID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching sessions for student: %w", err)
 	}
+	if err := s.attachResponses(ctx, sessions); err != nil {
+		return nil, err
+	}
 	return sessions, nil
 }
+
+// SearchSessionNotes finds the coach's own session feedback whose notes
+// match search. search takes web-search syntax: quoted phrases, "or", and a
+// leading "-" to exclude a word.
+func (s *SessionFeedbackService) SearchSessionNotes(ctx context.Context, coachID uuid.UUID, search string, page, pageSize int) ([]model.FeedbackSearchResult, int, error) {
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return nil, 0, &ErrNotAuthorized{UserID: coachID.String(), Action: "search session notes"}
+	}
+
+	search = strings.TrimSpace(search)
+	if search == "" {
+		return nil, 0, &ErrInvalidSearchQuery{Reason: "search text is required"}
+	}
+	if utf8.RuneCountInString(search) > maxNotesSearchLength {
+		return nil, 0, &ErrInvalidSearchQuery{Reason: fmt.Sprintf("search text cannot be longer than %d characters", maxNotesSearchLength)}
+	}
+
+	offset := (page - 1) * pageSize
+	results, totalCount, err := s.sessionFeedbackRepo.SearchNotes(ctx, coachID, search, offset, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error searching session notes: %w", err)
+	}
+	if results == nil {
+		results = []model.FeedbackSearchResult{} // Return an empty slice instead of nil
+	}
+	return results, totalCount, nil
+}
+
+// GetSatisfactionAnalytics summarizes satisfaction with sessions in the
+// filter's range, which defaults to the last twelve weeks. Program leads see
+// every coach; coaches see only their own sessions.
+func (s *SessionFeedbackService) GetSatisfactionAnalytics(ctx context.Context, userID uuid.UUID, filter model.SatisfactionAnalyticsFilter) (*model.SatisfactionAnalytics, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if err := scopeAnalyticsFilter(user, &filter); err != nil {
+		return nil, err
+	}
+
+	counts, err := s.sessionFeedbackRepo.GetSatisfactionDistribution(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching satisfaction distribution: %w", err)
+	}
+	coaches, err := s.sessionFeedbackRepo.GetWeeklySatisfaction(ctx, filter, false)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching weekly satisfaction by coach: %w", err)
+	}
+	students, err := s.sessionFeedbackRepo.GetWeeklySatisfaction(ctx, filter, true)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching weekly satisfaction by student: %w", err)
+	}
+	declining, err := s.sessionFeedbackRepo.GetDecliningStudents(ctx, filter, minDecliningSessions)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching declining students: %w", err)
+	}
+
+	analytics := model.SatisfactionAnalytics{
+		From:              filter.From,
+		To:                filter.To,
+		Distribution:      make(map[int]int, 5),
+		Coaches:           coaches,
+		Students:          students,
+		DecliningStudents: declining,
+	}
+	for score := 1; score <= 5; score++ {
+		analytics.Distribution[score] = 0
+	}
+	total := 0
+	for _, c := range counts {
+		analytics.Distribution[c.Score] = c.Count
+		analytics.Sessions += c.Count
+		total += c.Score * c.Count
+	}
+	if analytics.Sessions > 0 {
+		average := float64(total) / float64(analytics.Sessions)
+		analytics.Average = &average
+	}
+	// Return empty slices instead of nil
+	if analytics.Coaches == nil {
+		analytics.Coaches = []model.WeeklySatisfaction{}
+	}
+	if analytics.Students == nil {
+		analytics.Students = []model.WeeklySatisfaction{}
+	}
+	if analytics.DecliningStudents == nil {
+		analytics.DecliningStudents = []model.DecliningStudent{}
+	}
+	return &analytics, nil
+}
+
+// scopeAnalyticsFilter limits the filter to what the user may see and fills
+// in the default range. Program leads see every coach; coaches see only
+// their own sessions.
+func scopeAnalyticsFilter(user *model.User, filter *model.SatisfactionAnalyticsFilter) error {
+	switch user.Role {
+	case model.RoleProgramLead:
+	case model.RoleCoach:
+		if filter.CoachID != nil && *filter.CoachID != user.ID {
+			return &ErrNotAuthorized{UserID: user.ID.String(), Action: "view another coach's analytics"}
+		}
+		filter.CoachID = &user.ID
+	default:
+		return &ErrNotAuthorized{UserID: user.ID.String(), Action: "view feedback analytics"}
+	}
+
+	if filter.To.IsZero() {
+		filter.To = time.Now()
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -7*defaultAnalyticsWeeks)
+	}
+	if !filter.To.After(filter.From) {
+		return &ErrInvalidAnalyticsFilter{Reason: "to must be after from"}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+const maxRatingCommentsLength = 2000
+
+type SessionRatingService struct {
+	dbc               db.DbClient
+	sessionRatingRepo *repository.SessionRatingRepository
+	userRepo          *repository.UserRepository
+}
+
+func NewSessionRatingService(dbc db.DbClient, sessionRatingRepo *repository.SessionRatingRepository, userRepo *repository.UserRepository) *SessionRatingService {
+	return &SessionRatingService{
+		dbc:               dbc,
+		sessionRatingRepo: sessionRatingRepo,
+		userRepo:          userRepo,
+	}
+}
+
+// RateSession records the student's rating of a session they were booked
+// into, once it has ended. Each session can be rated once.
+func (s *SessionRatingService) RateSession(ctx context.Context, studentID, slotID uuid.UUID, stars int, comments string, useful bool) (*model.SessionRating, error) {
+	user, err := s.userRepo.GetUserByID(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleStudent {
+		return nil, &ErrNotStudent{UserID: studentID.String()}
+	}
+	comments = strings.TrimSpace(comments)
+	if stars < model.MinSessionRating || stars > model.MaxSessionRating {
+		return nil, &ErrInvalidSessionRating{Reason: fmt.Sprintf("rating must be between %d and %d", model.MinSessionRating, model.MaxSessionRating)}
+	}
+	if utf8.RuneCountInString(comments) > maxRatingCommentsLength {
+		return nil, &ErrInvalidSessionRating{Reason: fmt.Sprintf("comments must be at most %d characters", maxRatingCommentsLength)}
+	}
+
+	var rating model.SessionRating
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: slotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+		// A cancelled slot keeps its bookings, but there was no session
+		if slot.Status == model.SlotStatusCancelled || slot.Status == model.SlotStatusOpen {
+			return &ErrSlotNotBooked{SlotID: slotID.String()}
+		}
+
+		booking, err := repository.NewBookingRepository(tx).GetBooking(ctx, slotID, studentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrBookingNotFound{SlotID: slotID.String(), StudentID: studentID.String()}
+			}
+			return fmt.Errorf("error fetching booking: %w", err)
+		}
+		if booking.Status != model.BookingStatusConfirmed {
+			return &ErrBookingNotFound{SlotID: slotID.String(), StudentID: studentID.String()}
+		}
+		if slot.EndTime.After(time.Now()) {
+			return &ErrSessionNotEnded{SlotID: slotID.String()}
+		}
+		if booking.Attendance != nil && booking.Attendance.IsNoShow() {
+			return &ErrNoShowSession{SlotID: slotID.String(), StudentID: studentID.String(), Attendance: *booking.Attendance}
+		}
+
+		sessionRatingRepo := repository.NewSessionRatingRepository(tx)
+		if _, err := sessionRatingRepo.GetRatingForBooking(ctx, slotID, studentID); err == nil {
+			return &ErrSessionAlreadyRated{SlotID: slotID.String()}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error fetching session rating: %w", err)
+		}
+
+		rating = model.SessionRating{
+			ID:        uuid.New(),
+			SlotID:    slotID,
+			CoachID:   slot.CoachID,
+			StudentID: studentID,
+			Rating:    stars,
+			Comments:  comments,
+			Useful:    useful,
+			CreatedAt: time.Now(),
+		}
+		if err := sessionRatingRepo.CreateRating(ctx, rating); err != nil {
+			return fmt.Errorf("error creating session rating: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// GetRatingsByStudent returns the ratings the student has given, newest
+// first.
+func (s *SessionRatingService) GetRatingsByStudent(ctx context.Context, studentID uuid.UUID, page, pageSize int) ([]model.SessionRating, int, error) {
+	user, err := s.userRepo.GetUserByID(ctx, studentID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleStudent {
+		return nil, 0, &ErrNotStudent{UserID: studentID.String()}
+	}
+
+	offset := (page - 1) * pageSize
+	ratings, totalCount, err := s.sessionRatingRepo.GetRatingsByStudent(ctx, studentID, offset, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching session ratings: %w", err)
+	}
+	if ratings == nil {
+		ratings = []model.SessionRating{} // Return an empty slice instead of nil
+	}
+	return ratings, totalCount, nil
+}
+
+// GetCoachRatingSummary aggregates the ratings students have given the
+// coach. Anyone can see it.
+func (s *SessionRatingService) GetCoachRatingSummary(ctx context.Context, coachID uuid.UUID) (*model.CoachRatingSummary, error) {
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return nil, &ErrNotCoach{UserID: coachID.String()}
+	}
+
+	counts, err := s.sessionRatingRepo.GetCoachRatingCounts(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching coach ratings: %w", err)
+	}
+
+	summary := model.CoachRatingSummary{
+		CoachID:      coachID,
+		Distribution: make(map[int]int, model.MaxSessionRating),
+	}
+	for stars := model.MinSessionRating; stars <= model.MaxSessionRating; stars++ {
+		summary.Distribution[stars] = 0
+	}
+	totalStars := 0
+	for _, c := range counts {
+		summary.Distribution[c.Rating] = c.Count
+		summary.Count += c.Count
+		summary.UsefulCount += c.UsefulCount
+		totalStars += c.Rating * c.Count
+	}
+	if summary.Count > 0 {
+		average := float64(totalStars) / float64(summary.Count)
+		summary.Average = &average
+	}
+	return &summary, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+// Longest session a coach can offer; matches the length of the working day.
+const maxSessionTypeMinutes = 8 * 60
+
+// Currency of session types created without one.
+const defaultSessionTypeCurrency = "USD"
+
+type SessionTypeService struct {
+	sessionTypeRepo *repository.SessionTypeRepository
+	userRepo        *repository.UserRepository
+}
+
+func NewSessionTypeService(sessionTypeRepo *repository.SessionTypeRepository, userRepo *repository.UserRepository) *SessionTypeService {
+	return &SessionTypeService{
+		sessionTypeRepo: sessionTypeRepo,
+		userRepo:        userRepo,
+	}
+}
+
+// CreateSessionType adds a session type to the coach's catalog. A priceCents
+// of zero makes sessions of this type cost a session credit instead of a
+// payment.
+func (s *SessionTypeService) CreateSessionType(ctx context.Context, coachID uuid.UUID, name string, durationMinutes int, description string, priceCents int, currency string) (*model.SessionType, error) {
+	// Check if the user is a coach
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return nil, &ErrNotAuthorized{UserID: coachID.String(), Action: "create session types"}
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &ErrInvalidSessionType{Reason: "name is required"}
+	}
+	if durationMinutes <= 0 || durationMinutes > maxSessionTypeMinutes {
+		return nil, &ErrInvalidSessionType{Reason: fmt.Sprintf("duration must be between 15 and %d minutes", maxSessionTypeMinutes)}
+	}
+	if durationMinutes%15 != 0 {
+		return nil, &ErrInvalidSessionType{Reason: "duration must be a multiple of 15 minutes"}
+	}
+	if priceCents < 0 {
+		return nil, &ErrInvalidSessionType{Reason: "price cannot be negative"}
+	}
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = defaultSessionTypeCurrency
+	}
+	if !isCurrencyCode(currency) {
+		return nil, &ErrInvalidSessionType{Reason: "currency must be a three-letter ISO 4217 code"}
+	}
+
+	sessionType := model.SessionType{
+		ID:              uuid.New(),
+		CoachID:         coachID,
+		Name:            name,
+		DurationMinutes: durationMinutes,
+		Description:     strings.TrimSpace(description),
+		PriceCents:      priceCents,
+		Currency:        currency,
+	}
+	if err := s.sessionTypeRepo.CreateSessionType(ctx, sessionType); err != nil {
+		return nil, fmt.Errorf("error creating session type: %w", err)
+	}
+
+	return &sessionType, nil
+}
+
+func (s *SessionTypeService) GetSessionTypesForCoach(ctx context.Context, coachID uuid.UUID) ([]model.SessionType, error) {
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return nil, &ErrNotCoach{UserID: coachID.String()}
+	}
+
+	sessionTypes, err := s.sessionTypeRepo.GetSessionTypesByCoach(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching session types: %w", err)
+	}
+	if sessionTypes == nil {
+		sessionTypes = []model.SessionType{} // Return an empty slice instead of nil
+	}
+	return sessionTypes, nil
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// getCoachSessionType fetches a session type from the coach's own catalog.
+// Session types belonging to other coaches are reported as not found.
+func getCoachSessionType(ctx context.Context, repo *repository.SessionTypeRepository, coachID, sessionTypeID uuid.UUID) (*model.SessionType, error) {
+	sessionType, err := repo.GetSessionTypeByID(ctx, sessionTypeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &ErrSessionTypeNotFound{SessionTypeID: sessionTypeID.String()}
+		}
+		return nil, fmt.Errorf("error fetching session type: %w", err)
+	}
+	if sessionType.CoachID != coachID {
+		return nil, &ErrSessionTypeNotFound{SessionTypeID: sessionTypeID.String()}
+	}
+	return sessionType, nil
+}
@@ -1,95 +1,404 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/cargoreligion/booking/server/infrastructure/db"
 	"github.com/cargoreligion/booking/server/model"
 	"github.com/cargoreligion/booking/server/repository"
 	"github.com/google/uuid"
 )
 
+// Length of a slot created without a session type or an explicit end time.
+const defaultSlotDuration = 2 * time.Hour
+
+// Largest group a single slot can hold.
+const maxSlotCapacity = 50
+
 type SlotService struct {
-	slotRepo *repository.SlotRepository
-	userRepo *repository.UserRepository
+	dbc                db.DbClient
+	slotRepo           *repository.SlotRepository
+	bookingRepo        *repository.BookingRepository
+	userRepo           *repository.UserRepository
+	sessionTypeRepo    *repository.SessionTypeRepository
+	coachProfileRepo   *repository.CoachProfileRepository
+	bookingHistoryRepo *repository.BookingHistoryRepository
+	paymentRepo        *repository.PaymentRepository
+	paymentProvider    PaymentProvider
 }
 
-func NewSlotService(slotRepo *repository.SlotRepository, userRepo *repository.UserRepository) *SlotService {
+func NewSlotService(
+	dbc db.DbClient,
+	slotRepo *repository.SlotRepository,
+	bookingRepo *repository.BookingRepository,
+	userRepo *repository.UserRepository,
+	sessionTypeRepo *repository.SessionTypeRepository,
+	coachProfileRepo *repository.CoachProfileRepository,
+	bookingHistoryRepo *repository.BookingHistoryRepository,
+	paymentRepo *repository.PaymentRepository,
+	paymentProvider PaymentProvider,
+) *SlotService {
 	return &SlotService{
-		slotRepo: slotRepo,
-		userRepo: userRepo,
+		dbc:                dbc,
+		slotRepo:           slotRepo,
+		bookingRepo:        bookingRepo,
+		userRepo:           userRepo,
+		sessionTypeRepo:    sessionTypeRepo,
+		coachProfileRepo:   coachProfileRepo,
+		bookingHistoryRepo: bookingHistoryRepo,
+		paymentRepo:        paymentRepo,
+		paymentProvider:    paymentProvider,
 	}
 }
 
-func (s *SlotService) CreateSlot(coachID uuid.UUID, startTime time.Time) (uuid.UUID, error) {
-	estLoc, _ := time.LoadLocation("America/New_York")
-	localStartTime := startTime.In(estLoc)
-	// Check if the slot is in the past
-	now := time.Now()
-	if localStartTime.Before(now) {
-		return uuid.Nil, fmt.Errorf("cannot create a slot in the past")
+// CreateSlot creates an open slot for the coach. The slot length comes from
+// sessionTypeID when given, from endTime when given, and otherwise defaults to
+// two hours. Passing both a session type and an end time is an error. A
+// capacity of zero makes a one-on-one slot.
+func (s *SlotService) CreateSlot(ctx context.Context, coachID uuid.UUID, startTime time.Time, sessionTypeID *uuid.UUID, endTime *time.Time, capacity int) (uuid.UUID, error) {
+	// Fetch the user
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error fetching user: %w", err)
 	}
 
-	// Check if the start time is at a 15-minute increment
-	if localStartTime.Minute()%15 != 0 || localStartTime.Second() != 0 || localStartTime.Nanosecond() != 0 {
-		return uuid.Nil, fmt.Errorf("slot must start at 15-minute increments (e.g., 9:00, 9:15, 9:30, 9:45)")
+	// Check if the user is a coach
+	if user.Role != model.RoleCoach {
+		return uuid.Nil, &ErrNotAuthorized{UserID: coachID.String(), Action: "create slots"}
 	}
 
-	// Check if the slot is between 9 AM and 5 PM
-	startHour := localStartTime.Hour()
-	if startHour < 9 || startHour >= 17 {
-		return uuid.Nil, fmt.Errorf("slots must be between 9 AM and 5 PM")
+	localStartTime, localEndTime, err := s.prepareSlotTimes(ctx, coachID, startTime, sessionTypeID, endTime)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	capacity, err = resolveSlotCapacity(capacity)
+	if err != nil {
+		return uuid.Nil, err
 	}
 
-	// Calculate end time (2 hours after start time)
-	endTime := localStartTime.Add(2 * time.Hour)
+	// Create the slot
+	slot := model.Slot{
+		ID:            uuid.New(),
+		CoachID:       coachID,
+		SessionTypeID: sessionTypeID,
+		StartTime:     localStartTime.UTC(),
+		EndTime:       localEndTime.UTC(),
+		Booked:        false,
+		Status:        model.SlotStatusOpen,
+		Capacity:      capacity,
+	}
+
+	var id uuid.UUID
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+
+		// Serialize slot creation per coach so the overlap check holds
+		if err := repository.NewUserRepository(tx).LockUser(ctx, coachID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		// Check for overlapping slots
+		hasOverlap, err := slotRepo.HasOverlappingSlot(ctx, coachID, localStartTime, localEndTime)
+		if err != nil {
+			return fmt.Errorf("error checking for overlapping slots: %w", err)
+		}
+		if hasOverlap {
+			return &ErrOverlappingSlot{CoachID: coachID.String()}
+		}
+
+		// Save the slot
+		id, err = slotRepo.CreateSlot(ctx, slot)
+		if err != nil {
+			return fmt.Errorf("error creating slot: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
 
-	// Check if the end time is after 5 PM
-	if endTime.Hour() >= 17 && endTime.Minute() > 0 {
-		return uuid.Nil, fmt.Errorf("slots must end by 5 PM")
+// UpdateSlot moves an open slot, re-running the CreateSlot validations and
+// overlap checks. Booked slots must go through CoachCancelBooking.
+func (s *SlotService) UpdateSlot(ctx context.Context, coachID, slotID uuid.UUID, startTime time.Time, sessionTypeID *uuid.UUID, endTime *time.Time, capacity int) error {
+	user, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleCoach {
+		return &ErrNotAuthorized{UserID: coachID.String(), Action: "update slots"}
 	}
 
-	// Fetch the user
-	user, err := s.userRepo.GetUserByID(coachID)
+	localStartTime, localEndTime, err := s.prepareSlotTimes(ctx, coachID, startTime, sessionTypeID, endTime)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("error fetching user: %w", err)
+		return err
 	}
+	capacity, err = resolveSlotCapacity(capacity)
+	if err != nil {
+		return err
+	}
+
+	return s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+
+		if err := repository.NewUserRepository(tx).LockUser(ctx, coachID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		slot, err := s.getCoachSlotForChange(ctx, slotRepo, coachID, slotID)
+		if err != nil {
+			return err
+		}
+		switch slot.Status {
+		case model.SlotStatusOpen:
+			if err := checkSlotUnoccupied(ctx, tx, slot); err != nil {
+				return err
+			}
+		case model.SlotStatusBooked:
+			return &ErrSlotBooked{SlotID: slotID.String()}
+		default:
+			return &ErrSlotNotOpen{SlotID: slotID.String(), Status: slot.Status}
+		}
+
+		// Check for overlapping slots, ignoring the slot being moved
+		hasOverlap, err := slotRepo.HasOverlappingSlotExcluding(ctx, coachID, localStartTime, localEndTime, slotID)
+		if err != nil {
+			return fmt.Errorf("error checking for overlapping slots: %w", err)
+		}
+		if hasOverlap {
+			return &ErrOverlappingSlot{CoachID: coachID.String()}
+		}
+
+		slot.StartTime = localStartTime.UTC()
+		slot.EndTime = localEndTime.UTC()
+		slot.SessionTypeID = sessionTypeID
+		slot.Capacity = capacity
+		if err := slotRepo.RescheduleSlot(ctx, *slot); err != nil {
+			return fmt.Errorf("error updating slot: %w", err)
+		}
+		return nil
+	})
+}
 
-	// Check if the user is a coach
-	if user.Role != model.RoleCoach {
-		return uuid.Nil, fmt.Errorf("only coaches can create slots")
+// DeleteSlot removes an open or cancelled slot that has not started yet.
+// Booked slots must go through CoachCancelBooking.
+func (s *SlotService) DeleteSlot(ctx context.Context, coachID, slotID uuid.UUID) error {
+	return s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+
+		slot, err := s.getCoachSlotForChange(ctx, slotRepo, coachID, slotID)
+		if err != nil {
+			return err
+		}
+		switch slot.Status {
+		case model.SlotStatusOpen:
+			if err := checkSlotUnoccupied(ctx, tx, slot); err != nil {
+				return err
+			}
+		case model.SlotStatusCancelled:
+		case model.SlotStatusBooked:
+			return &ErrSlotBooked{SlotID: slotID.String()}
+		default:
+			return &ErrSlotNotOpen{SlotID: slotID.String(), Status: slot.Status}
+		}
+
+		if err := slotRepo.DeleteSlot(ctx, slotID); err != nil {
+			return fmt.Errorf("error deleting slot: %w", err)
+		}
+		return nil
+	})
+}
+
+// checkSlotUnoccupied rejects changes to an open slot that still has seats
+// held, in checkout or awaiting approval.
+func checkSlotUnoccupied(ctx context.Context, tx db.DbClient, slot *model.Slot) error {
+	bookings, err := repository.NewBookingRepository(tx).GetBookingsForSlots(ctx, []uuid.UUID{slot.ID})
+	if err != nil {
+		return fmt.Errorf("error fetching bookings: %w", err)
+	}
+	if len(bookings) > 0 {
+		return &ErrSlotBooked{SlotID: slot.ID.String()}
 	}
+	return nil
+}
+
+// CoachCancelBooking pulls a booked session: every booking is cancelled with
+// the coach's reason recorded in the booking history, each attendee is
+// notified, and the slot is marked cancelled.
+func (s *SlotService) CoachCancelBooking(ctx context.Context, coachID, slotID uuid.UUID, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return &ErrReasonRequired{Action: "cancel a booked session"}
+	}
+
+	payments := newBookingPayments(s.paymentProvider)
+	err := s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+
+		slot, err := s.getCoachSlotForChange(ctx, slotRepo, coachID, slotID)
+		if err != nil {
+			return err
+		}
+		if slot.Status != model.SlotStatusBooked {
+			return &ErrSlotNotBooked{SlotID: slotID.String()}
+		}
+		if err := checkSlotTransition(slot, model.SlotStatusCancelled); err != nil {
+			return err
+		}
+
+		attendees, err := repository.NewBookingRepository(tx).GetBookingsForSlots(ctx, []uuid.UUID{slotID})
+		if err != nil {
+			return fmt.Errorf("error fetching bookings: %w", err)
+		}
+		historyRepo := repository.NewBookingHistoryRepository(tx)
+		notificationRepo := repository.NewNotificationRepository(tx)
+		message := fmt.Sprintf("Your session on %s was cancelled by your coach: %s",
+			slot.StartTime.UTC().Format("Mon Jan 2 2006 15:04 MST"), reason)
+		for _, attendee := range attendees {
+			entry := model.BookingHistory{
+				ID:        uuid.New(),
+				SlotID:    slot.ID,
+				CoachID:   coachID,
+				StudentID: attendee.StudentID,
+				Event:     model.BookingEventCoachCancelled,
+				ActorID:   coachID,
+				Reason:    reason,
+				StartTime: slot.StartTime,
+				EndTime:   slot.EndTime,
+				CreatedAt: time.Now(),
+			}
+			if err := historyRepo.CreateBookingHistory(ctx, entry); err != nil {
+				return fmt.Errorf("error recording cancellation: %w", err)
+			}
+			if _, err := payments.refundSeat(ctx, tx, slot.ID, attendee.StudentID, coachID, "Session cancelled by coach"); err != nil {
+				return err
+			}
+			if err := notify(ctx, notificationRepo, attendee.StudentID, message); err != nil {
+				return err
+			}
+		}
+
+		if err := slotRepo.CancelSlot(ctx, slotID); err != nil {
+			return fmt.Errorf("error cancelling slot: %w", err)
+		}
+		return nil
+	})
+	payments.settle(ctx, s.paymentRepo, err)
+	return err
+}
 
-	// Check for overlapping slots
-	hasOverlap, err := s.slotRepo.HasOverlappingSlot(coachID, localStartTime, endTime)
+// getCoachSlotForChange locks a slot the coach owns and checks it has not
+// started yet.
+func (s *SlotService) getCoachSlotForChange(ctx context.Context, slotRepo *repository.SlotRepository, coachID, slotID uuid.UUID) (*model.Slot, error) {
+	slot, err := slotRepo.GetSlotByIDForUpdate(ctx, slotID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("error checking for overlapping slots: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &ErrSlotNotFound{SlotID: slotID.String()}
+		}
+		return nil, fmt.Errorf("error fetching slot: %w", err)
 	}
-	if hasOverlap {
-		return uuid.Nil, fmt.Errorf("slot overlaps with an existing slot")
+	if slot.CoachID != coachID {
+		return nil, &ErrSlotNotAssignedToCoach{SlotID: slotID.String(), CoachID: coachID.String()}
+	}
+	if !slot.StartTime.After(time.Now()) {
+		return nil, &ErrSlotStarted{SlotID: slotID.String()}
 	}
+	return slot, nil
+}
 
-	// Create the slot
-	slot := model.Slot{
-		ID:        uuid.New(),
-		CoachID:   coachID,
-		StartTime: localStartTime.UTC(),
-		EndTime:   endTime.UTC(),
-		Booked:    false,
+// prepareSlotTimes resolves a requested slot into start and end times in the
+// coach's time zone and checks them with validateSlotTimes.
+func (s *SlotService) prepareSlotTimes(ctx context.Context, coachID uuid.UUID, startTime time.Time, sessionTypeID *uuid.UUID, endTime *time.Time) (time.Time, time.Time, error) {
+	// Slots are validated against the coach's own time zone and schedule
+	profile, err := getCoachProfileOrDefault(ctx, s.coachProfileRepo, coachID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	loc, err := loadCoachLocation(profile.TimeZone)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
+	localStartTime := startTime.In(loc)
 
-	// Save the slot
-	id, err := s.slotRepo.CreateSlot(slot)
+	// Work out how long the slot runs
+	localEndTime, err := s.resolveSlotEnd(ctx, coachID, localStartTime, sessionTypeID, endTime)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("error creating slot: %w", err)
+		return time.Time{}, time.Time{}, err
 	}
 
-	return id, nil
+	if err := validateSlotTimes(profile, loc, localStartTime, localEndTime); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return localStartTime, localEndTime, nil
 }
 
-func (s *SlotService) GetUpcomingSlots(userID uuid.UUID, page, pageSize int) ([]model.Slot, int, error) {
+// validateSlotTimes checks a slot's times against the rules every slot must
+// satisfy: it starts in the future on a 15-minute increment in the coach's
+// time zone, and it fits inside one of the coach's working windows.
+func validateSlotTimes(profile *model.CoachProfile, loc *time.Location, startTime, endTime time.Time) error {
+	localStartTime := startTime.In(loc)
+
+	// Check if the slot is in the past
+	if localStartTime.Before(time.Now()) {
+		return &ErrSlotStartInPast{StartTime: localStartTime}
+	}
+
+	// Check if the start time is at a 15-minute increment
+	if localStartTime.Minute()%15 != 0 || localStartTime.Second() != 0 || localStartTime.Nanosecond() != 0 {
+		return &ErrSlotStartIncrement{StartTime: localStartTime}
+	}
+
+	// Check if the slot falls inside the coach's working hours
+	if !withinWorkingHours(profile.WorkingHours, loc, startTime, endTime) {
+		return &ErrOutsideWorkingHours{
+			CoachID:   profile.CoachID.String(),
+			StartTime: localStartTime,
+			EndTime:   endTime.In(loc),
+		}
+	}
+
+	return nil
+}
+
+// resolveSlotEnd returns the end time of a slot starting at startTime, taken
+// from the coach's session type, the explicit end time, or the default length.
+func (s *SlotService) resolveSlotEnd(ctx context.Context, coachID uuid.UUID, startTime time.Time, sessionTypeID *uuid.UUID, endTime *time.Time) (time.Time, error) {
+	if sessionTypeID != nil && endTime != nil {
+		return time.Time{}, &ErrInvalidSlotDuration{Reason: "specify either a session type or an end time, not both"}
+	}
+
+	if sessionTypeID != nil {
+		sessionType, err := getCoachSessionType(ctx, s.sessionTypeRepo, coachID, *sessionTypeID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return startTime.Add(sessionType.Duration()), nil
+	}
+
+	if endTime != nil {
+		localEndTime := endTime.In(startTime.Location())
+		if !localEndTime.After(startTime) {
+			return time.Time{}, &ErrInvalidSlotDuration{Reason: "end time must be after start time"}
+		}
+		if localEndTime.Minute()%15 != 0 || localEndTime.Second() != 0 || localEndTime.Nanosecond() != 0 {
+			return time.Time{}, &ErrInvalidSlotDuration{Reason: "end time must fall on a 15-minute increment"}
+		}
+		return localEndTime, nil
+	}
+
+	return startTime.Add(defaultSlotDuration), nil
+}
+
+func (s *SlotService) GetUpcomingSlots(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]model.Slot, int, error) {
 	// First, check if the user is a coach
-	user, err := s.userRepo.GetUserByID(userID)
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error fetching user: %w", err)
 	}
@@ -100,18 +409,24 @@ func (s *SlotService) GetUpcomingSlots(userID uuid.UUID, page, pageSize int) ([]
 
 	offset := (page - 1) * pageSize
 	// If the user is a coach, proceed to fetch upcoming slots
-	paginatedSlots, totalSlots, err := s.slotRepo.GetUpcomingSlots(userID, offset, pageSize)
+	paginatedSlots, totalSlots, err := s.slotRepo.GetUpcomingSlots(ctx, userID, offset, pageSize)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error fetching upcoming slots: %w", err)
 	}
 	if paginatedSlots == nil {
 		paginatedSlots = []model.Slot{} // Return an empty slice instead of nil
 	}
+	if err := s.attachAttendees(ctx, paginatedSlots, true); err != nil {
+		return nil, 0, err
+	}
 	return paginatedSlots, totalSlots, nil
 }
 
-func (s *SlotService) GetAvailableSlots(coachId uuid.UUID, page, pageSize int) ([]model.Slot, int, error) {
-	user, err := s.userRepo.GetUserByID(coachId)
+// GetAvailableSlots returns the coach's bookable slots as the viewer sees
+// them: slots other students hold in checkout are hidden, the viewer's own
+// are not.
+func (s *SlotService) GetAvailableSlots(ctx context.Context, viewerID, coachId uuid.UUID, page, pageSize int) ([]model.Slot, int, error) {
+	user, err := s.userRepo.GetUserByID(ctx, coachId)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error fetching user: %w", err)
 	}
@@ -120,8 +435,16 @@ func (s *SlotService) GetAvailableSlots(coachId uuid.UUID, page, pageSize int) (
 		return nil, 0, &ErrNotCoach{UserID: coachId.String()}
 	}
 
+	// Slots outside the coach's booking window cannot be booked, so they are
+	// not offered
+	profile, err := getCoachProfileOrDefault(ctx, s.coachProfileRepo, coachId)
+	if err != nil {
+		return nil, 0, err
+	}
+	earliest, latest := profile.BookingWindow(time.Now())
+
 	offset := (page - 1) * pageSize
-	paginatedSlots, totalSlots, err := s.slotRepo.GetAvailableSlots(coachId, offset, pageSize)
+	paginatedSlots, totalSlots, err := s.slotRepo.GetAvailableSlots(ctx, coachId, viewerID, earliest, latest, offset, pageSize)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error fetching available slots: %w", err)
 	}
@@ -131,62 +454,481 @@ func (s *SlotService) GetAvailableSlots(coachId uuid.UUID, page, pageSize int) (
 	return paginatedSlots, totalSlots, nil
 }
 
-func (s *SlotService) BookSlot(slotID, studentID uuid.UUID) error {
+// SearchAvailableSlots finds bookable slots across all coaches, earliest
+// first unless the criteria ask otherwise. Weekday and time-of-day filters
+// are read in the criteria's time zone, UTC by default.
+func (s *SlotService) SearchAvailableSlots(ctx context.Context, criteria model.SlotSearchCriteria, page, pageSize int) ([]model.Slot, int, error) {
+	if err := validateSlotSearch(&criteria); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	paginatedSlots, totalSlots, err := s.slotRepo.SearchAvailableSlots(ctx, criteria, offset, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error searching available slots: %w", err)
+	}
+	if paginatedSlots == nil {
+		paginatedSlots = []model.Slot{} // Return an empty slice instead of nil
+	}
+	return paginatedSlots, totalSlots, nil
+}
+
+// validateSlotSearch checks the criteria and fills in defaults.
+func validateSlotSearch(criteria *model.SlotSearchCriteria) error {
+	if criteria.TimeZone == "" {
+		criteria.TimeZone = "UTC"
+	}
+	// "Local" means the server's zone to Go and nothing to Postgres
+	if _, err := loadCoachLocation(criteria.TimeZone); err != nil || criteria.TimeZone == "Local" {
+		return &ErrInvalidTimeZone{TimeZone: criteria.TimeZone}
+	}
+
+	if criteria.From != nil && criteria.To != nil && !criteria.To.After(*criteria.From) {
+		return &ErrInvalidSlotSearch{Reason: "to must be after from"}
+	}
+	for _, minute := range []*int{criteria.StartMinute, criteria.EndMinute} {
+		if minute != nil && (*minute < 0 || *minute > 24*60) {
+			return &ErrInvalidSlotSearch{Reason: "times of day must fall within the day"}
+		}
+	}
+	if criteria.StartMinute != nil && criteria.EndMinute != nil && *criteria.StartMinute >= *criteria.EndMinute {
+		return &ErrInvalidSlotSearch{Reason: "time of day window must start before it ends"}
+	}
+	if criteria.MinDurationMinutes < 0 || criteria.MaxDurationMinutes < 0 {
+		return &ErrInvalidSlotSearch{Reason: "durations cannot be negative"}
+	}
+	if criteria.MaxDurationMinutes > 0 && criteria.MinDurationMinutes > criteria.MaxDurationMinutes {
+		return &ErrInvalidSlotSearch{Reason: "minimum duration is longer than maximum duration"}
+	}
+
+	switch criteria.Sort {
+	case "":
+		criteria.Sort = model.SlotSearchSortEarliest
+	case model.SlotSearchSortEarliest, model.SlotSearchSortLatest:
+	default:
+		return &ErrInvalidSlotSearch{Reason: fmt.Sprintf("unknown sort %q", criteria.Sort)}
+	}
+	return nil
+}
+
+// BookSlot takes a seat in the slot for the student, or converts the
+// student's checkout hold on it, and charges for it. When
+// the coach approves bookings the seat is held by a pending request instead,
+// which the returned booking's status shows.
+func (s *SlotService) BookSlot(ctx context.Context, slotID, studentID uuid.UUID) (*model.Booking, error) {
 	// Fetch the user
-	user, err := s.userRepo.GetUserByID(studentID)
+	user, err := s.userRepo.GetUserByID(ctx, studentID)
 	if err != nil {
-		return fmt.Errorf("error fetching user: %w", err)
+		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
 
 	// Check if the user is a student
 	if user.Role != model.RoleStudent {
-		return fmt.Errorf("only students can book slots")
+		return nil, &ErrNotStudent{UserID: studentID.String()}
+	}
+
+	var booking *model.Booking
+	payments := newBookingPayments(s.paymentProvider)
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+		userRepo := repository.NewUserRepository(tx)
+
+		// Serialize this student's bookings so two requests cannot both pass
+		// the overlap check below
+		if err := userRepo.LockUser(ctx, studentID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		// Fetch and lock the slot
+		slot, err := slotRepo.GetSlotByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: slotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+
+		// A checkout hold the student placed on the slot becomes the booking.
+		// The hold was only granted inside the coach's booking window.
+		held, err := takeCheckoutHold(ctx, tx, slot, studentID)
+		if err != nil {
+			return err
+		}
+		if !held {
+			if err := checkSlotBookable(ctx, slotRepo, slot, studentID, uuid.Nil); err != nil {
+				return err
+			}
+			if err := checkBookingWindow(ctx, tx, slot); err != nil {
+				return err
+			}
+			if err := checkNoShowPolicy(ctx, tx, slot, studentID); err != nil {
+				return err
+			}
+
+			// Book the slot. BookSlot relies on the row lock taken above to
+			// count the seats left.
+			booked, err := slotRepo.BookSlot(ctx, slotID, studentID)
+			if err != nil {
+				return fmt.Errorf("error booking slot: %w", err)
+			}
+			if !booked {
+				return &ErrSlotAlreadyBooked{SlotID: slotID.String()}
+			}
+		}
+
+		// The student's lock taken above keeps the credit balance check
+		// race-free. A failed charge rolls the booking back.
+		if err := payments.payForSeat(ctx, tx, slot, studentID); err != nil {
+			return err
+		}
+		if _, err := requestApprovalIfRequired(ctx, tx, slot, studentID); err != nil {
+			return err
+		}
+
+		booking, err = repository.NewBookingRepository(tx).GetBooking(ctx, slotID, studentID)
+		if err != nil {
+			return fmt.Errorf("error fetching booking: %w", err)
+		}
+		return nil
+	})
+	payments.settle(ctx, s.paymentRepo, err)
+	if err != nil {
+		return nil, err
 	}
+	return booking, nil
+}
+
+// CancelBooking lets the booked student give up their slot, provided they
+// cancel at least the coach's minimum notice before it starts. The seat is
+// offered to the waitlist, or becomes bookable again, and the cancellation is
+// kept in the booking history.
+func (s *SlotService) CancelBooking(ctx context.Context, slotID, studentID uuid.UUID, reason string) error {
+	payments := newBookingPayments(s.paymentProvider)
+	err := s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+
+		// Fetch and lock the slot
+		slot, err := slotRepo.GetSlotByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: slotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+
+		// Only a booked student can cancel
+		if err := checkStudentBooking(ctx, repository.NewBookingRepository(tx), slot, studentID); err != nil {
+			return err
+		}
+
+		// Enforce the coach's cancellation policy
+		profile, err := getCoachProfileOrDefault(ctx, repository.NewCoachProfileRepository(tx), slot.CoachID)
+		if err != nil {
+			return err
+		}
+		if time.Now().Add(profile.MinCancellationNotice()).After(slot.StartTime) {
+			return &ErrCancellationTooLate{SlotID: slotID.String(), Notice: profile.MinCancellationNotice()}
+		}
+
+		if err := slotRepo.ReleaseBooking(ctx, slotID, studentID); err != nil {
+			return fmt.Errorf("error releasing booking: %w", err)
+		}
+		if _, err := payments.refundSeat(ctx, tx, slotID, studentID, studentID, "Cancelled booking"); err != nil {
+			return err
+		}
+		if err := promoteFromWaitlist(ctx, tx, slot); err != nil {
+			return err
+		}
+
+		entry := model.BookingHistory{
+			ID:        uuid.New(),
+			SlotID:    slot.ID,
+			CoachID:   slot.CoachID,
+			StudentID: studentID,
+			Event:     model.BookingEventCancelled,
+			ActorID:   studentID,
+			Reason:    strings.TrimSpace(reason),
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			CreatedAt: time.Now(),
+		}
+		if err := repository.NewBookingHistoryRepository(tx).CreateBookingHistory(ctx, entry); err != nil {
+			return fmt.Errorf("error recording cancellation: %w", err)
+		}
+
+		return nil
+	})
+	payments.settle(ctx, s.paymentRepo, err)
+	return err
+}
 
-	// Fetch the slot
-	slot, err := s.slotRepo.GetSlotByID(slotID)
+// GetBookingHistory returns cancellations and other booking changes, newest
+// first. Coaches see the history of their own slots, students their own.
+func (s *SlotService) GetBookingHistory(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]model.BookingHistory, int, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("error fetching slot: %w", err)
+		return nil, 0, fmt.Errorf("error fetching user: %w", err)
 	}
 
-	// Check if the slot is already booked
-	if slot.Booked {
-		return fmt.Errorf("slot is already booked")
+	offset := (page - 1) * pageSize
+	var entries []model.BookingHistory
+	var totalCount int
+	switch user.Role {
+	case model.RoleCoach:
+		entries, totalCount, err = s.bookingHistoryRepo.GetBookingHistoryForCoach(ctx, userID, offset, pageSize)
+	case model.RoleStudent:
+		entries, totalCount, err = s.bookingHistoryRepo.GetBookingHistoryForStudent(ctx, userID, offset, pageSize)
+	default:
+		return nil, 0, &ErrNotAuthorized{UserID: userID.String(), Action: "view booking history"}
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching booking history: %w", err)
 	}
+	if entries == nil {
+		entries = []model.BookingHistory{} // Return an empty slice instead of nil
+	}
+	return entries, totalCount, nil
+}
 
-	// Check if the slot is in the past
-	if slot.StartTime.Before(time.Now()) {
-		return fmt.Errorf("cannot book a slot that has already begun")
+// RescheduleBooking moves the student's booking from one slot to another in a
+// single transaction, so neither slot can be taken by someone else in
+// between. The target slot must pass the same checks as BookSlot, ignoring
+// the booking being moved when looking for overlaps.
+func (s *SlotService) RescheduleBooking(ctx context.Context, studentID, fromSlotID, toSlotID uuid.UUID, reason string) error {
+	user, err := s.userRepo.GetUserByID(ctx, studentID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleStudent {
+		return &ErrNotStudent{UserID: studentID.String()}
+	}
+	if fromSlotID == toSlotID {
+		return &ErrSlotAlreadyBooked{SlotID: toSlotID.String()}
+	}
+
+	payments := newBookingPayments(s.paymentProvider)
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+
+		if err := repository.NewUserRepository(tx).LockUser(ctx, studentID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		// Lock both slots in a fixed order so concurrent reschedules between
+		// the same pair of slots cannot deadlock
+		slots := make(map[uuid.UUID]*model.Slot, 2)
+		ids := []uuid.UUID{fromSlotID, toSlotID}
+		if toSlotID.String() < fromSlotID.String() {
+			ids[0], ids[1] = ids[1], ids[0]
+		}
+		for _, id := range ids {
+			slot, err := slotRepo.GetSlotByIDForUpdate(ctx, id)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return &ErrSlotNotFound{SlotID: id.String()}
+				}
+				return fmt.Errorf("error fetching slot: %w", err)
+			}
+			slots[id] = slot
+		}
+		fromSlot, toSlot := slots[fromSlotID], slots[toSlotID]
+
+		// The booking being moved must belong to this student and not have started
+		if err := checkStudentBooking(ctx, repository.NewBookingRepository(tx), fromSlot, studentID); err != nil {
+			return err
+		}
+		if !fromSlot.StartTime.After(time.Now()) {
+			return &ErrSlotStarted{SlotID: fromSlotID.String()}
+		}
+
+		if err := checkSlotBookable(ctx, slotRepo, toSlot, studentID, fromSlotID); err != nil {
+			return err
+		}
+		if err := checkBookingWindow(ctx, tx, toSlot); err != nil {
+			return err
+		}
+		if err := checkNoShowPolicy(ctx, tx, toSlot, studentID); err != nil {
+			return err
+		}
+
+		if err := slotRepo.ReleaseBooking(ctx, fromSlotID, studentID); err != nil {
+			return fmt.Errorf("error releasing booking: %w", err)
+		}
+		if err := promoteFromWaitlist(ctx, tx, fromSlot); err != nil {
+			return err
+		}
+		booked, err := slotRepo.BookSlot(ctx, toSlotID, studentID)
+		if err != nil {
+			return fmt.Errorf("error booking slot: %w", err)
+		}
+		if !booked {
+			return &ErrSlotAlreadyBooked{SlotID: toSlotID.String()}
+		}
+
+		// The student gets back what they paid for the old slot and pays for
+		// the new one as if booking it, since the two may be priced
+		// differently
+		if _, err := payments.refundSeat(ctx, tx, fromSlotID, studentID, studentID, "Rescheduled booking"); err != nil {
+			return err
+		}
+		if err := payments.payForSeat(ctx, tx, toSlot, studentID); err != nil {
+			return err
+		}
+		// The coach has already accepted the student if the booking stays with
+		// them
+		if toSlot.CoachID != fromSlot.CoachID {
+			if _, err := requestApprovalIfRequired(ctx, tx, toSlot, studentID); err != nil {
+				return err
+			}
+		}
+
+		entry := model.BookingHistory{
+			ID:        uuid.New(),
+			SlotID:    fromSlot.ID,
+			NewSlotID: &toSlot.ID,
+			CoachID:   fromSlot.CoachID,
+			StudentID: studentID,
+			Event:     model.BookingEventRescheduled,
+			ActorID:   studentID,
+			Reason:    strings.TrimSpace(reason),
+			StartTime: fromSlot.StartTime,
+			EndTime:   fromSlot.EndTime,
+			CreatedAt: time.Now(),
+		}
+		if err := repository.NewBookingHistoryRepository(tx).CreateBookingHistory(ctx, entry); err != nil {
+			return fmt.Errorf("error recording reschedule: %w", err)
+		}
+
+		return nil
+	})
+	payments.settle(ctx, s.paymentRepo, err)
+	return err
+}
+
+// checkSlotBookable applies the booking rules to a locked slot: it must have
+// a seat left, be in the future, and not overlap the student's other
+// bookings, including a seat already taken in the same slot. excludeSlotID
+// names a booking to ignore in the overlap check, or uuid.Nil.
+func checkSlotBookable(ctx context.Context, slotRepo *repository.SlotRepository, slot *model.Slot, studentID, excludeSlotID uuid.UUID) error {
+	// Check if every seat is taken
+	if slot.Booked {
+		return &ErrSlotAlreadyBooked{SlotID: slot.ID.String()}
+	}
+	// A slot that already has attendees stays booked; anything else must
+	// be allowed to become booked
+	if slot.Status != model.SlotStatusBooked {
+		if err := checkSlotTransition(slot, model.SlotStatusBooked); err != nil {
+			return err
+		}
 	}
 
-	if slot.EndTime.Before(time.Now()) {
-		return fmt.Errorf("cannot book a slot that has already expired")
+	// Check if the slot is in the past
+	if !slot.StartTime.After(time.Now()) {
+		return &ErrPastSlot{SlotID: slot.ID.String()}
 	}
 
 	// Check for overlapping bookings
-	hasOverlap, err := s.slotRepo.HasOverlappingBooking(studentID, slot.StartTime, slot.EndTime)
+	hasOverlap, err := slotRepo.HasOverlappingBookingExcluding(ctx, studentID, slot.StartTime, slot.EndTime, excludeSlotID)
 	if err != nil {
 		return fmt.Errorf("error checking for overlapping bookings: %w", err)
 	}
 	if hasOverlap {
-		return fmt.Errorf("booking overlaps with an existing booking")
+		return &ErrOverlappingBooking{StudentID: studentID.String()}
 	}
+	return nil
+}
 
-	// Book the slot
-	slot.StudentID = &studentID
-	slot.Booked = true
+// checkBookingWindow enforces the coach's minimum lead time and booking
+// horizon on a slot the student is about to book.
+func checkBookingWindow(ctx context.Context, tx db.DbClient, slot *model.Slot) error {
+	profile, err := getCoachProfileOrDefault(ctx, repository.NewCoachProfileRepository(tx), slot.CoachID)
+	if err != nil {
+		return err
+	}
+	earliest, latest := profile.BookingWindow(time.Now())
+	if !slot.StartTime.After(earliest) {
+		return &ErrBookingTooSoon{SlotID: slot.ID.String(), LeadTime: profile.MinBookingLead()}
+	}
+	if latest != nil && slot.StartTime.After(*latest) {
+		return &ErrBookingTooFarAhead{SlotID: slot.ID.String(), HorizonDays: profile.MaxBookingHorizonDays}
+	}
+	return nil
+}
 
-	err = s.slotRepo.UpdateSlot(*slot)
+// CompleteEndedSessions marks booked sessions whose end time has passed as
+// completed. It runs periodically from the scheduler.
+func (s *SlotService) CompleteEndedSessions(ctx context.Context) (int, error) {
+	completed, err := s.slotRepo.CompleteEndedSlots(ctx)
 	if err != nil {
-		return fmt.Errorf("error updating slot: %w", err)
+		return 0, fmt.Errorf("error completing ended sessions: %w", err)
 	}
+	return int(completed), nil
+}
 
+// checkStudentBooking checks that the student has a confirmed seat in a booked
+// slot.
+func checkStudentBooking(ctx context.Context, bookingRepo *repository.BookingRepository, slot *model.Slot, studentID uuid.UUID) error {
+	if slot.Status != model.SlotStatusBooked {
+		return &ErrBookingNotFound{SlotID: slot.ID.String(), StudentID: studentID.String()}
+	}
+	booking, err := bookingRepo.GetBooking(ctx, slot.ID, studentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ErrBookingNotFound{SlotID: slot.ID.String(), StudentID: studentID.String()}
+		}
+		return fmt.Errorf("error fetching booking: %w", err)
+	}
+	// Held seats are answered through the waitlist and pending requests by
+	// the coach; neither is cancelled here
+	if booking.Status != model.BookingStatusConfirmed {
+		return &ErrBookingNotFound{SlotID: slot.ID.String(), StudentID: studentID.String()}
+	}
 	return nil
 }
 
-func (s *SlotService) GetUpcomingBookingsForStudent(studentID uuid.UUID, page, pageSize int) ([]model.Slot, int, error) {
+// resolveSlotCapacity defaults an unset capacity to a one-on-one slot and
+// checks the rest.
+func resolveSlotCapacity(capacity int) (int, error) {
+	if capacity == 0 {
+		return 1, nil
+	}
+	if capacity < 1 || capacity > maxSlotCapacity {
+		return 0, &ErrInvalidCapacity{Capacity: capacity, Max: maxSlotCapacity}
+	}
+	return capacity, nil
+}
+
+// attachAttendees loads the attendee list of each slot. Unless withContact is
+// set, attendees' phone numbers are left out.
+func (s *SlotService) attachAttendees(ctx context.Context, slots []model.Slot, withContact bool) error {
+	if len(slots) == 0 {
+		return nil
+	}
+	slotIDs := make([]uuid.UUID, len(slots))
+	for i, slot := range slots {
+		slotIDs[i] = slot.ID
+	}
+	bookings, err := s.bookingRepo.GetBookingsForSlots(ctx, slotIDs)
+	if err != nil {
+		return fmt.Errorf("error fetching attendees: %w", err)
+	}
+
+	bySlot := make(map[uuid.UUID][]model.Booking, len(slots))
+	for _, booking := range bookings {
+		if !withContact {
+			booking.StudentPhoneNumber = ""
+		}
+		bySlot[booking.SlotID] = append(bySlot[booking.SlotID], booking)
+	}
+	for i := range slots {
+		slots[i].Attendees = bySlot[slots[i].ID]
+	}
+	return nil
+}
+
+func (s *SlotService) GetUpcomingBookingsForStudent(ctx context.Context, studentID uuid.UUID, page, pageSize int) ([]model.Slot, int, error) {
 	// First, check if the user is a student
-	user, err := s.userRepo.GetUserByID(studentID)
+	user, err := s.userRepo.GetUserByID(ctx, studentID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error fetching user: %w", err)
 	}
@@ -196,34 +938,47 @@ func (s *SlotService) GetUpcomingBookingsForStudent(studentID uuid.UUID, page, p
 	}
 	offset := (page - 1) * pageSize
 	// If the user is a student, proceed to fetch upcoming bookings
-	paginatedSlots, totalCount, err := s.slotRepo.GetUpcomingBookingsForStudent(studentID, offset, pageSize)
+	paginatedSlots, totalCount, err := s.slotRepo.GetUpcomingBookingsForStudent(ctx, studentID, offset, pageSize)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error fetching upcoming bookings: %w", err)
 	}
 	if paginatedSlots == nil {
 		paginatedSlots = []model.Slot{} // Return an empty slice instead of nil
 	}
+	// Students see who else is attending, but not their phone numbers
+	if err := s.attachAttendees(ctx, paginatedSlots, false); err != nil {
+		return nil, 0, err
+	}
 
 	return paginatedSlots, totalCount, nil
 }
 
-func (s *SlotService) GetSlotDetails(userID, slotID uuid.UUID) (*model.SlotDetails, error) {
+func (s *SlotService) GetSlotDetails(ctx context.Context, userID, slotID uuid.UUID) (*model.SlotDetails, error) {
 	// Fetch the slot details
-	slotDetails, err := s.slotRepo.GetSlotDetails(slotID)
+	slotDetails, err := s.slotRepo.GetSlotDetails(ctx, slotID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching slot details: %w", err)
 	}
 
-	// Check if the user is either the coach or the student for this slot
-	if slotDetails.CoachID != userID && (slotDetails.StudentID == nil || *slotDetails.StudentID != userID) {
+	attendees, err := s.bookingRepo.GetBookingsForSlots(ctx, []uuid.UUID{slotID})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching attendees: %w", err)
+	}
+	slotDetails.Attendees = attendees
+
+	// Check if the user is either the coach or one of the attendees
+	isCoach := slotDetails.CoachID == userID
+	if !isCoach && !slotDetails.HasAttendee(userID) {
 		return nil, &ErrNotAuthorized{UserID: userID.String(), Action: "view slot details"}
 	}
 
-	// If the slot is not booked, remove student information
-	if !slotDetails.Booked {
-		slotDetails.StudentID = nil
-		slotDetails.StudentName = ""
-		slotDetails.StudentPhoneNumber = ""
+	// Attendees only see their own phone number
+	if !isCoach {
+		for i := range slotDetails.Attendees {
+			if slotDetails.Attendees[i].StudentID != userID {
+				slotDetails.Attendees[i].StudentPhoneNumber = ""
+			}
+		}
 	}
 
 	return slotDetails, nil
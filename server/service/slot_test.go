@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/infrastructure/payment"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// newTestDbClient connects to the database named by the DB_* environment
+// variables, with the schema migrated. Tests that need it are skipped when
+// they are unset.
+func newTestDbClient(t *testing.T) db.DbClient {
+	t.Helper()
+	for _, name := range []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_NAME"} {
+		if os.Getenv(name) == "" {
+			t.Skipf("%s is not set; skipping database test", name)
+		}
+	}
+	conn, err := db.GetDbConnection(1)
+	if err != nil {
+		t.Fatalf("connecting to database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return db.NewDbClient(conn, 0)
+}
+
+func createTestUser(t *testing.T, ctx context.Context, dbc db.DbClient, role model.UserRole) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	query := `INSERT INTO stepful_user (id, name, phone_number, user_role) VALUES ($1, $2, $3, $4)`
+	if _, err := dbc.ExecuteCommand(ctx, query, id, "Test "+string(role), "555-0000", role); err != nil {
+		t.Fatalf("creating %s: %v", role, err)
+	}
+	return id
+}
+
+// TestBookSlotConcurrentLastSeat races students for the only seat in a slot
+// and checks that exactly one of them gets it.
+func TestBookSlotConcurrentLastSeat(t *testing.T) {
+	const students = 10
+	ctx := context.Background()
+	dbc := newTestDbClient(t)
+
+	coachID := createTestUser(t, ctx, dbc, model.RoleCoach)
+	studentIDs := make([]uuid.UUID, students)
+	t.Cleanup(func() {
+		// Bookings go with the slot. Unused entries of studentIDs are nil and
+		// match nothing.
+		ids := pq.StringArray{coachID.String()}
+		for _, id := range studentIDs {
+			ids = append(ids, id.String())
+		}
+		for _, cmd := range []string{
+			`DELETE FROM credit_ledger_entry WHERE student_id = ANY($1::uuid[])`,
+			`DELETE FROM slot WHERE coach_id = ANY($1::uuid[])`,
+			`DELETE FROM stepful_user WHERE id = ANY($1::uuid[])`,
+		} {
+			if _, err := dbc.ExecuteCommand(ctx, cmd, ids); err != nil {
+				t.Errorf("cleaning up: %v", err)
+			}
+		}
+	})
+
+	creditRepo := repository.NewCreditRepository(dbc)
+	for i := range studentIDs {
+		studentIDs[i] = createTestUser(t, ctx, dbc, model.RoleStudent)
+		// One credit each, so every student can pay for the seat
+		grant := model.CreditLedgerEntry{
+			ID:        uuid.New(),
+			StudentID: studentIDs[i],
+			Kind:      model.CreditEntryGrant,
+			Amount:    1,
+			Note:      "Concurrency test",
+			CreatedAt: time.Now(),
+		}
+		if err := creditRepo.CreateEntry(ctx, grant); err != nil {
+			t.Fatalf("granting credit: %v", err)
+		}
+	}
+
+	slotRepo := repository.NewSlotRepository(dbc)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	slotID, err := slotRepo.CreateSlot(ctx, model.Slot{
+		ID:        uuid.New(),
+		CoachID:   coachID,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Status:    model.SlotStatusOpen,
+		Capacity:  1,
+	})
+	if err != nil {
+		t.Fatalf("creating slot: %v", err)
+	}
+
+	bookingRepo := repository.NewBookingRepository(dbc)
+	svc := NewSlotService(
+		dbc,
+		slotRepo,
+		bookingRepo,
+		repository.NewUserRepository(dbc),
+		repository.NewSessionTypeRepository(dbc),
+		repository.NewCoachProfileRepository(dbc),
+		repository.NewBookingHistoryRepository(dbc),
+		repository.NewPaymentRepository(dbc),
+		payment.NewFakeProvider(),
+	)
+
+	// Release every booking at once to make the race as tight as possible
+	ready := make(chan struct{})
+	errs := make([]error, students)
+	var wg sync.WaitGroup
+	for i, studentID := range studentIDs {
+		wg.Add(1)
+		go func(i int, studentID uuid.UUID) {
+			defer wg.Done()
+			<-ready
+			_, errs[i] = svc.BookSlot(ctx, slotID, studentID)
+		}(i, studentID)
+	}
+	close(ready)
+	wg.Wait()
+
+	succeeded, rejected := 0, 0
+	for i, err := range errs {
+		var errSlotAlreadyBooked *ErrSlotAlreadyBooked
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.As(err, &errSlotAlreadyBooked):
+			rejected++
+		default:
+			t.Errorf("student %d: unexpected error: %v", i, err)
+		}
+	}
+	if succeeded != 1 || rejected != students-1 {
+		t.Errorf("got %d bookings and %d ErrSlotAlreadyBooked, want 1 and %d", succeeded, rejected, students-1)
+	}
+
+	slot, err := slotRepo.GetSlotByID(ctx, slotID)
+	if err != nil {
+		t.Fatalf("fetching slot: %v", err)
+	}
+	if !slot.Booked {
+		t.Errorf("slot.booked = false, want true")
+	}
+	var confirmed int
+	query := `SELECT COUNT(*) FROM booking WHERE slot_id = $1 AND status = $2`
+	if err := dbc.GetSingleEntity(ctx, &confirmed, query, slotID, model.BookingStatusConfirmed); err != nil {
+		t.Fatalf("counting bookings: %v", err)
+	}
+	if confirmed != 1 {
+		t.Errorf("got %d confirmed bookings, want 1", confirmed)
+	}
+}
+
+// TestUnconfirmedSeatsLeaveSlotOpen checks that held, pending and checkout
+// seats count towards capacity but neither book a slot nor let it complete.
+func TestUnconfirmedSeatsLeaveSlotOpen(t *testing.T) {
+	ctx := context.Background()
+	dbc := newTestDbClient(t)
+
+	coachID := createTestUser(t, ctx, dbc, model.RoleCoach)
+	studentID := createTestUser(t, ctx, dbc, model.RoleStudent)
+	t.Cleanup(func() {
+		ids := pq.StringArray{coachID.String(), studentID.String()}
+		for _, cmd := range []string{
+			`DELETE FROM slot WHERE coach_id = ANY($1::uuid[])`,
+			`DELETE FROM stepful_user WHERE id = ANY($1::uuid[])`,
+		} {
+			if _, err := dbc.ExecuteCommand(ctx, cmd, ids); err != nil {
+				t.Errorf("cleaning up: %v", err)
+			}
+		}
+	})
+
+	slotRepo := repository.NewSlotRepository(dbc)
+	bookingRepo := repository.NewBookingRepository(dbc)
+	createSlot := func(start time.Time) uuid.UUID {
+		t.Helper()
+		slotID, err := slotRepo.CreateSlot(ctx, model.Slot{
+			ID:        uuid.New(),
+			CoachID:   coachID,
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+			Status:    model.SlotStatusOpen,
+			Capacity:  1,
+		})
+		if err != nil {
+			t.Fatalf("creating slot: %v", err)
+		}
+		return slotID
+	}
+	checkSlot := func(slotID uuid.UUID, wantStatus model.SlotStatus, wantBooked bool) {
+		t.Helper()
+		slot, err := slotRepo.GetSlotByID(ctx, slotID)
+		if err != nil {
+			t.Fatalf("fetching slot: %v", err)
+		}
+		if slot.Status != wantStatus || slot.Booked != wantBooked {
+			t.Errorf("slot status = %s, booked = %t; want %s, %t", slot.Status, slot.Booked, wantStatus, wantBooked)
+		}
+	}
+
+	// A held seat fills the slot without booking it
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	slotID := createSlot(start)
+	held, err := slotRepo.HoldSeat(ctx, slotID, studentID, time.Now().Add(time.Hour))
+	if err != nil || !held {
+		t.Fatalf("holding seat: held = %t, err = %v", held, err)
+	}
+	checkSlot(slotID, model.SlotStatusOpen, true)
+
+	// Confirming it books the slot, and sending it for approval opens it again
+	if err := bookingRepo.ConfirmBooking(ctx, slotID, studentID); err != nil {
+		t.Fatalf("confirming booking: %v", err)
+	}
+	checkSlot(slotID, model.SlotStatusBooked, true)
+	if err := bookingRepo.MarkPending(ctx, slotID, studentID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("marking booking pending: %v", err)
+	}
+	checkSlot(slotID, model.SlotStatusOpen, true)
+
+	// Only the ended slot with a confirmed booking is completed. Seats cannot
+	// be taken in the past, so these are written directly.
+	ended := time.Now().Add(-2 * time.Hour).Truncate(time.Hour)
+	slotIDs := map[model.BookingStatus]uuid.UUID{}
+	for _, status := range []model.BookingStatus{
+		model.BookingStatusConfirmed,
+		model.BookingStatusHeld,
+		model.BookingStatusPending,
+		model.BookingStatusCheckout,
+	} {
+		slotID := createSlot(ended)
+		query := `INSERT INTO booking (id, slot_id, student_id, status, created_at) VALUES ($1, $2, $3, $4, NOW())`
+		if _, err := dbc.ExecuteCommand(ctx, query, uuid.New(), slotID, studentID, status); err != nil {
+			t.Fatalf("creating %s booking: %v", status, err)
+		}
+		// Mark the slot booked the way it was before only confirmed
+		// bookings counted
+		if err := slotRepo.SetSlotStatus(ctx, slotID, model.SlotStatusBooked); err != nil {
+			t.Fatalf("booking slot: %v", err)
+		}
+		slotIDs[status] = slotID
+	}
+	if _, err := slotRepo.CompleteEndedSlots(ctx); err != nil {
+		t.Fatalf("completing ended slots: %v", err)
+	}
+	for status, slotID := range slotIDs {
+		slot, err := slotRepo.GetSlotByID(ctx, slotID)
+		if err != nil {
+			t.Fatalf("fetching slot: %v", err)
+		}
+		want := model.SlotStatusBooked
+		if status == model.BookingStatusConfirmed {
+			want = model.SlotStatusCompleted
+		}
+		if slot.Status != want {
+			t.Errorf("slot with a %s booking: status = %s, want %s", status, slot.Status, want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+// How long a seat stays reserved while the student checks out. Holds never
+// run past the start of the session.
+const checkoutHoldTTL = 5 * time.Minute
+
+// HoldSlot reserves a seat in the slot for the student for checkoutHoldTTL,
+// hiding it from other students until the student books it with BookSlot,
+// releases it, or the hold expires. A student can hold one seat at a time.
+func (s *SlotService) HoldSlot(ctx context.Context, slotID, studentID uuid.UUID) (*model.Booking, error) {
+	user, err := s.userRepo.GetUserByID(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleStudent {
+		return nil, &ErrNotStudent{UserID: studentID.String()}
+	}
+
+	var booking *model.Booking
+	err = s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slotRepo := repository.NewSlotRepository(tx)
+		bookingRepo := repository.NewBookingRepository(tx)
+
+		// Same lock order as BookSlot
+		if err := repository.NewUserRepository(tx).LockUser(ctx, studentID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+		slot, err := slotRepo.GetSlotByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: slotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+
+		existing, err := bookingRepo.GetActiveCheckoutHold(ctx, studentID)
+		if err == nil {
+			return &ErrCheckoutHoldExists{SlotID: existing.SlotID.String()}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error fetching checkout hold: %w", err)
+		}
+
+		if err := checkSlotBookable(ctx, slotRepo, slot, studentID, uuid.Nil); err != nil {
+			return err
+		}
+		if err := checkBookingWindow(ctx, tx, slot); err != nil {
+			return err
+		}
+		if err := checkNoShowPolicy(ctx, tx, slot, studentID); err != nil {
+			return err
+		}
+
+		expiresAt := time.Now().Add(checkoutHoldTTL)
+		if expiresAt.After(slot.StartTime) {
+			expiresAt = slot.StartTime
+		}
+		reserved, err := slotRepo.ReserveSeat(ctx, slotID, studentID, expiresAt)
+		if err != nil {
+			return fmt.Errorf("error holding seat: %w", err)
+		}
+		if !reserved {
+			return &ErrSlotAlreadyBooked{SlotID: slotID.String()}
+		}
+
+		booking, err = bookingRepo.GetBooking(ctx, slotID, studentID)
+		if err != nil {
+			return fmt.Errorf("error fetching booking: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// ReleaseHold gives up the student's checkout hold on the slot early.
+func (s *SlotService) ReleaseHold(ctx context.Context, slotID, studentID uuid.UUID) error {
+	return s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, slotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: slotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+		booking, err := repository.NewBookingRepository(tx).GetBooking(ctx, slotID, studentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrBookingNotFound{SlotID: slotID.String(), StudentID: studentID.String()}
+			}
+			return fmt.Errorf("error fetching booking: %w", err)
+		}
+		if booking.Status != model.BookingStatusCheckout {
+			return &ErrBookingNotFound{SlotID: slotID.String(), StudentID: studentID.String()}
+		}
+		return releaseCheckoutHold(ctx, tx, slot, studentID)
+	})
+}
+
+// ExpireCheckoutHolds releases checkout holds whose time is up and returns
+// how many were released. It is run periodically by the scheduler.
+func (s *SlotService) ExpireCheckoutHolds(ctx context.Context) (int, error) {
+	holds, err := s.bookingRepo.GetExpiredCheckoutHolds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching expired checkout holds: %w", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, hold := range holds {
+		released := false
+		err := s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+			slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, hold.SlotID)
+			if err != nil {
+				// The slot and its bookings are gone
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil
+				}
+				return fmt.Errorf("error fetching slot: %w", err)
+			}
+			locked, err := repository.NewBookingRepository(tx).GetBookingByID(ctx, hold.ID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil
+				}
+				return fmt.Errorf("error fetching booking: %w", err)
+			}
+			// Booked or released while we were waiting for the lock
+			if locked.Status != model.BookingStatusCheckout {
+				return nil
+			}
+
+			if err := releaseCheckoutHold(ctx, tx, slot, locked.StudentID); err != nil {
+				return err
+			}
+			released = true
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("checkout hold %s: %w", hold.ID, err))
+			continue
+		}
+		if released {
+			expired++
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+// takeCheckoutHold turns the student's live checkout hold on the slot into a
+// confirmed seat and reports whether there was one. A hold that has expired
+// but not been swept yet is released, and slot is reloaded to reflect the
+// freed seat. The caller must hold the slot's row lock.
+func takeCheckoutHold(ctx context.Context, tx db.DbClient, slot *model.Slot, studentID uuid.UUID) (bool, error) {
+	bookingRepo := repository.NewBookingRepository(tx)
+	booking, err := bookingRepo.GetBooking(ctx, slot.ID, studentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error fetching booking: %w", err)
+	}
+	// A hold on a slot the coach has since cancelled is left for the
+	// booking checks to reject
+	if booking.Status != model.BookingStatusCheckout || (slot.Status != model.SlotStatusOpen && slot.Status != model.SlotStatusBooked) {
+		return false, nil
+	}
+
+	if booking.ExpiresAt != nil && !booking.ExpiresAt.After(time.Now()) {
+		if err := releaseCheckoutHold(ctx, tx, slot, studentID); err != nil {
+			return false, err
+		}
+		reloaded, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, slot.ID)
+		if err != nil {
+			return false, fmt.Errorf("error fetching slot: %w", err)
+		}
+		*slot = *reloaded
+		return false, nil
+	}
+
+	if err := bookingRepo.ConfirmBooking(ctx, slot.ID, studentID); err != nil {
+		return false, fmt.Errorf("error confirming booking: %w", err)
+	}
+	return true, nil
+}
+
+// releaseCheckoutHold frees a held seat and offers it to the waitlist. The
+// caller must hold the slot's row lock.
+func releaseCheckoutHold(ctx context.Context, tx db.DbClient, slot *model.Slot, studentID uuid.UUID) error {
+	if err := repository.NewSlotRepository(tx).ReleaseBooking(ctx, slot.ID, studentID); err != nil {
+		return fmt.Errorf("error releasing checkout hold: %w", err)
+	}
+	return promoteFromWaitlist(ctx, tx, slot)
+}
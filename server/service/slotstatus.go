@@ -0,0 +1,26 @@
+package service
+
+import (
+	"github.com/cargoreligion/booking/server/model"
+)
+
+// slotTransitions lists the statuses a slot may move to from each status.
+// Cancelled is final; completed and no-show can be swapped to correct a
+// mistake after the session.
+var slotTransitions = map[model.SlotStatus][]model.SlotStatus{
+	model.SlotStatusOpen:      {model.SlotStatusBooked, model.SlotStatusCancelled},
+	model.SlotStatusBooked:    {model.SlotStatusOpen, model.SlotStatusCancelled, model.SlotStatusCompleted, model.SlotStatusNoShow},
+	model.SlotStatusCompleted: {model.SlotStatusNoShow},
+	model.SlotStatusNoShow:    {model.SlotStatusCompleted},
+	model.SlotStatusCancelled: {},
+}
+
+// checkSlotTransition returns an error unless the slot may move to status.
+func checkSlotTransition(slot *model.Slot, status model.SlotStatus) error {
+	for _, next := range slotTransitions[slot.Status] {
+		if next == status {
+			return nil
+		}
+	}
+	return &ErrInvalidSlotTransition{SlotID: slot.ID.String(), From: slot.Status, To: status}
+}
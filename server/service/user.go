@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/cargoreligion/booking/server/model"
 	"github.com/cargoreligion/booking/server/repository"
 )
@@ -13,6 +15,6 @@ func NewUserService(repo *repository.UserRepository) *UserService {
 	return &UserService{repo: repo}
 }
 
-func (s *UserService) GetAllUsers() ([]model.User, error) {
-	return s.repo.GetAllUsers()
+func (s *UserService) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	return s.repo.GetAllUsers(ctx)
 }
@@ -0,0 +1,490 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cargoreligion/booking/server/infrastructure/db"
+	"github.com/cargoreligion/booking/server/model"
+	"github.com/cargoreligion/booking/server/repository"
+	"github.com/google/uuid"
+)
+
+// How long a promoted student has to confirm the seat. Holds never run past
+// the start of the session.
+const waitlistHoldWindow = 2 * time.Hour
+
+type WaitlistService struct {
+	dbc              db.DbClient
+	waitlistRepo     *repository.WaitlistRepository
+	slotRepo         *repository.SlotRepository
+	bookingRepo      *repository.BookingRepository
+	userRepo         *repository.UserRepository
+	coachProfileRepo *repository.CoachProfileRepository
+	paymentRepo      *repository.PaymentRepository
+	paymentProvider  PaymentProvider
+}
+
+func NewWaitlistService(
+	dbc db.DbClient,
+	waitlistRepo *repository.WaitlistRepository,
+	slotRepo *repository.SlotRepository,
+	bookingRepo *repository.BookingRepository,
+	userRepo *repository.UserRepository,
+	coachProfileRepo *repository.CoachProfileRepository,
+	paymentRepo *repository.PaymentRepository,
+	paymentProvider PaymentProvider,
+) *WaitlistService {
+	return &WaitlistService{
+		dbc:              dbc,
+		waitlistRepo:     waitlistRepo,
+		slotRepo:         slotRepo,
+		bookingRepo:      bookingRepo,
+		userRepo:         userRepo,
+		coachProfileRepo: coachProfileRepo,
+		paymentRepo:      paymentRepo,
+		paymentProvider:  paymentProvider,
+	}
+}
+
+// JoinSlotWaitlist puts the student in line for a seat in a full slot.
+func (s *WaitlistService) JoinSlotWaitlist(ctx context.Context, studentID, slotID uuid.UUID) (*model.WaitlistEntry, error) {
+	if err := s.checkStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+
+	slot, err := s.slotRepo.GetSlotByID(ctx, slotID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &ErrSlotNotFound{SlotID: slotID.String()}
+		}
+		return nil, fmt.Errorf("error fetching slot: %w", err)
+	}
+	if !slot.StartTime.After(time.Now()) {
+		return nil, &ErrPastSlot{SlotID: slotID.String()}
+	}
+	if slot.Status != model.SlotStatusOpen && slot.Status != model.SlotStatusBooked {
+		return nil, &ErrInvalidWaitlistRequest{Reason: fmt.Sprintf("slot is %s", slot.Status)}
+	}
+	if !slot.Booked {
+		return nil, &ErrSlotHasSeats{SlotID: slotID.String()}
+	}
+	if _, err := s.bookingRepo.GetBooking(ctx, slotID, studentID); err == nil {
+		return nil, &ErrInvalidWaitlistRequest{Reason: "student already has a seat in this slot"}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("error fetching booking: %w", err)
+	}
+
+	entry := model.WaitlistEntry{
+		ID:        uuid.New(),
+		StudentID: studentID,
+		CoachID:   slot.CoachID,
+		SlotID:    &slot.ID,
+		Status:    model.WaitlistStatusWaiting,
+		CreatedAt: time.Now(),
+	}
+	if err := s.createEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// JoinWeekWaitlist puts the student in line for any slot with the coach in
+// the week containing day. Weeks start on Monday in the coach's time zone.
+func (s *WaitlistService) JoinWeekWaitlist(ctx context.Context, studentID, coachID uuid.UUID, day time.Time) (*model.WaitlistEntry, error) {
+	if err := s.checkStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+
+	coach, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+	if coach.Role != model.RoleCoach {
+		return nil, &ErrNotCoach{UserID: coachID.String()}
+	}
+
+	profile, err := getCoachProfileOrDefault(ctx, s.coachProfileRepo, coachID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadCoachLocation(profile.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	y, m, d := day.Date()
+	weekStart := waitlistWeekStart(time.Date(y, m, d, 0, 0, 0, 0, loc), loc)
+	y, m, d = weekStart.Date()
+	if !time.Date(y, m, d+7, 0, 0, 0, 0, loc).After(time.Now()) {
+		return nil, &ErrInvalidWaitlistRequest{Reason: "week is in the past"}
+	}
+
+	entry := model.WaitlistEntry{
+		ID:        uuid.New(),
+		StudentID: studentID,
+		CoachID:   coachID,
+		WeekStart: &weekStart,
+		Status:    model.WaitlistStatusWaiting,
+		CreatedAt: time.Now(),
+	}
+	if err := s.createEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *WaitlistService) createEntry(ctx context.Context, entry model.WaitlistEntry) error {
+	return s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		waitlistRepo := repository.NewWaitlistRepository(tx)
+
+		// Serialize this student's waitlist changes so the duplicate check holds
+		if err := repository.NewUserRepository(tx).LockUser(ctx, entry.StudentID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		exists, err := waitlistRepo.HasWaitingEntry(ctx, entry.StudentID, entry.CoachID, entry.SlotID, entry.WeekStart)
+		if err != nil {
+			return fmt.Errorf("error checking waitlist: %w", err)
+		}
+		if exists {
+			return &ErrAlreadyWaitlisted{StudentID: entry.StudentID.String()}
+		}
+
+		if err := waitlistRepo.CreateEntry(ctx, entry); err != nil {
+			return fmt.Errorf("error creating waitlist entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *WaitlistService) GetWaitlistEntries(ctx context.Context, studentID uuid.UUID) ([]model.WaitlistEntry, error) {
+	if err := s.checkStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.waitlistRepo.GetEntriesForStudent(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching waitlist entries: %w", err)
+	}
+	if entries == nil {
+		entries = []model.WaitlistEntry{} // Return an empty slice instead of nil
+	}
+	return entries, nil
+}
+
+// LeaveWaitlist takes the student out of line. Seats already offered are
+// answered through ConfirmPromotion or DeclinePromotion instead.
+func (s *WaitlistService) LeaveWaitlist(ctx context.Context, studentID, entryID uuid.UUID) error {
+	entry, err := s.waitlistRepo.GetEntryByID(ctx, entryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ErrWaitlistEntryNotFound{EntryID: entryID.String()}
+		}
+		return fmt.Errorf("error fetching waitlist entry: %w", err)
+	}
+	if entry.StudentID != studentID || entry.Status != model.WaitlistStatusWaiting {
+		return &ErrWaitlistEntryNotFound{EntryID: entryID.String()}
+	}
+
+	if err := s.waitlistRepo.UpdateEntryStatus(ctx, entryID, model.WaitlistStatusLeft); err != nil {
+		return fmt.Errorf("error leaving waitlist: %w", err)
+	}
+	return nil
+}
+
+func (s *WaitlistService) GetPromotions(ctx context.Context, studentID uuid.UUID, page, pageSize int) ([]model.WaitlistPromotion, int, error) {
+	if err := s.checkStudent(ctx, studentID); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	promotions, totalCount, err := s.waitlistRepo.GetPromotionsForStudent(ctx, studentID, offset, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching promotions: %w", err)
+	}
+	if promotions == nil {
+		promotions = []model.WaitlistPromotion{} // Return an empty slice instead of nil
+	}
+	return promotions, totalCount, nil
+}
+
+// ConfirmPromotion turns the seat held for the student into a booking, or a
+// booking request if the coach approves bookings, and charges for it like
+// BookSlot. A student who cannot pay keeps the hold until
+// it expires or they decline it. A student the coach now blocks for no-shows
+// loses the offer and gets ErrTooManyNoShows.
+func (s *WaitlistService) ConfirmPromotion(ctx context.Context, studentID, promotionID uuid.UUID) error {
+	payments := newBookingPayments(s.paymentProvider)
+	var blocked error
+	err := s.answerPromotion(ctx, studentID, promotionID, func(tx db.DbClient, slot *model.Slot, promotion *model.WaitlistPromotion) error {
+		// The student may have missed sessions since the seat was offered.
+		// If the coach now blocks them, the seat goes to the next student.
+		if err := checkNoShowPolicy(ctx, tx, slot, studentID); err != nil {
+			var errTooManyNoShows *ErrTooManyNoShows
+			if !errors.As(err, &errTooManyNoShows) {
+				return err
+			}
+			blocked = err
+			return declinePromotion(ctx, tx, slot, promotion, studentID)
+		}
+		if err := payments.payForSeat(ctx, tx, slot, studentID); err != nil {
+			return err
+		}
+		if err := repository.NewBookingRepository(tx).ConfirmBooking(ctx, slot.ID, studentID); err != nil {
+			return fmt.Errorf("error confirming booking: %w", err)
+		}
+		if _, err := requestApprovalIfRequired(ctx, tx, slot, studentID); err != nil {
+			return err
+		}
+		return resolvePromotion(ctx, tx, promotion, model.PromotionStatusConfirmed)
+	})
+	payments.settle(ctx, s.paymentRepo, err)
+	if err != nil {
+		return err
+	}
+	return blocked
+}
+
+// DeclinePromotion gives the held seat back and offers it to the next student
+// in line.
+func (s *WaitlistService) DeclinePromotion(ctx context.Context, studentID, promotionID uuid.UUID) error {
+	return s.answerPromotion(ctx, studentID, promotionID, func(tx db.DbClient, slot *model.Slot, promotion *model.WaitlistPromotion) error {
+		return declinePromotion(ctx, tx, slot, promotion, studentID)
+	})
+}
+
+func declinePromotion(ctx context.Context, tx db.DbClient, slot *model.Slot, promotion *model.WaitlistPromotion, studentID uuid.UUID) error {
+	if err := repository.NewSlotRepository(tx).ReleaseBooking(ctx, slot.ID, studentID); err != nil {
+		return fmt.Errorf("error releasing booking: %w", err)
+	}
+	if err := resolvePromotion(ctx, tx, promotion, model.PromotionStatusDeclined); err != nil {
+		return err
+	}
+	return promoteFromWaitlist(ctx, tx, slot)
+}
+
+// answerPromotion locks the slot and the student's open offer on it and runs
+// fn on them in a transaction.
+func (s *WaitlistService) answerPromotion(ctx context.Context, studentID, promotionID uuid.UUID, fn func(tx db.DbClient, slot *model.Slot, promotion *model.WaitlistPromotion) error) error {
+	promotion, err := s.waitlistRepo.GetPromotionByID(ctx, promotionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ErrPromotionNotFound{PromotionID: promotionID.String()}
+		}
+		return fmt.Errorf("error fetching promotion: %w", err)
+	}
+	if promotion.StudentID != studentID {
+		return &ErrPromotionNotFound{PromotionID: promotionID.String()}
+	}
+
+	return s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+		// Lock the student first, as BookSlot does, so confirming cannot
+		// race another booking for the student's last credit
+		if err := repository.NewUserRepository(tx).LockUser(ctx, studentID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		// Lock the slot before the promotion, in the same order as the
+		// expiry job
+		slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, promotion.SlotID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ErrSlotNotFound{SlotID: promotion.SlotID.String()}
+			}
+			return fmt.Errorf("error fetching slot: %w", err)
+		}
+		promotion, err := repository.NewWaitlistRepository(tx).GetPromotionByIDForUpdate(ctx, promotionID)
+		if err != nil {
+			return fmt.Errorf("error fetching promotion: %w", err)
+		}
+
+		if promotion.Status != model.PromotionStatusOffered {
+			return &ErrPromotionClosed{PromotionID: promotionID.String(), Status: promotion.Status}
+		}
+		if !promotion.ExpiresAt.After(time.Now()) {
+			return &ErrPromotionClosed{PromotionID: promotionID.String(), Status: model.PromotionStatusExpired}
+		}
+		return fn(tx, slot, promotion)
+	})
+}
+
+// ExpirePromotions releases seats whose hold window passed without an answer
+// and offers them to the next student in line. It runs periodically from the
+// scheduler. Each promotion is handled in its own transaction, so one failure
+// does not hold up the rest.
+func (s *WaitlistService) ExpirePromotions(ctx context.Context) (int, error) {
+	promotions, err := s.waitlistRepo.GetExpiredPromotions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching expired promotions: %w", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, promotion := range promotions {
+		resolved := false
+		err := s.dbc.WithTx(ctx, func(tx db.DbClient) error {
+			slot, err := repository.NewSlotRepository(tx).GetSlotByIDForUpdate(ctx, promotion.SlotID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("error fetching slot: %w", err)
+			}
+			locked, err := repository.NewWaitlistRepository(tx).GetPromotionByIDForUpdate(ctx, promotion.ID)
+			if err != nil {
+				return fmt.Errorf("error fetching promotion: %w", err)
+			}
+			// Answered while we were waiting for the lock
+			if locked.Status != model.PromotionStatusOffered {
+				return nil
+			}
+
+			if err := resolvePromotion(ctx, tx, locked, model.PromotionStatusExpired); err != nil {
+				return err
+			}
+			resolved = true
+			if slot == nil {
+				return nil
+			}
+
+			if err := repository.NewSlotRepository(tx).ReleaseBooking(ctx, slot.ID, locked.StudentID); err != nil {
+				return fmt.Errorf("error releasing booking: %w", err)
+			}
+			message := fmt.Sprintf("Your held seat in the session on %s has expired.",
+				slot.StartTime.UTC().Format("Mon Jan 2 2006 15:04 MST"))
+			if err := notify(ctx, repository.NewNotificationRepository(tx), locked.StudentID, message); err != nil {
+				return err
+			}
+			return promoteFromWaitlist(ctx, tx, slot)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("promotion %s: %w", promotion.ID, err))
+			continue
+		}
+		if resolved {
+			expired++
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+func (s *WaitlistService) checkStudent(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %w", err)
+	}
+	if user.Role != model.RoleStudent {
+		return &ErrNotStudent{UserID: userID.String()}
+	}
+	return nil
+}
+
+func resolvePromotion(ctx context.Context, tx db.DbClient, promotion *model.WaitlistPromotion, status model.PromotionStatus) error {
+	if err := repository.NewWaitlistRepository(tx).ResolvePromotion(ctx, promotion.ID, status, time.Now()); err != nil {
+		return fmt.Errorf("error updating promotion: %w", err)
+	}
+	return nil
+}
+
+// promoteFromWaitlist offers free seats in the slot to waiting students, first
+// come first served. Each promoted student gets a held seat they must confirm
+// within the hold window. Students who already have a seat in the slot or an
+// overlapping booking are passed over but keep their place. The caller must
+// hold the slot's row lock.
+func promoteFromWaitlist(ctx context.Context, tx db.DbClient, slot *model.Slot) error {
+	if !slot.StartTime.After(time.Now()) {
+		return nil
+	}
+	if slot.Status != model.SlotStatusOpen && slot.Status != model.SlotStatusBooked {
+		return nil
+	}
+
+	profile, err := getCoachProfileOrDefault(ctx, repository.NewCoachProfileRepository(tx), slot.CoachID)
+	if err != nil {
+		return err
+	}
+	loc, err := loadCoachLocation(profile.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	waitlistRepo := repository.NewWaitlistRepository(tx)
+	slotRepo := repository.NewSlotRepository(tx)
+	bookingRepo := repository.NewBookingRepository(tx)
+
+	entries, err := waitlistRepo.GetWaitingEntriesForSlot(ctx, slot.ID, slot.CoachID, waitlistWeekStart(slot.StartTime, loc))
+	if err != nil {
+		return fmt.Errorf("error fetching waitlist: %w", err)
+	}
+
+	expiresAt := time.Now().Add(waitlistHoldWindow)
+	if expiresAt.After(slot.StartTime) {
+		expiresAt = slot.StartTime
+	}
+
+	for _, entry := range entries {
+		if _, err := bookingRepo.GetBooking(ctx, slot.ID, entry.StudentID); err == nil {
+			continue
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error fetching booking: %w", err)
+		}
+		hasOverlap, err := slotRepo.HasOverlappingBooking(ctx, entry.StudentID, slot.StartTime, slot.EndTime)
+		if err != nil {
+			return fmt.Errorf("error checking for overlapping bookings: %w", err)
+		}
+		if hasOverlap {
+			continue
+		}
+		// Students the coach has blocked for no-shows stay on the waitlist
+		// but are passed over
+		if err := checkNoShowPolicy(ctx, tx, slot, entry.StudentID); err != nil {
+			var errTooManyNoShows *ErrTooManyNoShows
+			if errors.As(err, &errTooManyNoShows) {
+				continue
+			}
+			return err
+		}
+
+		held, err := slotRepo.HoldSeat(ctx, slot.ID, entry.StudentID, expiresAt)
+		if err != nil {
+			return fmt.Errorf("error holding seat: %w", err)
+		}
+		if !held {
+			// No seats left
+			return nil
+		}
+
+		if err := waitlistRepo.UpdateEntryStatus(ctx, entry.ID, model.WaitlistStatusPromoted); err != nil {
+			return fmt.Errorf("error updating waitlist entry: %w", err)
+		}
+		promotion := model.WaitlistPromotion{
+			ID:        uuid.New(),
+			EntryID:   entry.ID,
+			SlotID:    slot.ID,
+			StudentID: entry.StudentID,
+			Status:    model.PromotionStatusOffered,
+			ExpiresAt: expiresAt,
+			CreatedAt: time.Now(),
+		}
+		if err := waitlistRepo.CreatePromotion(ctx, promotion); err != nil {
+			return fmt.Errorf("error recording promotion: %w", err)
+		}
+
+		message := fmt.Sprintf("A seat opened up in the session on %s. Confirm it by %s or it goes to the next student in line.",
+			slot.StartTime.UTC().Format("Mon Jan 2 2006 15:04 MST"), expiresAt.UTC().Format("Mon Jan 2 2006 15:04 MST"))
+		if err := notify(ctx, repository.NewNotificationRepository(tx), entry.StudentID, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitlistWeekStart returns the Monday of the week containing t in loc, as a
+// date.
+func waitlistWeekStart(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	offset := (int(local.Weekday()) + 6) % 7
+	y, m, d := local.Date()
+	return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+}